@@ -0,0 +1,308 @@
+package Macku
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// AccelerationModel maps device counts to on-screen cursor displacement for a
+// single move on one axis. Models are odd-symmetric: negative counts produce
+// the mirrored displacement.
+type AccelerationModel interface {
+	// Displacement returns the cursor displacement (px) produced by counts.
+	Displacement(counts float64) float64
+	// Counts returns the device counts needed to move the cursor by px.
+	Counts(px float64) float64
+}
+
+// FlatAcceleration is a 1:1 mapping between device counts and pixels.
+type FlatAcceleration struct{}
+
+func (FlatAcceleration) Displacement(counts float64) float64 { return counts }
+func (FlatAcceleration) Counts(px float64) float64           { return px }
+
+// LinearAcceleration scales every move by a constant Multiplier (pixels per count),
+// matching the Windows pointer-speed slider with enhanced precision disabled.
+type LinearAcceleration struct {
+	Multiplier float64
+}
+
+func (a LinearAcceleration) Displacement(counts float64) float64 {
+	return counts * a.multiplier()
+}
+
+func (a LinearAcceleration) Counts(px float64) float64 {
+	return px / a.multiplier()
+}
+
+func (a LinearAcceleration) multiplier() float64 {
+	if a.Multiplier <= 0 {
+		return 1
+	}
+	return a.Multiplier
+}
+
+// AccelPoint is one point on a piecewise-linear acceleration curve.
+type AccelPoint struct {
+	Counts float64 // Device counts in a single move (>= 0)
+	Pixels float64 // Resulting cursor displacement (px)
+}
+
+// PiecewiseAcceleration interpolates displacement linearly between Points and
+// extrapolates with the slope of the last segment. The curve implicitly starts
+// at the origin. Points must be monotonically increasing in both fields.
+type PiecewiseAcceleration struct {
+	Points []AccelPoint
+}
+
+func (a PiecewiseAcceleration) Displacement(counts float64) float64 {
+	return mirror(counts, func(c float64) float64 {
+		return interpolate(a.points(), c, func(p AccelPoint) (float64, float64) { return p.Counts, p.Pixels })
+	})
+}
+
+func (a PiecewiseAcceleration) Counts(px float64) float64 {
+	return mirror(px, func(p float64) float64 {
+		return interpolate(a.points(), p, func(pt AccelPoint) (float64, float64) { return pt.Pixels, pt.Counts })
+	})
+}
+
+func (a PiecewiseAcceleration) points() []AccelPoint {
+	pts := make([]AccelPoint, 0, len(a.Points)+1)
+	if len(a.Points) == 0 || a.Points[0].Counts > 0 {
+		pts = append(pts, AccelPoint{})
+	}
+	return append(pts, a.Points...)
+}
+
+// AdaptiveAcceleration approximates libinput's adaptive pointer profile: a
+// constant base gain up to Threshold counts per move, then a gain rising with
+// Incline until it reaches MaxFactor.
+type AdaptiveAcceleration struct {
+	BaseGain  float64 // Gain below the threshold (pixels per count)
+	Threshold float64 // Counts per move where acceleration starts
+	Incline   float64 // Gain increase per count above the threshold
+	MaxFactor float64 // Upper bound on the gain
+}
+
+// NewAdaptiveAcceleration returns an AdaptiveAcceleration for a libinput speed
+// setting in [-1, 1], following the same shape as libinput's defaults: faster
+// settings lower the threshold and raise the maximum gain.
+func NewAdaptiveAcceleration(speed float64) AdaptiveAcceleration {
+	speed = math.Max(-1, math.Min(1, speed))
+	return AdaptiveAcceleration{
+		BaseGain:  1,
+		Threshold: 4 - 2*speed,
+		Incline:   1.1 / 8,
+		MaxFactor: 2 + 1.5*speed,
+	}
+}
+
+func (a AdaptiveAcceleration) factor(counts float64) float64 {
+	base := a.BaseGain
+	if base <= 0 {
+		base = 1
+	}
+	if counts <= a.Threshold {
+		return base
+	}
+	f := base + (counts-a.Threshold)*a.Incline
+	if a.MaxFactor > 0 {
+		f = math.Min(f, math.Max(a.MaxFactor, base))
+	}
+	return f
+}
+
+func (a AdaptiveAcceleration) Displacement(counts float64) float64 {
+	return mirror(counts, func(c float64) float64 { return c * a.factor(c) })
+}
+
+func (a AdaptiveAcceleration) Counts(px float64) float64 {
+	return mirror(px, func(p float64) float64 { return invertMonotonic(a.Displacement, p) })
+}
+
+// mirror applies f to |v| and restores the sign of v.
+func mirror(v float64, f func(float64) float64) float64 {
+	if v < 0 {
+		return -f(-v)
+	}
+	return f(v)
+}
+
+// interpolate evaluates the piecewise-linear function through pts at x, using
+// xy to select the input and output coordinate of each point.
+func interpolate(pts []AccelPoint, x float64, xy func(AccelPoint) (float64, float64)) float64 {
+	if len(pts) < 2 {
+		return x
+	}
+	for i := 1; i < len(pts); i++ {
+		x0, y0 := xy(pts[i-1])
+		x1, y1 := xy(pts[i])
+		if x <= x1 || i == len(pts)-1 {
+			if x1 == x0 {
+				return y1
+			}
+			return y0 + (x-x0)*(y1-y0)/(x1-x0)
+		}
+	}
+	return x
+}
+
+// invertMonotonic solves f(x) = y for a non-decreasing f with f(0) = 0 by bisection.
+func invertMonotonic(f func(float64) float64, y float64) float64 {
+	if y <= 0 {
+		return 0
+	}
+	lo, hi := 0.0, 1.0
+	for f(hi) < y && hi < 1e9 {
+		lo, hi = hi, hi*2
+	}
+	for i := 0; i < 64; i++ {
+		mid := (lo + hi) / 2
+		if f(mid) < y {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2
+}
+
+// --- calibration ---
+
+// CalibrationOptions configures CalibrateAcceleration.
+type CalibrationOptions struct {
+	Steps   []int         // Move sizes (counts) to probe; defaults to 1..64 in powers of two
+	Repeats int           // Moves per step size and direction
+	Settle  time.Duration // Wait after each move before reading the cursor
+}
+
+// DefaultCalibrationOptions returns CalibrationOptions probing 1–64 counts.
+func DefaultCalibrationOptions() CalibrationOptions {
+	return CalibrationOptions{
+		Steps:   []int{1, 2, 4, 8, 16, 32, 64},
+		Repeats: 3,
+		Settle:  10 * time.Millisecond,
+	}
+}
+
+// CalibrationSample is the mean displacement observed for one move size.
+type CalibrationSample struct {
+	Counts int
+	Pixels float64
+}
+
+// Calibration holds the observations and models fitted by CalibrateAcceleration.
+type Calibration struct {
+	Samples []CalibrationSample
+	Linear  LinearAcceleration    // Least-squares fit through the origin
+	Curve   PiecewiseAcceleration // Curve through the observed samples
+}
+
+// CalibrateAcceleration probes the pointer response by issuing horizontal
+// moves of each step size through move, measuring the displacement reported
+// by cursor. Every move is followed by the opposite move so the cursor ends
+// where it started; both directions contribute to the sample.
+func CalibrateAcceleration(cursor CursorProvider, move func(dx, dy int) error, opts CalibrationOptions) (Calibration, error) {
+	if len(opts.Steps) == 0 {
+		opts.Steps = DefaultCalibrationOptions().Steps
+	}
+	if opts.Repeats <= 0 {
+		opts.Repeats = 1
+	}
+
+	measure := func(counts int) (float64, error) {
+		x0, _, err := cursor.CursorPos()
+		if err != nil {
+			return 0, err
+		}
+		if err := move(counts, 0); err != nil {
+			return 0, err
+		}
+		if opts.Settle > 0 {
+			time.Sleep(opts.Settle)
+		}
+		x1, _, err := cursor.CursorPos()
+		if err != nil {
+			return 0, err
+		}
+		return float64(x1 - x0), nil
+	}
+
+	var cal Calibration
+	for _, step := range opts.Steps {
+		if step <= 0 {
+			return Calibration{}, NewCommandError(fmt.Sprintf("invalid calibration step: %d", step))
+		}
+		var total float64
+		for r := 0; r < opts.Repeats; r++ {
+			fwd, err := measure(step)
+			if err != nil {
+				return Calibration{}, err
+			}
+			back, err := measure(-step)
+			if err != nil {
+				return Calibration{}, err
+			}
+			total += fwd - back
+		}
+		cal.Samples = append(cal.Samples, CalibrationSample{
+			Counts: step,
+			Pixels: total / float64(2*opts.Repeats),
+		})
+	}
+
+	cal.Linear = FitLinearAcceleration(cal.Samples)
+	cal.Curve = FitPiecewiseAcceleration(cal.Samples)
+	return cal, nil
+}
+
+// FitLinearAcceleration fits a LinearAcceleration through the origin by least squares.
+func FitLinearAcceleration(samples []CalibrationSample) LinearAcceleration {
+	var sxy, sxx float64
+	for _, s := range samples {
+		c := float64(s.Counts)
+		sxy += c * s.Pixels
+		sxx += c * c
+	}
+	if sxx == 0 || sxy <= 0 {
+		return LinearAcceleration{Multiplier: 1}
+	}
+	return LinearAcceleration{Multiplier: sxy / sxx}
+}
+
+// FitPiecewiseAcceleration builds a monotonic PiecewiseAcceleration through the
+// samples. Samples that would make the curve decrease (e.g. clamped at a
+// screen edge) are dropped.
+func FitPiecewiseAcceleration(samples []CalibrationSample) PiecewiseAcceleration {
+	sorted := append([]CalibrationSample(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Counts < sorted[j].Counts })
+
+	var curve PiecewiseAcceleration
+	last := 0.0
+	for _, s := range sorted {
+		if s.Counts <= 0 || s.Pixels <= last {
+			continue
+		}
+		curve.Points = append(curve.Points, AccelPoint{Counts: float64(s.Counts), Pixels: s.Pixels})
+		last = s.Pixels
+	}
+	return curve
+}
+
+// SetAccelerationModel sets the pointer acceleration model MoveAbs uses to
+// convert pixel error into device counts. Pass nil to use the platform default.
+func (m *Mouse) SetAccelerationModel(model AccelerationModel) {
+	m.accel = model
+}
+
+// CalibrateAcceleration fits acceleration models from moves observed through
+// the mouse's CursorProvider. Apply the result with SetAccelerationModel.
+func (m *Mouse) CalibrateAcceleration(opts CalibrationOptions) (Calibration, error) {
+	if m.cursor == nil {
+		return Calibration{}, NewCommandError("calibration requires a CursorProvider on this platform")
+	}
+	return CalibrateAcceleration(m.cursor, m.Move, opts)
+}
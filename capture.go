@@ -0,0 +1,314 @@
+package Macku
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CaptureDir is the direction of a captured chunk of bytes.
+type CaptureDir uint8
+
+const (
+	CaptureWrite CaptureDir = 1 // host -> device
+	CaptureRead  CaptureDir = 2 // device -> host
+)
+
+// String returns ">" for writes and "<" for reads, as in the text format.
+func (d CaptureDir) String() string {
+	switch d {
+	case CaptureWrite:
+		return ">"
+	case CaptureRead:
+		return "<"
+	default:
+		return "?"
+	}
+}
+
+// CaptureFormat selects the encoding of a capture file.
+type CaptureFormat int
+
+const (
+	// CaptureBinary is compact: a header, then per chunk the direction,
+	// uvarint offset in nanoseconds, uvarint length and the raw bytes.
+	CaptureBinary CaptureFormat = iota
+	// CaptureText is one line per chunk:
+	//	+0.001234567 > "km.version()#1\r\n"
+	// with the bytes as a Go-quoted string. Lines starting with # are comments.
+	CaptureText
+)
+
+// captureMagic starts a binary capture; it is followed by a version byte
+// and the start time in Unix nanoseconds (int64, big endian).
+var captureMagic = []byte("MAKCUCAP")
+
+const (
+	captureVersion    = 1
+	captureTextHeader = "# makcu capture v1 start="
+	maxCaptureChunk   = 1 << 20
+)
+
+// CaptureRecord is one chunk of bytes seen on the wire.
+type CaptureRecord struct {
+	Offset time.Duration // since Capture.Start
+	Dir    CaptureDir
+	Data   []byte
+}
+
+// Capture is a decoded capture file.
+type Capture struct {
+	Start   time.Time
+	Records []CaptureRecord
+}
+
+// --- writing ---
+
+// CaptureWriter streams wire traffic into a capture file. It is safe for
+// concurrent use; the first write error is kept and later records are
+// dropped.
+type CaptureWriter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	format  CaptureFormat
+	start   time.Time
+	started bool
+	err     error
+}
+
+// NewCaptureWriter returns a CaptureWriter encoding to w. The capture
+// starts at the first record.
+func NewCaptureWriter(w io.Writer, format CaptureFormat) *CaptureWriter {
+	return &CaptureWriter{w: w, format: format}
+}
+
+// Record timestamps data with the current time and appends it.
+func (c *CaptureWriter) Record(dir CaptureDir, data []byte) {
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.started {
+		c.begin(now)
+	}
+	c.write(CaptureRecord{Offset: now.Sub(c.start), Dir: dir, Data: data})
+}
+
+// Err returns the first write error, if any.
+func (c *CaptureWriter) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+func (c *CaptureWriter) begin(start time.Time) {
+	c.start = start
+	c.started = true
+	if c.err != nil {
+		return
+	}
+	if c.format == CaptureText {
+		_, c.err = fmt.Fprintf(c.w, "%s%s\n", captureTextHeader, start.UTC().Format(time.RFC3339Nano))
+		return
+	}
+	hdr := make([]byte, 0, len(captureMagic)+9)
+	hdr = append(hdr, captureMagic...)
+	hdr = append(hdr, captureVersion)
+	hdr = binary.BigEndian.AppendUint64(hdr, uint64(start.UnixNano()))
+	_, c.err = c.w.Write(hdr)
+}
+
+func (c *CaptureWriter) write(r CaptureRecord) {
+	if c.err != nil {
+		return
+	}
+	if c.format == CaptureText {
+		_, c.err = fmt.Fprintf(c.w, "+%s %s %s\n", formatOffset(r.Offset), r.Dir, strconv.Quote(string(r.Data)))
+		return
+	}
+	buf := make([]byte, 0, 1+2*binary.MaxVarintLen64+len(r.Data))
+	buf = append(buf, byte(r.Dir))
+	buf = binary.AppendUvarint(buf, uint64(r.Offset))
+	buf = binary.AppendUvarint(buf, uint64(len(r.Data)))
+	buf = append(buf, r.Data...)
+	_, c.err = c.w.Write(buf)
+}
+
+// WriteTo encodes the whole capture in the given format.
+func (c *Capture) WriteTo(w io.Writer, format CaptureFormat) error {
+	cw := NewCaptureWriter(w, format)
+	cw.begin(c.Start)
+	for _, r := range c.Records {
+		cw.write(r)
+	}
+	return cw.err
+}
+
+// formatOffset renders d as seconds with nanosecond precision.
+func formatOffset(d time.Duration) string {
+	return fmt.Sprintf("%d.%09d", d/time.Second, d%time.Second)
+}
+
+// --- reading ---
+
+// ReadCapture decodes a capture in either format.
+func ReadCapture(r io.Reader) (*Capture, error) {
+	br := bufio.NewReader(r)
+	head, err := br.Peek(len(captureMagic))
+	if err == nil && bytes.Equal(head, captureMagic) {
+		return readBinaryCapture(br)
+	}
+	return readTextCapture(br)
+}
+
+func readBinaryCapture(br *bufio.Reader) (*Capture, error) {
+	hdr := make([]byte, len(captureMagic)+9)
+	if _, err := io.ReadFull(br, hdr); err != nil {
+		return nil, fmt.Errorf("failed to read capture header: %w", err)
+	}
+	if v := hdr[len(captureMagic)]; v != captureVersion {
+		return nil, fmt.Errorf("unsupported capture version: %d", v)
+	}
+	c := &Capture{Start: time.Unix(0, int64(binary.BigEndian.Uint64(hdr[len(captureMagic)+1:])))}
+	for {
+		dir, err := br.ReadByte()
+		if errors.Is(err, io.EOF) {
+			return c, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if dir != byte(CaptureWrite) && dir != byte(CaptureRead) {
+			return nil, fmt.Errorf("capture record %d: bad direction %d", len(c.Records), dir)
+		}
+		off, err := binary.ReadUvarint(br)
+		if err != nil {
+			return nil, fmt.Errorf("capture record %d: %w", len(c.Records), noEOF(err))
+		}
+		n, err := binary.ReadUvarint(br)
+		if err != nil {
+			return nil, fmt.Errorf("capture record %d: %w", len(c.Records), noEOF(err))
+		}
+		if n > maxCaptureChunk {
+			return nil, fmt.Errorf("capture record %d: chunk of %d bytes", len(c.Records), n)
+		}
+		data := make([]byte, n)
+		if _, err := io.ReadFull(br, data); err != nil {
+			return nil, fmt.Errorf("capture record %d: %w", len(c.Records), noEOF(err))
+		}
+		c.Records = append(c.Records, CaptureRecord{Offset: time.Duration(off), Dir: CaptureDir(dir), Data: data})
+	}
+}
+
+func readTextCapture(br *bufio.Reader) (*Capture, error) {
+	c := &Capture{}
+	sc := bufio.NewScanner(br)
+	sc.Buffer(make([]byte, 0, 64*1024), 4*maxCaptureChunk+64)
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		line := strings.TrimSpace(sc.Text())
+		if strings.HasPrefix(line, captureTextHeader) {
+			start, err := time.Parse(time.RFC3339Nano, strings.TrimPrefix(line, captureTextHeader))
+			if err != nil {
+				return nil, fmt.Errorf("capture line %d: bad start time: %w", lineNo, err)
+			}
+			c.Start = start
+			continue
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rec, err := parseCaptureLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("capture line %d: %w", lineNo, err)
+		}
+		c.Records = append(c.Records, rec)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// parseCaptureLine parses `+1.000000123 > "bytes"`.
+func parseCaptureLine(line string) (CaptureRecord, error) {
+	fields := strings.SplitN(line, " ", 3)
+	if len(fields) != 3 || !strings.HasPrefix(fields[0], "+") {
+		return CaptureRecord{}, fmt.Errorf("malformed record %q", line)
+	}
+	off, err := parseOffset(fields[0][1:])
+	if err != nil {
+		return CaptureRecord{}, err
+	}
+	var dir CaptureDir
+	switch fields[1] {
+	case ">":
+		dir = CaptureWrite
+	case "<":
+		dir = CaptureRead
+	default:
+		return CaptureRecord{}, fmt.Errorf("bad direction %q", fields[1])
+	}
+	data, err := strconv.Unquote(fields[2])
+	if err != nil {
+		return CaptureRecord{}, fmt.Errorf("bad data %s: %w", fields[2], err)
+	}
+	return CaptureRecord{Offset: off, Dir: dir, Data: []byte(data)}, nil
+}
+
+// parseOffset parses seconds with up to nine decimals ("1.5", "0.000000123").
+func parseOffset(s string) (time.Duration, error) {
+	secs, frac, _ := strings.Cut(s, ".")
+	if len(frac) > 9 {
+		return 0, fmt.Errorf("bad offset %q", s)
+	}
+	sec, err := strconv.ParseUint(secs, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("bad offset %q", s)
+	}
+	var ns uint64
+	if frac != "" {
+		if ns, err = strconv.ParseUint(frac+strings.Repeat("0", 9-len(frac)), 10, 64); err != nil {
+			return 0, fmt.Errorf("bad offset %q", s)
+		}
+	}
+	return time.Duration(sec)*time.Second + time.Duration(ns), nil
+}
+
+func noEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// --- tap ---
+
+// tapPort records every byte through a Port into a CaptureWriter.
+type tapPort struct {
+	Port
+	cw *CaptureWriter
+}
+
+func (p *tapPort) Read(b []byte) (int, error) {
+	n, err := p.Port.Read(b)
+	if n > 0 {
+		p.cw.Record(CaptureRead, b[:n])
+	}
+	return n, err
+}
+
+func (p *tapPort) Write(b []byte) (int, error) {
+	n, err := p.Port.Write(b)
+	if n > 0 {
+		p.cw.Record(CaptureWrite, b[:n])
+	}
+	return n, err
+}
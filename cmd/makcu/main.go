@@ -0,0 +1,35 @@
+// Command makcu operates a Makcu device from the shell.
+//
+//	makcu list
+//	makcu -json info
+//	makcu move 100 0 10
+//	makcu click -count 2 right
+//	makcu -port /dev/ttyACM0 monitor
+//	makcu raw -response "km.version()"
+//	makcu console
+//	makcu -daemon /tmp/makcud.sock info
+//	makcu -port /dev/ttyACM0 relay -listen :7712
+//	makcu -remote lab-pc:7712 info
+//	makcu -capture session.txt move 10 0 && makcu decode session.txt
+//
+// Exit codes: 0 success, 1 other error, 2 usage, 3 connection, 4 timeout,
+// 5 command, 6 response.
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+
+	"github.com/Auchrio/Makcu-go-lib/v3/internal/cli"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	app := &cli.App{Stdout: os.Stdout, Stderr: os.Stderr, Stdin: os.Stdin}
+	code := app.Run(ctx, os.Args[1:])
+	stop()
+	os.Exit(code)
+}
@@ -0,0 +1,128 @@
+// Command makcud owns a Makcu device and shares it with other processes over
+// a Unix domain socket (see package daemon for the wire protocol).
+//
+//	makcud
+//	makcud -socket /run/makcud.sock -port /dev/ttyACM0
+//	makcud -grpc :7710
+//	makcud -http 127.0.0.1:7711 -http-token secret
+//
+// Clients connect with daemon.NewClient, or the makcu tool's -daemon flag.
+// With -grpc the controller is also served to other machines through
+// package grpcapi, and with -http through the REST/WebSocket API of package
+// httpapi. The HTTP API refuses a non-loopback address unless a token is set;
+// the token may also come from $MAKCUD_HTTP_TOKEN.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	Macku "github.com/Auchrio/Makcu-go-lib/v3"
+	"github.com/Auchrio/Makcu-go-lib/v3/daemon"
+	"github.com/Auchrio/Makcu-go-lib/v3/grpcapi"
+	"github.com/Auchrio/Makcu-go-lib/v3/httpapi"
+	"google.golang.org/grpc"
+)
+
+func main() {
+	socket := flag.String("socket", daemon.DefaultSocketPath(), "Unix socket path to serve on")
+	port := flag.String("port", "", "serial port (default: auto-detect)")
+	grpcAddr := flag.String("grpc", "", "also serve gRPC on this TCP address (e.g. :7710)")
+	httpAddr := flag.String("http", "", "also serve the REST/WebSocket API on this address (e.g. "+httpapi.DefaultAddr+")")
+	httpToken := flag.String("http-token", os.Getenv("MAKCUD_HTTP_TOKEN"), "bearer token required by the HTTP API")
+	debug := flag.Bool("debug", false, "enable debug logging")
+	flag.Parse()
+
+	opts := options{
+		socket:    *socket,
+		port:      *port,
+		grpcAddr:  *grpcAddr,
+		httpAddr:  *httpAddr,
+		httpToken: *httpToken,
+		debug:     *debug,
+	}
+	if err := run(opts); err != nil {
+		fmt.Fprintln(os.Stderr, "makcud:", err)
+		os.Exit(1)
+	}
+}
+
+type options struct {
+	socket, port        string
+	grpcAddr            string
+	httpAddr, httpToken string
+	debug               bool
+}
+
+func run(o options) error {
+	if o.httpAddr != "" && o.httpToken == "" && !httpapi.IsLoopback(o.httpAddr) {
+		return fmt.Errorf("refusing to serve HTTP on non-loopback address %s without -http-token", o.httpAddr)
+	}
+
+	cfg := Macku.DefaultConfig()
+	cfg.Debug = o.debug
+	if o.port != "" {
+		cfg.FallbackCOMPort = o.port
+		cfg.OverridePort = true
+	}
+	c, err := Macku.CreateController(cfg)
+	if err != nil {
+		return err
+	}
+	defer c.Disconnect()
+
+	srv := daemon.NewServer(c)
+	if o.debug {
+		srv.Logf = log.Printf
+	}
+
+	var g *grpc.Server
+	if o.grpcAddr != "" {
+		lis, err := net.Listen("tcp", o.grpcAddr)
+		if err != nil {
+			return err
+		}
+		g = grpc.NewServer()
+		defer grpcapi.Register(g, c).Close()
+		go g.Serve(lis)
+		log.Printf("makcud: serving gRPC on %s", lis.Addr())
+	}
+
+	var hs *http.Server
+	if o.httpAddr != "" {
+		lis, err := net.Listen("tcp", o.httpAddr)
+		if err != nil {
+			return err
+		}
+		api := httpapi.NewServer(c, httpapi.Options{Token: o.httpToken})
+		defer api.Close()
+		hs = &http.Server{Handler: api, ReadHeaderTimeout: 10 * time.Second}
+		go hs.Serve(lis)
+		log.Printf("makcud: serving HTTP on %s", lis.Addr())
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		if g != nil {
+			g.Stop()
+		}
+		if hs != nil {
+			hs.Close()
+		}
+		srv.Close()
+	}()
+
+	log.Printf("makcud: serving %s on %s", c.Transport.PortName(), o.socket)
+	defer os.Remove(o.socket)
+	return srv.ListenAndServe(o.socket)
+}
@@ -2,12 +2,14 @@ package Macku
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
-	"go.bug.st/serial"
+	"github.com/Auchrio/Makcu-go-lib/v3/protocol"
 	"go.bug.st/serial/enumerator"
 )
 
@@ -16,9 +18,10 @@ var baudChangeCommand = []byte{0xDE, 0xAD, 0x05, 0x00, 0xA5, 0x00, 0x09, 0x3D, 0
 
 // Button name/enum lookup tables.
 var (
-	buttonNames   = [5]string{"left", "right", "middle", "mouse4", "mouse5"}
-	buttonEnumMap = [5]MouseButton{
+	buttonNames   = [MaxButtons]string{"left", "right", "middle", "mouse4", "mouse5", "mouse6", "mouse7", "mouse8"}
+	buttonEnumMap = [MaxButtons]MouseButton{
 		MouseButtonLeft, MouseButtonRight, MouseButtonMiddle, MouseButton4, MouseButton5,
+		MouseButton6, MouseButton7, MouseButton8,
 	}
 )
 
@@ -38,65 +41,142 @@ type PendingCommand struct {
 
 // SerialTransport manages the serial connection to a Makcu device.
 type SerialTransport struct {
-	Port string // The COM port in use (exported for Mouse.GetDeviceInfo)
+	Port string // The COM port in use
 
 	fallbackPort  string
-	debug         bool
+	debug         atomic.Bool
+	logOut        atomic.Pointer[io.Writer] // debug output; nil is stdout
 	sendInit      bool
 	autoReconnect bool
 	overridePort  bool
+	framing       bool        // negotiate framed button reports on connect
+	framed        atomic.Bool // the device is framing its button reports
+	framesAsked   atomic.Bool // km.buttons(2) was sent on this connection
 
 	isConnected       atomic.Bool
+	connections       atomic.Uint64 // successful connects and reconnects
 	reconnectAttempts int
 	baudrate          int
-	serialPort        serial.Port
-	currentBaud       int
+	serialPort        Port // guarded by portMu; swapped on reconnect
+	portMu            sync.RWMutex
+	openPort          func(name string) (Port, error) // nil opens a serial port
+	tap               *CaptureWriter                  // records wire traffic when set
+	metrics           *transportMetrics
 
 	commandCounter  int
 	pendingCommands map[int]*PendingCommand
 	commandLock     sync.Mutex
 
-	buttonCallback func(MouseButton, bool)
-	lastButtonMask int
-	buttonStates   int
+	buttonCallback    func(MouseButton, bool)
+	reconnectCallback func()
+	lastButtonMask    int
+	buttonStates      int
 
-	stopChan chan struct{}
+	stopChan   chan struct{}
+	listenDone chan struct{} // closed when the listener goroutine exits
 }
 
 // NewSerialTransport creates a new serial transport.
 func NewSerialTransport(fallback string, debug, sendInit, autoReconnect, overridePort bool) *SerialTransport {
 	s := &SerialTransport{
 		fallbackPort:    fallback,
-		debug:           debug,
 		sendInit:        sendInit,
 		autoReconnect:   autoReconnect,
 		overridePort:    overridePort,
 		baudrate:        115200,
 		pendingCommands: make(map[int]*PendingCommand),
 		stopChan:        make(chan struct{}),
+		metrics:         newTransportMetrics(),
 	}
+	s.debug.Store(debug)
 	s.log("Macku version: %s", Version)
 	s.log("Initializing SerialTransport: fallback=%q, debug=%v, sendInit=%v, autoReconnect=%v, overridePort=%v",
 		fallback, debug, sendInit, autoReconnect, overridePort)
 	return s
 }
 
+// NewStreamTransport creates a transport that speaks the Makcu byte protocol
+// over the Port returned by open instead of a local serial port. name is
+// reported by PortName. Use it for emulators and custom links.
+func NewStreamTransport(name string, open func() (Port, error), debug, sendInit, autoReconnect bool) *SerialTransport {
+	s := NewSerialTransport(name, debug, sendInit, autoReconnect, true)
+	s.openPort = func(string) (Port, error) { return open() }
+	return s
+}
+
+// SetDebug enables or disables debug logging. It is safe to call while
+// the transport is running.
+func (s *SerialTransport) SetDebug(debug bool) {
+	s.debug.Store(debug)
+}
+
+// SetLogOutput sends debug logging to w instead of stdout. Pass nil to
+// restore stdout.
+func (s *SerialTransport) SetLogOutput(w io.Writer) {
+	if w == nil {
+		s.logOut.Store(nil)
+		return
+	}
+	s.logOut.Store(&w)
+}
+
 // log prints a debug message if debug mode is enabled.
 func (s *SerialTransport) log(format string, args ...interface{}) {
-	if !s.debug {
+	if !s.debug.Load() {
 		return
 	}
 	timestamp := time.Now().Format("15:04:05")
 	msg := fmt.Sprintf(format, args...)
-	fmt.Printf("[%s] [INFO] %s\n", timestamp, msg)
+	var out io.Writer = os.Stdout
+	if w := s.logOut.Load(); w != nil {
+		out = *w
+	}
+	fmt.Fprintf(out, "[%s] [INFO] %s\n", timestamp, msg)
 }
 
 // generateCommandID returns a monotonically increasing command ID (wraps at 10000).
+// Callers hold commandLock.
 func (s *SerialTransport) generateCommandID() int {
 	s.commandCounter = (s.commandCounter + 1) % 10000
 	return s.commandCounter
 }
 
+// PortInfo describes a serial port found during enumeration.
+type PortInfo struct {
+	Name    string `json:"name"`
+	Product string `json:"product,omitempty"`
+	VID     string `json:"vid,omitempty"`
+	PID     string `json:"pid,omitempty"`
+	IsUSB   bool   `json:"usb"`
+	IsMakcu bool   `json:"makcu"` // VID:PID matches a Makcu device (1A86:55D3)
+}
+
+// isMakcuPort reports whether a USB VID:PID pair identifies a Makcu device.
+func isMakcuPort(vid, pid string) bool {
+	return strings.ToUpper(vid) == "1A86" && strings.ToUpper(pid) == "55D3"
+}
+
+// ListPorts enumerates the serial ports on the system, flagging those whose
+// USB VID:PID matches a Makcu device.
+func ListPorts() ([]PortInfo, error) {
+	ports, err := enumerator.GetDetailedPortsList()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list COM ports: %w", err)
+	}
+	infos := make([]PortInfo, 0, len(ports))
+	for _, p := range ports {
+		infos = append(infos, PortInfo{
+			Name:    p.Name,
+			Product: p.Product,
+			VID:     p.VID,
+			PID:     p.PID,
+			IsUSB:   p.IsUSB,
+			IsMakcu: p.IsUSB && isMakcuPort(p.VID, p.PID),
+		})
+	}
+	return infos, nil
+}
+
 // FindCOMPort discovers the Makcu device COM port by USB VID:PID (1A86:55D3),
 // falling back to the configured fallback port.
 func (s *SerialTransport) FindCOMPort() (string, error) {
@@ -120,7 +200,7 @@ func (s *SerialTransport) FindCOMPort() (string, error) {
 
 	for _, port := range ports {
 		s.log("Port: %s - VID: %s PID: %s USB: %v", port.Name, port.VID, port.PID, port.IsUSB)
-		if port.IsUSB && strings.ToUpper(port.VID) == "1A86" && strings.ToUpper(port.PID) == "55D3" {
+		if port.IsUSB && isMakcuPort(port.VID, port.PID) {
 			s.log("Target device found on port: %s", port.Name)
 			return port.Name, nil
 		}
@@ -136,8 +216,8 @@ func (s *SerialTransport) FindCOMPort() (string, error) {
 	return "", nil
 }
 
-// Connect opens the serial connection, switches to 4M baud, and starts the
-// background listener goroutine.
+// Connect opens the port (switching a serial device to 4M baud) and starts
+// the background listener goroutine.
 func (s *SerialTransport) Connect() error {
 	s.log("Starting connection process")
 
@@ -161,38 +241,37 @@ func (s *SerialTransport) Connect() error {
 
 	s.log("Connecting to %s", s.Port)
 
-	mode := &serial.Mode{
-		BaudRate: 115200,
-		DataBits: 8,
-		StopBits: serial.OneStopBit,
-		Parity:   serial.NoParity,
-	}
-
-	sp, err := serial.Open(s.Port, mode)
+	sp, err := s.open(s.Port)
 	if err != nil {
-		return NewConnectionError(fmt.Sprintf("failed to open %s: %v", s.Port, err))
-	}
-
-	s.serialPort = sp
-
-	if err := s.changeBaudTo4M(); err != nil {
-		s.serialPort.Close()
-		s.serialPort = nil
-		return NewConnectionError(fmt.Sprintf("failed to switch to 4M baud: %v", err))
+		return err
 	}
+	s.setPort(sp)
 
 	s.isConnected.Store(true)
+	s.connections.Add(1)
 	s.reconnectAttempts = 0
+	s.framed.Store(false)
+	s.framesAsked.Store(false)
 
-	if s.sendInit {
+	if s.sendInit && !s.framing {
 		s.log("Sending initialization command")
-		s.serialPort.Write([]byte("km.buttons(1)\r"))
+		s.write([]byte("km.buttons(1)\r"))
+		s.metrics.commandSent("km.buttons(1)")
 	}
 
-	s.serialPort.SetReadTimeout(time.Millisecond)
+	sp.SetReadTimeout(time.Millisecond)
 
 	s.stopChan = make(chan struct{})
-	go s.listen()
+	done := make(chan struct{})
+	s.listenDone = done
+	go func() {
+		defer close(done)
+		s.listen()
+	}()
+
+	if s.sendInit && s.framing {
+		s.negotiateFraming()
+	}
 
 	s.log("Connection established")
 	return nil
@@ -212,8 +291,14 @@ func (s *SerialTransport) Disconnect() error {
 		close(s.stopChan)
 	}
 
-	// Brief wait for listener to exit
-	time.Sleep(10 * time.Millisecond)
+	// Wait for the listener to exit so it no longer touches the port. The
+	// bound covers a listener mid-reconnect or blocked in a callback.
+	if s.listenDone != nil {
+		select {
+		case <-s.listenDone:
+		case <-time.After(3 * reconnectDelay):
+		}
+	}
 
 	// Clear pending commands
 	s.commandLock.Lock()
@@ -224,10 +309,10 @@ func (s *SerialTransport) Disconnect() error {
 	s.pendingCommands = make(map[int]*PendingCommand)
 	s.commandLock.Unlock()
 
-	if s.serialPort != nil {
+	if sp := s.port(); sp != nil {
 		s.log("Closing serial port: %s", s.Port)
-		s.serialPort.Close()
-		s.serialPort = nil
+		sp.Close()
+		s.setPort(nil)
 	}
 
 	s.log("Disconnection completed")
@@ -237,8 +322,15 @@ func (s *SerialTransport) Disconnect() error {
 // SendCommand sends a command string to the device. If expectResponse is true,
 // the call blocks until a response is received or timeout expires.
 func (s *SerialTransport) SendCommand(command string, expectResponse bool, timeout time.Duration) (string, error) {
-	if !s.isConnected.Load() || s.serialPort == nil {
-		return "", NewConnectionError("not connected")
+	resp, _, err := s.sendTagged(command, expectResponse, timeout)
+	return resp, err
+}
+
+// sendTagged is SendCommand that also returns the tag given to a query
+// (0 for fire-and-forget commands).
+func (s *SerialTransport) sendTagged(command string, expectResponse bool, timeout time.Duration) (string, int, error) {
+	if !s.isConnected.Load() || s.port() == nil {
+		return "", 0, NewConnectionError("not connected")
 	}
 
 	if timeout == 0 {
@@ -246,18 +338,19 @@ func (s *SerialTransport) SendCommand(command string, expectResponse bool, timeo
 	}
 
 	if !expectResponse {
-		_, err := s.serialPort.Write([]byte(command + "\r\n"))
+		_, err := s.write(protocol.EncodeLine(command, protocol.NoTag))
 		if err != nil {
-			return "", err
+			return "", 0, err
 		}
+		s.metrics.commandSent(command)
 		s.log("Command '%s' sent (no response expected)", command)
-		return command, nil
+		return command, 0, nil
 	}
 
-	cmdID := s.generateCommandID()
 	resultCh := make(chan string, 1)
 
 	s.commandLock.Lock()
+	cmdID := s.generateCommandID()
 	s.pendingCommands[cmdID] = &PendingCommand{
 		CommandID: cmdID,
 		Command:   command,
@@ -266,14 +359,15 @@ func (s *SerialTransport) SendCommand(command string, expectResponse bool, timeo
 	}
 	s.commandLock.Unlock()
 
-	taggedCmd := fmt.Sprintf("%s#%d\r\n", command, cmdID)
-	_, err := s.serialPort.Write([]byte(taggedCmd))
+	sentAt := time.Now()
+	_, err := s.write(protocol.EncodeLine(command, cmdID))
 	if err != nil {
 		s.commandLock.Lock()
 		delete(s.pendingCommands, cmdID)
 		s.commandLock.Unlock()
-		return "", err
+		return "", cmdID, err
 	}
+	s.metrics.commandSent(command)
 
 	stopCh := s.stopChan
 
@@ -282,24 +376,31 @@ func (s *SerialTransport) SendCommand(command string, expectResponse bool, timeo
 		if idx := strings.Index(result, "#"); idx >= 0 {
 			result = result[:idx]
 		}
+		s.metrics.observeLatency(command, time.Since(sentAt))
 		s.log("Command '%s' completed", command)
-		return result, nil
+		return result, cmdID, nil
 	case <-stopCh:
 		s.commandLock.Lock()
 		delete(s.pendingCommands, cmdID)
 		s.commandLock.Unlock()
-		return "", NewConnectionError("disconnected while waiting for response")
+		return "", cmdID, NewConnectionError("disconnected while waiting for response")
 	case <-time.After(timeout):
 		s.commandLock.Lock()
 		delete(s.pendingCommands, cmdID)
 		s.commandLock.Unlock()
-		return "", NewTimeoutError(fmt.Sprintf("command timed out: %s", command))
+		s.metrics.add(&s.metrics.timeouts, 1)
+		return "", cmdID, NewTimeoutError(fmt.Sprintf("command timed out: %s", command))
 	}
 }
 
 // IsConnected returns true if the transport has an active serial connection.
 func (s *SerialTransport) IsConnected() bool {
-	return s.isConnected.Load() && s.serialPort != nil
+	return s.isConnected.Load() && s.port() != nil
+}
+
+// PortName returns the COM port in use.
+func (s *SerialTransport) PortName() string {
+	return s.Port
 }
 
 // SetButtonCallback sets a function that is called when a mouse button
@@ -309,9 +410,25 @@ func (s *SerialTransport) SetButtonCallback(cb func(MouseButton, bool)) {
 	s.buttonCallback = cb
 }
 
+// SetReconnectCallback sets a function that is called from the listener
+// goroutine after the transport reconnects on its own. It must not block on
+// commands; start a goroutine for those. Pass nil to remove the callback.
+// A MakcuController sets it to run its OnReconnect hooks; register those
+// instead when the transport belongs to a controller.
+func (s *SerialTransport) SetReconnectCallback(cb func()) {
+	s.reconnectCallback = cb
+}
+
+// Connections counts the times the link has been opened, by Connect or by
+// reconnecting. Device state such as locks may have been reset whenever it
+// changes.
+func (s *SerialTransport) Connections() uint64 {
+	return s.connections.Load()
+}
+
 // GetButtonStates returns the current pressed state of each mouse button.
 func (s *SerialTransport) GetButtonStates() map[string]bool {
-	states := make(map[string]bool, 5)
+	states := make(map[string]bool, len(buttonNames))
 	for i, name := range buttonNames {
 		states[name] = s.buttonStates&(1<<i) != 0
 	}
@@ -323,47 +440,131 @@ func (s *SerialTransport) GetButtonMask() int {
 	return s.lastButtonMask
 }
 
-// EnableButtonMonitoring enables or disables button-state monitoring on the device.
+// EnableButtonMonitoring enables or disables button-state monitoring on the
+// device, keeping framed reports if they were negotiated.
 func (s *SerialTransport) EnableButtonMonitoring(enable bool) error {
-	cmd := "km.buttons(0)"
-	if enable {
-		cmd = "km.buttons(1)"
-	}
+	cmd := s.monitorCommand(enable)
 	s.log("%s button monitoring", map[bool]string{true: "Enabling", false: "Disabling"}[enable])
 	_, err := s.SendCommand(cmd, false, 0)
 	return err
 }
 
+// SetButtonFraming makes Connect ask the device for framed button reports
+// (km.buttons(2)), in which every mask is sent as ButtonFramePrefix and the
+// mask byte, instead of a raw byte that can be mistaken for CR or LF. If
+// the device does not start framing within DefaultTimeout, Connect falls
+// back to km.buttons(1) and the heuristic parser. It only applies with
+// sendInit and takes effect on the next Connect.
+func (s *SerialTransport) SetButtonFraming(enable bool) {
+	s.framing = enable
+}
+
+// ButtonFraming reports whether the device is sending framed button
+// reports on the current connection.
+func (s *SerialTransport) ButtonFraming() bool {
+	return s.framed.Load()
+}
+
+// SetTap records every byte written to and read from the device into cw,
+// or stops recording when cw is nil. It takes effect on the next Connect
+// (or reconnect); the baud-change handshake is not captured.
+func (s *SerialTransport) SetTap(cw *CaptureWriter) {
+	s.tap = cw
+}
+
+// Metrics returns a snapshot of the transport's counters and latency
+// histograms. They accumulate over the transport's lifetime, across
+// reconnects.
+func (s *SerialTransport) Metrics() Metrics {
+	return s.metrics.snapshot(s.isConnected.Load())
+}
+
 // --- internal methods ---
 
-// changeBaudTo4M sends the baud-change magic bytes and switches to 4 000 000 baud.
-func (s *SerialTransport) changeBaudTo4M() error {
-	s.log("Changing baud rate to 4M")
+// serialTransport implements serialBacked.
+func (s *SerialTransport) serialTransport() *SerialTransport {
+	return s
+}
+
+// port returns the current port, or nil when closed.
+func (s *SerialTransport) port() Port {
+	s.portMu.RLock()
+	defer s.portMu.RUnlock()
+	return s.serialPort
+}
+
+func (s *SerialTransport) setPort(p Port) {
+	s.portMu.Lock()
+	s.serialPort = p
+	s.portMu.Unlock()
+}
 
-	if s.serialPort == nil {
-		return NewConnectionError("serial port not open")
+// write sends raw bytes to the device, counting them.
+func (s *SerialTransport) write(b []byte) (int, error) {
+	sp := s.port()
+	if sp == nil {
+		return 0, NewConnectionError("not connected")
 	}
+	n, err := sp.Write(b)
+	s.metrics.add(&s.metrics.bytesOut, uint64(n))
+	return n, err
+}
 
-	_, err := s.serialPort.Write(baudChangeCommand)
-	if err != nil {
-		return err
+// open opens the named port with the configured opener, or as a serial
+// port switched to 4M baud, wrapped in the tap if one is set.
+func (s *SerialTransport) open(name string) (Port, error) {
+	var p Port
+	var err error
+	if s.openPort != nil {
+		p, err = s.openPort(name)
+	} else {
+		p, err = openSerial(name, s.log)
+	}
+	if err != nil || s.tap == nil {
+		return p, err
 	}
+	return &tapPort{Port: p, cw: s.tap}, nil
+}
 
-	time.Sleep(20 * time.Millisecond)
+// monitorCommand returns the command EnableButtonMonitoring sends: it
+// disables button reports, or enables them in the negotiated format.
+func (s *SerialTransport) monitorCommand(enable bool) string {
+	if !enable {
+		return "km.buttons(0)"
+	}
+	if s.framed.Load() {
+		return "km.buttons(2)"
+	}
+	return "km.buttons(1)"
+}
 
-	err = s.serialPort.SetMode(&serial.Mode{
-		BaudRate: 4000000,
-		DataBits: 8,
-		StopBits: serial.OneStopBit,
-		Parity:   serial.NoParity,
-	})
-	if err != nil {
-		return err
+// negotiateFraming asks the device for framed button reports. A device
+// that supports them answers with a frame carrying the current mask; the
+// listener sees it and sets framed. Otherwise it falls back to raw masks,
+// unless the connection changed in the meantime.
+func (s *SerialTransport) negotiateFraming() {
+	conns := s.connections.Load()
+	s.log("Requesting framed button reports")
+	s.framesAsked.Store(true)
+	s.write([]byte("km.buttons(2)\r"))
+	s.metrics.commandSent("km.buttons(2)")
+
+	deadline := time.Now().Add(DefaultTimeout)
+	for time.Now().Before(deadline) {
+		if s.framed.Load() {
+			s.log("Framed button reports enabled")
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if s.connections.Load() != conns {
+		return
 	}
 
-	s.currentBaud = 4000000
-	s.log("Baud rate changed: 115200 -> 4000000")
-	return nil
+	s.log("Device did not frame button reports, falling back to raw mask bytes")
+	s.framesAsked.Store(false)
+	s.write([]byte("km.buttons(1)\r"))
+	s.metrics.commandSent("km.buttons(1)")
 }
 
 // parseResponseLine extracts the content from a raw response line (strips ">>> " prefix).
@@ -394,6 +595,9 @@ func (s *SerialTransport) handleButtonData(byteVal int) {
 				s.buttonStates &= ^(1 << bit)
 			}
 
+			if bit < len(buttonNames) {
+				s.metrics.buttonEvent(buttonNames[bit])
+			}
 			if s.buttonCallback != nil && bit < len(buttonEnumMap) {
 				s.buttonCallback(buttonEnumMap[bit], isPressed)
 			}
@@ -448,20 +652,23 @@ func (s *SerialTransport) cleanupTimedOutCommands() {
 		if now.Sub(pending.Timestamp) > time.Second {
 			s.log("Cleaning up stale command '%s'", pending.Command)
 			delete(s.pendingCommands, id)
+			s.metrics.add(&s.metrics.stale, 1)
 		}
 	}
 }
 
-// listen is the background goroutine that reads serial data, parsing text responses
-// and button-state bytes. The protocol distinguishes printable text lines (terminated
-// by CR+LF) from raw button data (bytes < 32).
+// listen is the background goroutine that reads serial data and feeds it to
+// a StreamParser, routing text responses to pending commands and button-mask
+// bytes to handleButtonData.
 func (s *SerialTransport) listen() {
 	s.log("Listener goroutine started")
 
-	lineBuffer := make([]byte, 256)
-	linePos := 0
-	expectingTextMode := false
-	lastByte := -1 // -1 = no previous byte
+	parser := NewStreamParser(func(line []byte) {
+		if content := s.parseResponseLine(line); content != "" {
+			s.processPendingCommands(content)
+		}
+	}, s.handleButtonData)
+	parser.SetMask(s.lastButtonMask)
 
 	readBuf := make([]byte, 4096)
 	lastCleanup := time.Now()
@@ -475,12 +682,18 @@ func (s *SerialTransport) listen() {
 		default:
 		}
 
-		n, err := s.serialPort.Read(readBuf)
+		sp := s.port()
+		if sp == nil {
+			break
+		}
+		n, err := sp.Read(readBuf)
 		if err != nil {
 			if s.isConnected.Load() {
 				s.log("Serial read error: %v", err)
 				if s.autoReconnect {
 					s.attemptReconnect()
+					// The device resends a frame if it is still framing.
+					parser.SetFramed(false)
 				} else {
 					return
 				}
@@ -490,88 +703,14 @@ func (s *SerialTransport) listen() {
 		if n == 0 {
 			continue
 		}
+		s.metrics.add(&s.metrics.bytesIn, uint64(n))
 
-		for i := 0; i < n; i++ {
-			b := int(readBuf[i])
-
-			switch {
-			// Case 1: CR+LF — complete a text line.
-			case lastByte == 0x0D && b == 0x0A:
-				if linePos > 0 {
-					content := s.parseResponseLine(lineBuffer[:linePos])
-					linePos = 0
-					if content != "" {
-						s.processPendingCommands(content)
-					}
-				}
-				expectingTextMode = false
-
-			// Case 2: printable ASCII or TAB — accumulate text.
-			case b >= 32 || b == 0x09:
-				expectingTextMode = true
-				if linePos < 256 {
-					lineBuffer[linePos] = byte(b)
-					linePos++
-				}
-
-			// Case 3: CR — may be start of CRLF.
-			case b == 0x0D:
-				if expectingTextMode || linePos > 0 {
-					expectingTextMode = true
-				}
-
-			// Case 4: bare LF — disambiguate between text terminator and button data (0x0A = right+mouse4).
-			case b == 0x0A:
-				buttonCombo := false
-
-				if s.lastButtonMask != 0 ||
-					(lastByte >= 0 && lastByte < 32 && lastByte != 0x0D) ||
-					(linePos > 0 && !expectingTextMode) {
-					s.handleButtonData(b)
-					expectingTextMode = false
-					buttonCombo = true
-					linePos = 0
-				}
-
-				if !buttonCombo {
-					if lastByte == 0x0D {
-						// Completing CRLF
-						if linePos > 0 {
-							content := s.parseResponseLine(lineBuffer[:linePos])
-							linePos = 0
-							if content != "" {
-								s.processPendingCommands(content)
-							}
-						}
-						expectingTextMode = false
-					} else if linePos > 0 && expectingTextMode {
-						// LF-only line end
-						content := s.parseResponseLine(lineBuffer[:linePos])
-						linePos = 0
-						if content != "" {
-							s.processPendingCommands(content)
-						}
-						expectingTextMode = false
-					} else if expectingTextMode {
-						expectingTextMode = false
-					} else {
-						s.handleButtonData(b)
-						expectingTextMode = false
-						linePos = 0
-					}
-				}
-
-			// Case 5: other control bytes (< 32, excluding TAB/CR/LF) — button data.
-			default:
-				if lastByte == 0x0D {
-					s.handleButtonData(0x0D)
-				}
-				s.handleButtonData(b)
-				expectingTextMode = false
-				linePos = 0
-			}
-
-			lastByte = b
+		parser.AcceptFrames(s.framesAsked.Load())
+		for _, b := range readBuf[:n] {
+			parser.Feed(b)
+		}
+		if parser.Framed() && !s.framed.Load() {
+			s.framed.Store(true)
 		}
 
 		// Periodic cleanup of timed-out commands.
@@ -595,9 +734,10 @@ func (s *SerialTransport) attemptReconnect() {
 	}
 
 	s.reconnectAttempts++
+	s.metrics.add(&s.metrics.reconnectAttempts, 1)
 
-	if s.serialPort != nil {
-		s.serialPort.Close()
+	if sp := s.port(); sp != nil {
+		sp.Close()
 	}
 
 	time.Sleep(reconnectDelay)
@@ -611,34 +751,34 @@ func (s *SerialTransport) attemptReconnect() {
 
 	s.Port = port
 
-	mode := &serial.Mode{
-		BaudRate: 115200,
-		DataBits: 8,
-		StopBits: serial.OneStopBit,
-		Parity:   serial.NoParity,
-	}
-
-	sp, err := serial.Open(s.Port, mode)
+	sp, err := s.open(s.Port)
 	if err != nil {
-		s.log("Reconnect open failed: %v", err)
+		s.log("Reconnect failed: %v", err)
 		time.Sleep(reconnectDelay)
 		return
 	}
 
-	s.serialPort = sp
+	s.setPort(sp)
 
-	if err := s.changeBaudTo4M(); err != nil {
-		s.log("Reconnect baud change failed: %v", err)
-		s.serialPort.Close()
-		time.Sleep(reconnectDelay)
-		return
-	}
-
-	if s.sendInit {
-		s.serialPort.Write([]byte("km.buttons(1)\r"))
+	// Assume the same device: ask for frames again if it sent them, with
+	// the same fallback as Connect. That waits on the listener, which is
+	// the caller, so it runs once the reconnect is done.
+	reframe := s.framed.Swap(false) && s.sendInit
+	s.framesAsked.Store(false)
+	if s.sendInit && !reframe {
+		s.write([]byte("km.buttons(1)\r"))
+		s.metrics.commandSent("km.buttons(1)")
 	}
 
-	s.serialPort.SetReadTimeout(time.Millisecond)
+	sp.SetReadTimeout(time.Millisecond)
 	s.reconnectAttempts = 0
+	s.connections.Add(1)
+	if reframe {
+		go s.negotiateFraming()
+	}
+	s.metrics.add(&s.metrics.reconnects, 1)
 	s.log("Reconnect successful")
+	if s.reconnectCallback != nil {
+		s.reconnectCallback()
+	}
 }
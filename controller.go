@@ -3,6 +3,9 @@ package Macku
 import (
 	"fmt"
 	"math/rand"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -13,9 +16,13 @@ type Config struct {
 	SendInit        bool   // Send km.buttons(1) on connect
 	AutoReconnect   bool   // Auto-reconnect on serial errors
 	OverridePort    bool   // Skip auto-detection and use FallbackCOMPort directly
+	FramedButtons   bool   // Negotiate framed button reports on connect (needs SendInit)
+	DetectFirmware  bool   // Resolve firmware capabilities on connect (opt-in)
+	SyncLocks       bool   // Read lock states on connect and after reconnects (opt-in)
 }
 
-// DefaultConfig returns a Config with sensible defaults (SendInit and AutoReconnect enabled).
+// DefaultConfig returns a Config with sensible defaults (SendInit and
+// AutoReconnect enabled).
 func DefaultConfig() Config {
 	return Config{
 		SendInit:      true,
@@ -38,11 +45,26 @@ var clickProfiles = map[ClickProfile]timingProfile{
 
 // MakcuController is the high-level API for interacting with a Makcu device.
 type MakcuController struct {
-	Transport *SerialTransport
+	Transport Transport
 	Mouse     *Mouse
 
-	connected           bool
-	connectionCallbacks []func(bool)
+	connected           atomic.Bool
+	connMu              sync.Mutex
+	connectionCallbacks []*func(bool)
+	reconnectHooks      []*func() error
+	onReconnectError    func(error)
+	hooksMu             sync.Mutex // runs the reconnect hooks one reconnect at a time
+	detectFirmware      atomic.Bool
+	lockSync            lockSync
+	handlers            buttonHandlers
+}
+
+// lockSync holds the state of Config.SyncLocks.
+type lockSync struct {
+	mu      sync.Mutex
+	enabled bool
+	unhook  func() // removes the reconnect hook
+	onError func(error)
 }
 
 // NewController creates (but does not connect) a new MakcuController.
@@ -54,10 +76,41 @@ func NewController(cfg Config) *MakcuController {
 		cfg.AutoReconnect,
 		cfg.OverridePort,
 	)
-	return &MakcuController{
+	transport.SetButtonFraming(cfg.FramedButtons)
+	c := NewControllerWithTransport(transport)
+	c.SetDetectFirmware(cfg.DetectFirmware)
+	c.SetSyncLocks(cfg.SyncLocks)
+	return c
+}
+
+// NewControllerWithTransport creates (but does not connect) a MakcuController
+// that talks to the device through the given Transport.
+func NewControllerWithTransport(transport Transport) *MakcuController {
+	c := &MakcuController{
 		Transport: transport,
 		Mouse:     NewMouse(transport),
 	}
+	if rc, ok := transport.(interface{ SetReconnectCallback(func()) }); ok {
+		rc.SetReconnectCallback(c.transportReconnected)
+	}
+	return c
+}
+
+// serialBacked is implemented by *SerialTransport and the transports that
+// embed it.
+type serialBacked interface {
+	serialTransport() *SerialTransport
+}
+
+// SerialTransport returns the SerialTransport behind c.Transport, e.g. for
+// SetTap or Metrics, or nil if it is not one (a daemon client, a recorder).
+// Code written against the v2 field, c.Transport.SetTap(cw), becomes
+// c.SerialTransport().SetTap(cw).
+func (c *MakcuController) SerialTransport() *SerialTransport {
+	if sb, ok := c.Transport.(serialBacked); ok {
+		return sb.serialTransport()
+	}
+	return nil
 }
 
 // CreateController creates a MakcuController and connects it immediately.
@@ -70,26 +123,49 @@ func CreateController(cfg Config) (*MakcuController, error) {
 }
 
 func (c *MakcuController) checkConnection() error {
-	if !c.connected {
+	if !c.connected.Load() {
 		return NewConnectionError("not connected")
 	}
 	return nil
 }
 
 func (c *MakcuController) notifyConnectionChange(connected bool) {
-	for _, cb := range c.connectionCallbacks {
-		cb(connected)
+	c.connMu.Lock()
+	callbacks := slices.Clone(c.connectionCallbacks)
+	c.connMu.Unlock()
+	for _, cb := range callbacks {
+		(*cb)(connected)
 	}
 }
 
 // --- connection ---
 
-// Connect opens the serial connection to the Makcu device.
+// Connect opens the serial connection to the Makcu device. With
+// Config.DetectFirmware it then resolves the firmware capabilities; if that
+// fails, the connection is closed again and the error returned. Lock states
+// from an earlier connection are dropped, and read again with
+// Config.SyncLocks; targets that cannot be read are reported to
+// OnLockSyncError.
 func (c *MakcuController) Connect() error {
 	if err := c.Transport.Connect(); err != nil {
 		return err
 	}
-	c.connected = true
+	c.Mouse.InvalidateCache()
+	if c.detectFirmware.Load() {
+		if _, err := c.Mouse.DetectCapabilities(); err != nil {
+			c.Transport.Disconnect()
+			return fmt.Errorf("detect firmware capabilities: %w", err)
+		}
+	}
+	c.lockSync.mu.Lock()
+	enabled, onError := c.lockSync.enabled, c.lockSync.onError
+	c.lockSync.mu.Unlock()
+	if enabled {
+		if _, err := c.Mouse.RefreshLocks(); err != nil && onError != nil {
+			onError(err)
+		}
+	}
+	c.connected.Store(true)
 	c.notifyConnectionChange(true)
 	return nil
 }
@@ -97,20 +173,27 @@ func (c *MakcuController) Connect() error {
 // Disconnect closes the serial connection.
 func (c *MakcuController) Disconnect() error {
 	err := c.Transport.Disconnect()
-	c.connected = false
+	c.connected.Store(false)
 	c.notifyConnectionChange(false)
 	return err
 }
 
 // IsConnected returns true if the controller has an active device connection.
 func (c *MakcuController) IsConnected() bool {
-	return c.connected && c.Transport.IsConnected()
+	return c.connected.Load() && c.Transport.IsConnected()
 }
 
 // --- basic mouse actions ---
 
+// MaxClickCount is the most clicks the CLI, HTTP API and script builtins
+// send for one request, so a single call cannot keep the device busy
+// indefinitely.
+const MaxClickCount = 1000
+
 // Click presses and releases a mouse button.
-func (c *MakcuController) Click(button MouseButton) error {
+func (c *MakcuController) Click(button MouseButton) (err error) {
+	end := c.startSpan("makcu.Click", "makcu.button", button.String())
+	defer func() { end(err) }()
 	if err := c.checkConnection(); err != nil {
 		return err
 	}
@@ -164,14 +247,35 @@ func (c *MakcuController) Move(dx, dy int) error {
 	return c.Mouse.Move(dx, dy)
 }
 
-// MoveAbs moves the cursor to an absolute screen position (Windows only).
-func (c *MakcuController) MoveAbs(target [2]int, speed, waitMs int) error {
+// MoveAbs moves the cursor to an absolute screen position. On platforms other
+// than Windows a CursorProvider must be set on the Mouse first.
+func (c *MakcuController) MoveAbs(target [2]int, speed, waitMs int) (err error) {
+	end := c.startSpan("makcu.MoveAbs", "makcu.target.x", target[0], "makcu.target.y", target[1], "makcu.speed", speed)
+	defer func() { end(err) }()
 	if err := c.checkConnection(); err != nil {
 		return err
 	}
 	return c.Mouse.MoveAbs(target, speed, waitMs)
 }
 
+// MoveAbsWithOptions moves the cursor to an absolute screen position using the
+// closed-loop controller and reports iterations, final error and elapsed time.
+func (c *MakcuController) MoveAbsWithOptions(target [2]int, opts MoveAbsOptions) (MoveAbsResult, error) {
+	if err := c.checkConnection(); err != nil {
+		return MoveAbsResult{}, err
+	}
+	return c.Mouse.MoveAbsWithOptions(target, opts)
+}
+
+// CalibrateAcceleration fits pointer acceleration models from observed moves.
+// Pass Calibration.Curve to Mouse.SetAccelerationModel to use it for MoveAbs.
+func (c *MakcuController) CalibrateAcceleration(opts CalibrationOptions) (Calibration, error) {
+	if err := c.checkConnection(); err != nil {
+		return Calibration{}, err
+	}
+	return c.Mouse.CalibrateAcceleration(opts)
+}
+
 // MoveSmooth performs a segmented smooth relative movement.
 func (c *MakcuController) MoveSmooth(dx, dy, segments int) error {
 	if err := c.checkConnection(); err != nil {
@@ -308,14 +412,129 @@ func (c *MakcuController) IsLocked(button MouseButton) (bool, error) {
 	return c.Mouse.IsLocked(button)
 }
 
-// GetAllLockStates returns the lock state for every button and axis.
-func (c *MakcuController) GetAllLockStates() (map[string]bool, error) {
+// GetAllLockStates returns the lock state for every button and axis. If
+// some could not be read, it returns the others with a *LockQueryError.
+func (c *MakcuController) GetAllLockStates() (states map[string]bool, err error) {
+	end := c.startSpan("makcu.GetAllLockStates")
+	defer func() { end(err) }()
 	if err := c.checkConnection(); err != nil {
 		return nil, err
 	}
 	return c.Mouse.GetAllLockStates()
 }
 
+// RefreshLocks reads every lock state from the device, ignoring what is
+// cached. If some could not be read, it returns the others with a
+// *LockQueryError.
+func (c *MakcuController) RefreshLocks() (map[string]bool, error) {
+	if err := c.checkConnection(); err != nil {
+		return nil, err
+	}
+	return c.Mouse.RefreshLocks()
+}
+
+// SetDetectFirmware turns Config.DetectFirmware on or off for the next
+// Connect.
+func (c *MakcuController) SetDetectFirmware(on bool) {
+	c.detectFirmware.Store(on)
+}
+
+// SetSyncLocks turns Config.SyncLocks on or off: lock states are read on
+// connect and, for transports that report reconnects, again after each
+// reconnect.
+func (c *MakcuController) SetSyncLocks(on bool) {
+	c.lockSync.mu.Lock()
+	defer c.lockSync.mu.Unlock()
+	c.lockSync.enabled = on
+	switch {
+	case on && c.lockSync.unhook == nil:
+		c.lockSync.unhook = c.OnReconnect(func() error {
+			c.refreshLocksForSync()
+			return nil
+		})
+	case !on && c.lockSync.unhook != nil:
+		c.lockSync.unhook()
+		c.lockSync.unhook = nil
+	}
+}
+
+// OnLockSyncError sets a callback for lock states that could not be read
+// by SyncLocks, on connect or after a reconnect. err is usually a
+// *LockQueryError. Pass nil to remove it.
+func (c *MakcuController) OnLockSyncError(cb func(error)) {
+	c.lockSync.mu.Lock()
+	defer c.lockSync.mu.Unlock()
+	c.lockSync.onError = cb
+}
+
+// refreshLocksForSync reads the lock states, reporting failures to the
+// OnLockSyncError callback.
+func (c *MakcuController) refreshLocksForSync() {
+	_, err := c.Mouse.RefreshLocks()
+	c.lockSync.mu.Lock()
+	onError := c.lockSync.onError
+	c.lockSync.mu.Unlock()
+	if err != nil && onError != nil {
+		onError(err)
+	}
+}
+
+// OnReconnect registers fn to run after the transport reconnects on its
+// own, for transports that report it (SerialTransport and those embedding
+// it), e.g. to restore device state the reconnect lost. Hooks run in the
+// order registered, on a goroutine of their own and one reconnect at a
+// time; errors they return go to the OnReconnectError callback. The
+// returned function removes fn.
+func (c *MakcuController) OnReconnect(fn func() error) (remove func()) {
+	p := &fn
+	c.connMu.Lock()
+	c.reconnectHooks = append(c.reconnectHooks, p)
+	c.connMu.Unlock()
+	return func() {
+		c.connMu.Lock()
+		defer c.connMu.Unlock()
+		c.reconnectHooks = slices.DeleteFunc(c.reconnectHooks, func(h *func() error) bool { return h == p })
+	}
+}
+
+// OnReconnectError sets a callback for errors returned by OnReconnect
+// hooks, such as an interception or WithLocks lock that could not be
+// applied again. Pass nil to remove it.
+func (c *MakcuController) OnReconnectError(cb func(error)) {
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+	c.onReconnectError = cb
+}
+
+// transportReconnected is the transport's reconnect callback. It runs on
+// the listener goroutine, so the hooks, which send commands, run on
+// another.
+func (c *MakcuController) transportReconnected() {
+	c.connMu.Lock()
+	hooks := slices.Clone(c.reconnectHooks)
+	c.connMu.Unlock()
+	go func() {
+		c.hooksMu.Lock()
+		defer c.hooksMu.Unlock()
+		for _, h := range hooks {
+			if err := (*h)(); err != nil {
+				c.connMu.Lock()
+				onError := c.onReconnectError
+				c.connMu.Unlock()
+				if onError != nil {
+					onError(err)
+				}
+			}
+		}
+	}()
+}
+
+// LockStatuses returns what is known about each lock target, including
+// whether it is stale, without querying the device.
+func (c *MakcuController) LockStatuses() map[string]LockStatus {
+	return c.Mouse.LockStatuses()
+}
+
 // --- serial spoofing ---
 
 // SpoofSerial sets a custom serial number on the device.
@@ -352,6 +571,35 @@ func (c *MakcuController) GetFirmwareVersion() (string, error) {
 	return c.Mouse.GetFirmwareVersion()
 }
 
+// GetFirmwareVersionInfo queries the device for its firmware version and
+// parses it.
+func (c *MakcuController) GetFirmwareVersionInfo() (FirmwareVersion, error) {
+	if err := c.checkConnection(); err != nil {
+		return FirmwareVersion{}, err
+	}
+	resp, err := c.Mouse.GetFirmwareVersion()
+	if err != nil {
+		return FirmwareVersion{}, err
+	}
+	return ParseFirmwareVersion(resp), nil
+}
+
+// Capabilities returns what the firmware supports, as resolved at connect
+// or by DetectCapabilities.
+func (c *MakcuController) Capabilities() Capabilities {
+	return c.Mouse.Capabilities()
+}
+
+// DetectCapabilities queries the firmware version and resolves its
+// capabilities. Afterwards commands the firmware lacks return
+// ErrUnsupported instead of being sent.
+func (c *MakcuController) DetectCapabilities() (Capabilities, error) {
+	if err := c.checkConnection(); err != nil {
+		return Capabilities{}, err
+	}
+	return c.Mouse.DetectCapabilities()
+}
+
 // --- button monitoring ---
 
 // GetButtonMask returns the raw button bitmask.
@@ -388,27 +636,48 @@ func (c *MakcuController) EnableButtonMonitoring(enable bool) error {
 }
 
 // SetButtonCallback sets a callback invoked when a mouse button state changes.
+// Intercepted changes are included; use SetButtonEventCallback to tell them
+// apart. Removing the callback with nil also works while disconnected.
 func (c *MakcuController) SetButtonCallback(cb func(MouseButton, bool)) error {
-	if err := c.checkConnection(); err != nil {
-		return err
+	if cb != nil {
+		if err := c.checkConnection(); err != nil {
+			return err
+		}
 	}
-	c.Transport.SetButtonCallback(cb)
+	c.handlers.mu.Lock()
+	c.handlers.button = cb
+	c.handlers.mu.Unlock()
+	c.installButtonHandler()
 	return nil
 }
 
 // --- connection callbacks ---
 
-// OnConnectionChange registers a callback invoked when the connection state changes.
-func (c *MakcuController) OnConnectionChange(cb func(bool)) {
-	c.connectionCallbacks = append(c.connectionCallbacks, cb)
+// OnConnectionChange registers a callback invoked when the connection state
+// changes. Calling the returned function unregisters exactly this
+// registration.
+func (c *MakcuController) OnConnectionChange(cb func(bool)) (remove func()) {
+	entry := &cb
+	c.connMu.Lock()
+	c.connectionCallbacks = append(c.connectionCallbacks, entry)
+	c.connMu.Unlock()
+	return func() {
+		c.connMu.Lock()
+		defer c.connMu.Unlock()
+		c.connectionCallbacks = slices.DeleteFunc(c.connectionCallbacks, func(e *func(bool)) bool { return e == entry })
+	}
 }
 
 // RemoveConnectionCallback removes a previously registered connection callback.
-// Comparison is done by matching the function pointer.
+// Comparison is done by matching the function pointer, so method values of
+// the same method cannot be told apart; prefer the function returned by
+// OnConnectionChange.
 func (c *MakcuController) RemoveConnectionCallback(cb func(bool)) {
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
 	for i, existing := range c.connectionCallbacks {
 		// Best-effort comparison via fmt pointer
-		if fmt.Sprintf("%p", existing) == fmt.Sprintf("%p", cb) {
+		if fmt.Sprintf("%p", *existing) == fmt.Sprintf("%p", cb) {
 			c.connectionCallbacks = append(c.connectionCallbacks[:i], c.connectionCallbacks[i+1:]...)
 			return
 		}
@@ -420,7 +689,9 @@ func (c *MakcuController) RemoveConnectionCallback(cb func(bool)) {
 // ClickHumanLike performs one or more human-like clicks with randomised timing.
 // Supported profiles: "normal", "fast", "slow", "variable", "gaming".
 // jitter adds random pixel movement before each click.
-func (c *MakcuController) ClickHumanLike(button MouseButton, count int, profile ClickProfile, jitter int) error {
+func (c *MakcuController) ClickHumanLike(button MouseButton, count int, profile ClickProfile, jitter int) (err error) {
+	end := c.startSpan("makcu.ClickHumanLike", "makcu.button", button.String(), "makcu.count", count, "makcu.profile", string(profile))
+	defer func() { end(err) }()
 	if err := c.checkConnection(); err != nil {
 		return err
 	}
@@ -434,12 +705,18 @@ func (c *MakcuController) ClickHumanLike(button MouseButton, count int, profile
 		if jitter > 0 {
 			dx := rand.Intn(2*jitter+1) - jitter
 			dy := rand.Intn(2*jitter+1) - jitter
-			c.Mouse.Move(dx, dy)
+			if err := c.Mouse.Move(dx, dy); err != nil {
+				return err
+			}
 		}
 
-		c.Mouse.Press(button)
+		if err := c.Mouse.Press(button); err != nil {
+			return err
+		}
 		time.Sleep(time.Duration(rand.Intn(p.maxDown-p.minDown)+p.minDown) * time.Millisecond)
-		c.Mouse.Release(button)
+		if err := c.Mouse.Release(button); err != nil {
+			return err
+		}
 
 		if i < count-1 {
 			time.Sleep(time.Duration(rand.Intn(p.maxWait-p.minWait)+p.minWait) * time.Millisecond)
@@ -451,7 +728,9 @@ func (c *MakcuController) ClickHumanLike(button MouseButton, count int, profile
 
 // Drag performs a mouse drag: moves to (startX,startY), holds the button,
 // smooth-moves to (endX,endY), then releases.
-func (c *MakcuController) Drag(startX, startY, endX, endY int, button MouseButton, duration time.Duration) error {
+func (c *MakcuController) Drag(startX, startY, endX, endY int, button MouseButton, duration time.Duration) (err error) {
+	end := c.startSpan("makcu.Drag", "makcu.button", button.String(), "makcu.duration_ms", int(duration/time.Millisecond))
+	defer func() { end(err) }()
 	if err := c.checkConnection(); err != nil {
 		return err
 	}
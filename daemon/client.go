@@ -0,0 +1,265 @@
+package daemon
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	Macku "github.com/Auchrio/Makcu-go-lib/v3"
+)
+
+// callGrace is added to a command's device timeout while waiting for the
+// daemon's reply, covering the socket round trip.
+const callGrace = 2 * time.Second
+
+// Client is a Macku.Transport that forwards every command to a makcud
+// daemon. Pass it to Macku.NewControllerWithTransport to use the normal
+// controller API against a shared device:
+//
+//	c := Macku.NewControllerWithTransport(daemon.NewClient(daemon.DefaultSocketPath()))
+type Client struct {
+	path string
+
+	mu        sync.Mutex
+	conn      net.Conn
+	nextID    uint64
+	pending   map[uint64]chan *Message
+	callback  func(Macku.MouseButton, bool)
+	mask      int
+	port      string
+	connected bool
+	done      chan struct{}
+
+	wmu sync.Mutex
+}
+
+var _ Macku.Transport = (*Client)(nil)
+
+// NewClient creates (but does not connect) a Client for the daemon socket at path.
+func NewClient(path string) *Client {
+	return &Client{path: path}
+}
+
+// Connect dials the daemon, subscribes to button and connection events and
+// fetches the current button mask.
+func (cl *Client) Connect() error {
+	cl.mu.Lock()
+	if cl.connected {
+		cl.mu.Unlock()
+		return nil
+	}
+	conn, err := net.Dial("unix", cl.path)
+	if err != nil {
+		cl.mu.Unlock()
+		return Macku.NewConnectionError(fmt.Sprintf("failed to reach daemon at %s: %v", cl.path, err))
+	}
+	cl.conn = conn
+	cl.pending = make(map[uint64]chan *Message)
+	cl.connected = true
+	cl.done = make(chan struct{})
+	cl.mu.Unlock()
+
+	go cl.readLoop(conn, cl.done)
+
+	setup := func() error {
+		if err := cl.call(MethodSubscribe, SubscribeParams{Events: []string{EventButton, EventConnection}}, nil, callGrace); err != nil {
+			return err
+		}
+		var mask int
+		if err := cl.call(MethodButtonMask, nil, &mask, callGrace); err != nil {
+			return err
+		}
+		var port string
+		if err := cl.call(MethodPort, nil, &port, callGrace); err != nil {
+			return err
+		}
+		cl.mu.Lock()
+		cl.mask, cl.port = mask, port
+		cl.mu.Unlock()
+		return nil
+	}
+	if err := setup(); err != nil {
+		cl.Disconnect()
+		return err
+	}
+	return nil
+}
+
+// Disconnect closes the socket. The daemon keeps its device connection.
+func (cl *Client) Disconnect() error {
+	cl.mu.Lock()
+	conn, done := cl.conn, cl.done
+	cl.mu.Unlock()
+	if conn == nil {
+		return nil
+	}
+	err := conn.Close()
+	<-done
+	return err
+}
+
+// IsConnected reports whether the socket to the daemon is open.
+func (cl *Client) IsConnected() bool {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	return cl.connected
+}
+
+// SendCommand forwards a raw command to the daemon's device.
+func (cl *Client) SendCommand(command string, expectResponse bool, timeout time.Duration) (string, error) {
+	var res SendResult
+	params := SendParams{Command: command, ExpectResponse: expectResponse, TimeoutMs: int(timeout / time.Millisecond)}
+	if err := cl.call(MethodSend, params, &res, timeout+callGrace); err != nil {
+		return "", err
+	}
+	return res.Response, nil
+}
+
+// SetButtonCallback sets the callback invoked for button events relayed by
+// the daemon. It runs on the client's read loop, so it must not block on
+// further daemon calls.
+func (cl *Client) SetButtonCallback(cb func(Macku.MouseButton, bool)) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	cl.callback = cb
+}
+
+// GetButtonStates returns the button states last reported by the daemon.
+func (cl *Client) GetButtonStates() map[string]bool {
+	mask := cl.GetButtonMask()
+	states := make(map[string]bool)
+	for b := Macku.MouseButtonLeft; b <= Macku.MouseButton8; b++ {
+		states[b.String()] = mask&(1<<uint(b)) != 0
+	}
+	return states
+}
+
+// GetButtonMask returns the button bitmask last reported by the daemon.
+func (cl *Client) GetButtonMask() int {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	return cl.mask
+}
+
+// EnableButtonMonitoring enables or disables button reports on the device.
+// The daemon's controller sends the command, so reports stay in the format
+// it negotiated, framed or raw, for every client.
+func (cl *Client) EnableButtonMonitoring(enable bool) error {
+	return cl.call(MethodMonitorButtons, MonitorParams{Enable: enable}, nil, Macku.DefaultTimeout+callGrace)
+}
+
+// PortName returns the daemon's serial port, prefixed with the socket path.
+func (cl *Client) PortName() string {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	return fmt.Sprintf("%s (via %s)", cl.port, cl.path)
+}
+
+// call performs one RPC and decodes its result into out (if non-nil).
+func (cl *Client) call(method string, params, out interface{}, timeout time.Duration) error {
+	req := Request{Method: method}
+	if params != nil {
+		raw, err := json.Marshal(params)
+		if err != nil {
+			return Macku.NewCommandError(err.Error())
+		}
+		req.Params = raw
+	}
+
+	cl.mu.Lock()
+	if !cl.connected {
+		cl.mu.Unlock()
+		return Macku.NewConnectionError("not connected to daemon")
+	}
+	cl.nextID++
+	req.ID = cl.nextID
+	ch := make(chan *Message, 1)
+	cl.pending[req.ID] = ch
+	conn := cl.conn
+	cl.mu.Unlock()
+
+	defer func() {
+		cl.mu.Lock()
+		delete(cl.pending, req.ID)
+		cl.mu.Unlock()
+	}()
+
+	data, _ := json.Marshal(req)
+	cl.wmu.Lock()
+	_, err := conn.Write(append(data, '\n'))
+	cl.wmu.Unlock()
+	if err != nil {
+		return Macku.NewConnectionError(fmt.Sprintf("daemon write failed: %v", err))
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case msg, ok := <-ch:
+		if !ok {
+			return Macku.NewConnectionError("daemon connection closed")
+		}
+		if msg.Error != nil {
+			return fromError(msg.Error)
+		}
+		if out != nil {
+			if err := json.Unmarshal(msg.Result, out); err != nil {
+				return Macku.NewResponseError(fmt.Sprintf("%s: bad result: %v", method, err))
+			}
+		}
+		return nil
+	case <-timer.C:
+		return Macku.NewTimeoutError(fmt.Sprintf("daemon did not answer %s within %v", method, timeout))
+	}
+}
+
+func (cl *Client) readLoop(conn net.Conn, done chan struct{}) {
+	defer func() {
+		cl.mu.Lock()
+		cl.connected = false
+		cl.conn = nil
+		for id, ch := range cl.pending {
+			close(ch)
+			delete(cl.pending, id)
+		}
+		cl.mu.Unlock()
+		close(done)
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 4096), maxLineSize)
+	for scanner.Scan() {
+		var msg Message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			continue
+		}
+		if msg.Event != nil {
+			cl.handleEvent(msg.Event)
+			continue
+		}
+		cl.mu.Lock()
+		ch := cl.pending[msg.ID]
+		delete(cl.pending, msg.ID)
+		cl.mu.Unlock()
+		if ch != nil {
+			ch <- &msg
+		}
+	}
+}
+
+func (cl *Client) handleEvent(ev *Event) {
+	cl.mu.Lock()
+	cl.mask = ev.Mask
+	cb := cl.callback
+	cl.mu.Unlock()
+
+	if ev.Type != EventButton || cb == nil {
+		return
+	}
+	if b, err := Macku.ParseMouseButton(ev.Button); err == nil {
+		cb(b, ev.Pressed)
+	}
+}
@@ -0,0 +1,187 @@
+// Package daemon shares one MakcuController between several processes. A
+// Server owns the controller and serves a line-delimited JSON RPC over a Unix
+// domain socket; Client implements Macku.Transport on top of that RPC so
+// existing code can drive the device through the daemon unchanged.
+//
+// Each line sent to the server is a Request; each line sent back is a
+// Message carrying either the response to a request (matched by ID) or an
+// Event for subscribed connections:
+//
+//	-> {"id":1,"method":"send","params":{"command":"km.version()","expect_response":true}}
+//	<- {"id":1,"result":{"response":"km.MAKCU"}}
+//	-> {"id":2,"method":"subscribe","params":{"events":["button"]}}
+//	<- {"id":2,"result":true}
+//	<- {"event":{"type":"button","button":"left","pressed":true,"mask":1}}
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+
+	Macku "github.com/Auchrio/Makcu-go-lib/v3"
+)
+
+// DefaultSocketPath returns $MAKCUD_SOCKET if set, otherwise makcud.sock in
+// $XDG_RUNTIME_DIR or the system temp directory.
+func DefaultSocketPath() string {
+	if p := os.Getenv("MAKCUD_SOCKET"); p != "" {
+		return p
+	}
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "makcud.sock")
+}
+
+// RPC methods served by Server.
+const (
+	MethodSend            = "send"             // SendParams -> SendResult
+	MethodButtonStates    = "button_states"    // -> map[string]bool
+	MethodButtonMask      = "button_mask"      // -> int
+	MethodIsConnected     = "is_connected"     // -> bool
+	MethodPort            = "port"             // -> string
+	MethodSubscribe       = "subscribe"        // SubscribeParams -> true
+	MethodUnsubscribe     = "unsubscribe"      // SubscribeParams -> true
+	MethodMove            = "move"             // MoveParams -> true
+	MethodClick           = "click"            // ButtonParams -> true
+	MethodPress           = "press"            // ButtonParams -> true
+	MethodRelease         = "release"          // ButtonParams -> true
+	MethodScroll          = "scroll"           // ScrollParams -> true
+	MethodLock            = "lock"             // LockParams -> true
+	MethodUnlock          = "unlock"           // LockParams -> true
+	MethodLockStates      = "lock_states"      // -> map[string]bool
+	MethodDeviceInfo      = "device_info"      // -> Macku.DeviceInfo
+	MethodFirmwareVersion = "firmware_version" // -> string
+	MethodMonitorButtons  = "monitor_buttons"  // MonitorParams -> true
+)
+
+// Event types delivered to subscribers.
+const (
+	EventButton     = "button"
+	EventConnection = "connection"
+)
+
+// Request is one RPC call from a client.
+type Request struct {
+	ID     uint64          `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// Message is one line from the server: a response (ID set) or an event.
+type Message struct {
+	ID     uint64          `json:"id,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *Error          `json:"error,omitempty"`
+	Event  *Event          `json:"event,omitempty"`
+}
+
+// Event is an asynchronous notification pushed to subscribed connections.
+type Event struct {
+	Type        string `json:"type"`
+	Button      string `json:"button,omitempty"`
+	Pressed     bool   `json:"pressed,omitempty"`
+	Intercepted bool   `json:"intercepted,omitempty"`
+	Mask        int    `json:"mask"`
+	Connected   bool   `json:"connected,omitempty"`
+}
+
+// SendParams are the parameters of MethodSend.
+type SendParams struct {
+	Command        string `json:"command"`
+	ExpectResponse bool   `json:"expect_response,omitempty"`
+	TimeoutMs      int    `json:"timeout_ms,omitempty"`
+}
+
+// SendResult is the result of MethodSend.
+type SendResult struct {
+	Response string `json:"response"`
+}
+
+// SubscribeParams select the event types for MethodSubscribe/MethodUnsubscribe.
+type SubscribeParams struct {
+	Events []string `json:"events"`
+}
+
+// MoveParams are the parameters of MethodMove (smooth when Segments > 0).
+type MoveParams struct {
+	DX       int `json:"dx"`
+	DY       int `json:"dy"`
+	Segments int `json:"segments,omitempty"`
+}
+
+// ButtonParams name a mouse button ("left", "right", ...).
+type ButtonParams struct {
+	Button string `json:"button"`
+}
+
+// ScrollParams are the parameters of MethodScroll.
+type ScrollParams struct {
+	Delta int `json:"delta"`
+}
+
+// MonitorParams are the parameters of MethodMonitorButtons.
+type MonitorParams struct {
+	Enable bool `json:"enable"`
+}
+
+// LockParams name a lock target ("left", ..., "x", "y").
+type LockParams struct {
+	Target string `json:"target"`
+}
+
+// Error codes mirror the Macku sentinel errors.
+const (
+	CodeConnection = "connection"
+	CodeTimeout    = "timeout"
+	CodeCommand    = "command"
+	CodeResponse   = "response"
+	CodeInvalid    = "invalid_request"
+	CodeInternal   = "internal"
+)
+
+// Error is an RPC error. Code identifies the Macku error class so clients
+// can reconstruct errors that satisfy errors.Is.
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// toError classifies err for transmission.
+func toError(err error) *Error {
+	code := CodeInternal
+	switch {
+	case errors.Is(err, Macku.ErrConnection):
+		code = CodeConnection
+	case errors.Is(err, Macku.ErrTimeout):
+		code = CodeTimeout
+	case errors.Is(err, Macku.ErrCommand):
+		code = CodeCommand
+	case errors.Is(err, Macku.ErrResponse):
+		code = CodeResponse
+	}
+	return &Error{Code: code, Message: err.Error()}
+}
+
+// fromError rebuilds a Macku error from its wire form.
+func fromError(e *Error) error {
+	switch e.Code {
+	case CodeConnection:
+		return Macku.NewConnectionError(e.Message)
+	case CodeTimeout:
+		return Macku.NewTimeoutError(e.Message)
+	case CodeCommand, CodeInvalid:
+		return Macku.NewCommandError(e.Message)
+	case CodeResponse:
+		return Macku.NewResponseError(e.Message)
+	default:
+		return e
+	}
+}
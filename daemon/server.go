@@ -0,0 +1,346 @@
+package daemon
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	Macku "github.com/Auchrio/Makcu-go-lib/v3"
+	"github.com/Auchrio/Makcu-go-lib/v3/internal/events"
+)
+
+const (
+	maxLineSize = 1 << 20 // bounds a single request line
+	eventBuffer = 64      // events queued per connection before dropping
+)
+
+// Server serves one MakcuController to any number of socket clients.
+// It takes over the controller's button callback to fan events out to
+// subscribed connections.
+type Server struct {
+	Controller *Macku.MakcuController
+	Logf       func(format string, args ...interface{}) // optional diagnostics
+
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[*serverConn]struct{}
+	hub       *events.Hub
+	closed    bool
+}
+
+// NewServer creates a Server for an already-connected controller.
+func NewServer(c *Macku.MakcuController) *Server {
+	return &Server{
+		Controller: c,
+		listeners:  make(map[net.Listener]struct{}),
+		conns:      make(map[*serverConn]struct{}),
+	}
+}
+
+// ListenAndServe listens on the Unix socket at path and serves until Close.
+// A stale socket file left by a previous daemon is removed first.
+func (s *Server) ListenAndServe(path string) error {
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return Macku.NewConnectionError(fmt.Sprintf("socket %s is already in use", path))
+	}
+	os.Remove(path)
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return Macku.NewConnectionError(fmt.Sprintf("failed to listen on %s: %v", path, err))
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts connections on l until l is closed or Close is called.
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		l.Close()
+		return net.ErrClosed
+	}
+	s.listeners[l] = struct{}{}
+	if s.hub == nil {
+		s.hub = events.Attach(s.Controller)
+	}
+	hub := s.hub
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.listeners, l)
+		s.mu.Unlock()
+	}()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed || errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		sc := &serverConn{s: s, conn: conn, hub: hub, subs: make(map[string]bool)}
+		s.mu.Lock()
+		s.conns[sc] = struct{}{}
+		s.mu.Unlock()
+		go sc.serve()
+	}
+}
+
+// Close stops all listeners and drops every client connection. The
+// controller itself is left connected; its owner disconnects it.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	for l := range s.listeners {
+		l.Close()
+	}
+	for sc := range s.conns {
+		sc.conn.Close()
+	}
+	if s.hub != nil {
+		s.hub.Detach()
+	}
+	return nil
+}
+
+func (s *Server) logf(format string, args ...interface{}) {
+	if s.Logf != nil {
+		s.Logf(format, args...)
+	}
+}
+
+// serverConn is one client connection. Requests on a connection are handled
+// in order; events may interleave with responses.
+type serverConn struct {
+	s    *Server
+	conn net.Conn
+	hub  *events.Hub
+
+	wmu sync.Mutex
+
+	smu  sync.Mutex
+	subs map[string]bool
+}
+
+func (sc *serverConn) subscribed(typ string) bool {
+	sc.smu.Lock()
+	defer sc.smu.Unlock()
+	return sc.subs[typ]
+}
+
+func (sc *serverConn) write(m *Message) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	sc.wmu.Lock()
+	defer sc.wmu.Unlock()
+	sc.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	_, err = sc.conn.Write(append(data, '\n'))
+	return err
+}
+
+// forward relays hub events the connection has subscribed to.
+func (sc *serverConn) forward(sub *events.Subscription) {
+	for ev := range sub.C() {
+		msg := &Event{Mask: ev.Mask}
+		if ev.Kind == events.Connection {
+			msg.Type, msg.Connected = EventConnection, ev.Connected
+		} else {
+			msg.Type, msg.Button, msg.Pressed = EventButton, ev.Button.String(), ev.Pressed
+			msg.Intercepted = ev.Intercepted
+		}
+		if !sc.subscribed(msg.Type) {
+			continue
+		}
+		if err := sc.write(&Message{Event: msg}); err != nil {
+			sc.s.logf("daemon: dropping event for %v: %v", sc.conn.RemoteAddr(), err)
+		}
+	}
+}
+
+func (sc *serverConn) serve() {
+	sub := sc.hub.Subscribe(eventBuffer)
+	go sc.forward(sub)
+	defer func() {
+		sub.Close()
+		sc.conn.Close()
+		sc.s.mu.Lock()
+		delete(sc.s.conns, sc)
+		sc.s.mu.Unlock()
+	}()
+
+	scanner := bufio.NewScanner(sc.conn)
+	scanner.Buffer(make([]byte, 4096), maxLineSize)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var req Request
+		if err := json.Unmarshal(line, &req); err != nil {
+			sc.write(&Message{Error: &Error{Code: CodeInvalid, Message: "malformed request: " + err.Error()}})
+			continue
+		}
+		result, err := sc.handle(&req)
+		resp := &Message{ID: req.ID}
+		if err == nil {
+			resp.Result, err = json.Marshal(result)
+		}
+		if err != nil {
+			var rpcErr *Error
+			if !errors.As(err, &rpcErr) {
+				rpcErr = toError(err)
+			}
+			resp.Result, resp.Error = nil, rpcErr
+		}
+		if err := sc.write(resp); err != nil {
+			sc.s.logf("daemon: write to %v: %v", sc.conn.RemoteAddr(), err)
+			return
+		}
+	}
+}
+
+func invalid(format string, args ...interface{}) error {
+	return &Error{Code: CodeInvalid, Message: fmt.Sprintf(format, args...)}
+}
+
+func decodeParams(req *Request, v interface{}) error {
+	if len(req.Params) == 0 {
+		return invalid("%s: missing params", req.Method)
+	}
+	if err := json.Unmarshal(req.Params, v); err != nil {
+		return invalid("%s: invalid params: %v", req.Method, err)
+	}
+	return nil
+}
+
+func (sc *serverConn) handle(req *Request) (interface{}, error) {
+	c := sc.s.Controller
+	switch req.Method {
+	case MethodSend:
+		var p SendParams
+		if err := decodeParams(req, &p); err != nil {
+			return nil, err
+		}
+		if p.Command == "" {
+			return nil, invalid("send: empty command")
+		}
+		timeout := Macku.DefaultTimeout
+		if p.TimeoutMs > 0 {
+			timeout = time.Duration(p.TimeoutMs) * time.Millisecond
+		}
+		resp, err := c.Transport.SendCommand(p.Command, p.ExpectResponse, timeout)
+		if err != nil {
+			return nil, err
+		}
+		return SendResult{Response: resp}, nil
+
+	case MethodButtonStates:
+		return c.GetButtonStates()
+	case MethodButtonMask:
+		return c.GetButtonMask()
+	case MethodIsConnected:
+		return c.IsConnected(), nil
+	case MethodPort:
+		return c.Transport.PortName(), nil
+
+	case MethodSubscribe, MethodUnsubscribe:
+		var p SubscribeParams
+		if err := decodeParams(req, &p); err != nil {
+			return nil, err
+		}
+		for _, ev := range p.Events {
+			if ev != EventButton && ev != EventConnection {
+				return nil, invalid("%s: unknown event type %q", req.Method, ev)
+			}
+		}
+		sc.smu.Lock()
+		for _, ev := range p.Events {
+			sc.subs[ev] = req.Method == MethodSubscribe
+		}
+		sc.smu.Unlock()
+		return true, nil
+
+	case MethodMove:
+		var p MoveParams
+		if err := decodeParams(req, &p); err != nil {
+			return nil, err
+		}
+		if p.Segments > 0 {
+			return true, c.MoveSmooth(p.DX, p.DY, p.Segments)
+		}
+		return true, c.Move(p.DX, p.DY)
+
+	case MethodClick, MethodPress, MethodRelease:
+		var p ButtonParams
+		if err := decodeParams(req, &p); err != nil {
+			return nil, err
+		}
+		b, err := Macku.ParseMouseButton(p.Button)
+		if err != nil {
+			return nil, err
+		}
+		switch req.Method {
+		case MethodClick:
+			return true, c.Click(b)
+		case MethodPress:
+			return true, c.Press(b)
+		default:
+			return true, c.Release(b)
+		}
+
+	case MethodScroll:
+		var p ScrollParams
+		if err := decodeParams(req, &p); err != nil {
+			return nil, err
+		}
+		return true, c.Scroll(p.Delta)
+
+	case MethodLock, MethodUnlock:
+		var p LockParams
+		if err := decodeParams(req, &p); err != nil {
+			return nil, err
+		}
+		t, err := Macku.ParseLockTarget(p.Target)
+		if err != nil {
+			return nil, err
+		}
+		if req.Method == MethodLock {
+			return true, c.Lock(t)
+		}
+		return true, c.Unlock(t)
+
+	case MethodLockStates:
+		return c.GetAllLockStates()
+	case MethodDeviceInfo:
+		return c.GetDeviceInfo()
+	case MethodFirmwareVersion:
+		return c.GetFirmwareVersion()
+	case MethodMonitorButtons:
+		var p MonitorParams
+		if err := decodeParams(req, &p); err != nil {
+			return nil, err
+		}
+		return true, c.EnableButtonMonitoring(p.Enable)
+
+	default:
+		return nil, invalid("unknown method %q", req.Method)
+	}
+}
@@ -0,0 +1,199 @@
+package Macku
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DecodedKind classifies an element of a decoded capture.
+type DecodedKind int
+
+const (
+	DecodedCommand  DecodedKind = iota // command line written by the host
+	DecodedResponse                    // device line answering a pending command
+	DecodedEcho                        // device echo of the pending command
+	DecodedText                        // device line with no command pending
+	DecodedButtons                     // button-mask byte
+)
+
+// String returns the lowercase name of the kind.
+func (k DecodedKind) String() string {
+	switch k {
+	case DecodedCommand:
+		return "command"
+	case DecodedResponse:
+		return "response"
+	case DecodedEcho:
+		return "echo"
+	case DecodedText:
+		return "text"
+	case DecodedButtons:
+		return "buttons"
+	default:
+		return "unknown"
+	}
+}
+
+// DecodedEvent is one protocol element recovered from a capture.
+type DecodedEvent struct {
+	Offset  time.Duration
+	Dir     CaptureDir
+	Kind    DecodedKind
+	Text    string // command or response content, without ">>> " or "#id"
+	Tag     int    // command ID from "#id" (for responses, of the command answered), or -1
+	Command string // for responses and echoes, the command answered
+	Mask    int    // for DecodedButtons
+}
+
+// String renders the event as one line of an annotated dump.
+func (e DecodedEvent) String() string {
+	head := fmt.Sprintf("+%s %s %-8s ", formatOffset(e.Offset), e.Dir, e.Kind)
+	switch e.Kind {
+	case DecodedCommand:
+		if e.Tag >= 0 {
+			return head + fmt.Sprintf("%s #%d", e.Text, e.Tag)
+		}
+		return head + e.Text
+	case DecodedResponse, DecodedEcho:
+		return head + fmt.Sprintf("%q (%s #%d)", e.Text, e.Command, e.Tag)
+	case DecodedButtons:
+		return head + fmt.Sprintf("0x%02X %s", e.Mask, maskNames(e.Mask))
+	default:
+		return head + strconv.Quote(e.Text)
+	}
+}
+
+// maskNames lists the buttons set in mask, e.g. "[left mouse4]".
+func maskNames(mask int) string {
+	var names []string
+	for bit := 0; bit < 8; bit++ {
+		if mask&(1<<bit) == 0 {
+			continue
+		}
+		if bit < len(buttonNames) {
+			names = append(names, buttonNames[bit])
+		} else {
+			names = append(names, fmt.Sprintf("bit%d", bit))
+		}
+	}
+	return "[" + strings.Join(names, " ") + "]"
+}
+
+// Decode annotates the capture with commands, responses, tags and
+// button-mask bytes. Device output goes through the same parser as the
+// transport's listener, and responses are matched to tagged commands by
+// the same oldest-pending rule, so the dump shows what the library saw.
+func (c *Capture) Decode() []DecodedEvent {
+	d := &decoder{}
+	d.parser = NewStreamParser(d.deviceLine, d.buttons)
+	for _, r := range c.Records {
+		d.at = r.Offset
+		for _, b := range r.Data {
+			if r.Dir == CaptureWrite {
+				d.hostByte(b)
+			} else {
+				d.parser.Feed(b)
+			}
+		}
+	}
+	return d.events
+}
+
+// decodedPending is a tagged command awaiting its response.
+type decodedPending struct {
+	id      int
+	command string
+	at      time.Duration
+}
+
+type decoder struct {
+	at      time.Duration
+	parser  *StreamParser
+	host    []byte
+	pending []decodedPending
+	events  []DecodedEvent
+}
+
+// hostByte accumulates host output into CR/LF-terminated command lines.
+func (d *decoder) hostByte(b byte) {
+	if b != '\r' && b != '\n' {
+		if len(d.host) < maxLineLength {
+			d.host = append(d.host, b)
+		}
+		return
+	}
+	if len(d.host) == 0 {
+		return
+	}
+	line := string(d.host)
+	d.host = d.host[:0]
+
+	ev := DecodedEvent{Offset: d.at, Dir: CaptureWrite, Kind: DecodedCommand, Text: line, Tag: -1}
+	if i := strings.LastIndex(line, "#"); i >= 0 {
+		if id, err := strconv.Atoi(line[i+1:]); err == nil {
+			ev.Text, ev.Tag = line[:i], id
+			d.pending = append(d.pending, decodedPending{id: id, command: ev.Text, at: d.at})
+		}
+	}
+	switch ev.Text {
+	case "km.buttons(2)":
+		d.parser.AcceptFrames(true)
+	case "km.buttons(1)", "km.buttons(0)":
+		d.parser.AcceptFrames(false)
+	}
+	d.events = append(d.events, ev)
+}
+
+// deviceLine mirrors parseResponseLine and processPendingCommands.
+func (d *decoder) deviceLine(line []byte) {
+	content := strings.TrimSpace(string(line))
+	if strings.HasPrefix(content, ">>> ") {
+		content = strings.TrimSpace(content[4:])
+	}
+	if content == "" {
+		return
+	}
+
+	// The listener drops commands left pending for over a second.
+	kept := d.pending[:0]
+	for _, p := range d.pending {
+		if d.at-p.at <= time.Second {
+			kept = append(kept, p)
+		}
+	}
+	d.pending = kept
+
+	ev := DecodedEvent{Offset: d.at, Dir: CaptureRead, Kind: DecodedText, Text: content, Tag: -1}
+	if oldest := d.oldestPending(); oldest >= 0 {
+		p := d.pending[oldest]
+		ev.Command, ev.Tag = p.command, p.id
+		if content == p.command {
+			ev.Kind = DecodedEcho
+		} else {
+			ev.Kind = DecodedResponse
+			if i := strings.Index(content, "#"); i >= 0 {
+				ev.Text = content[:i]
+			}
+			d.pending = append(d.pending[:oldest], d.pending[oldest+1:]...)
+		}
+	}
+	d.events = append(d.events, ev)
+}
+
+// oldestPending returns the index of the pending command with the lowest
+// ID, or -1.
+func (d *decoder) oldestPending() int {
+	idx := -1
+	for i, p := range d.pending {
+		if idx < 0 || p.id < d.pending[idx].id {
+			idx = i
+		}
+	}
+	return idx
+}
+
+func (d *decoder) buttons(mask int) {
+	d.events = append(d.events, DecodedEvent{Offset: d.at, Dir: CaptureRead, Kind: DecodedButtons, Tag: -1, Mask: mask})
+}
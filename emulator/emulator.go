@@ -0,0 +1,350 @@
+// Package emulator simulates a Makcu device at the byte level, so the real
+// SerialTransport parser, command correlation and button reports can be
+// exercised without hardware.
+//
+// The emulator understands the km.* commands the library sends: moves,
+// wheel, button presses, locks and their queries, catch on locked buttons,
+// km.buttons monitoring
+// (raw mask bytes, or framed reports with km.buttons(2)), km.version and
+// km.serial, decoded with the protocol package. Queries are answered with
+// ">>> value\r\n"; other commands are recorded silently. Unknown commands
+// are recorded and ignored.
+package emulator
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	Macku "github.com/Auchrio/Makcu-go-lib/v3"
+	"github.com/Auchrio/Makcu-go-lib/v3/protocol"
+)
+
+// DefaultVersion is the firmware string returned by km.version().
+const DefaultVersion = "km.MAKCU"
+
+// baudMagic is the 9-byte baud-change sequence a host sends after opening
+// the port; the emulator skips it.
+var baudMagic = []byte{0xDE, 0xAD, 0x05, 0x00, 0xA5, 0x00, 0x09, 0x3D, 0x00}
+
+var buttonCmds = map[string]Macku.MouseButton{
+	"left":   Macku.MouseButtonLeft,
+	"right":  Macku.MouseButtonRight,
+	"middle": Macku.MouseButtonMiddle,
+	"ms1":    Macku.MouseButton4,
+	"ms2":    Macku.MouseButton5,
+}
+
+var lockCmds = map[string]Macku.LockTarget{
+	"lock_ml":  Macku.LockLeft,
+	"lock_mr":  Macku.LockRight,
+	"lock_mm":  Macku.LockMiddle,
+	"lock_ms1": Macku.LockMouse4,
+	"lock_ms2": Macku.LockMouse5,
+	"lock_mx":  Macku.LockX,
+	"lock_my":  Macku.LockY,
+}
+
+var catchCmds = map[string]Macku.LockTarget{
+	"catch_ml":  Macku.LockLeft,
+	"catch_mr":  Macku.LockRight,
+	"catch_mm":  Macku.LockMiddle,
+	"catch_ms1": Macku.LockMouse4,
+	"catch_ms2": Macku.LockMouse5,
+}
+
+// lockButtons maps the button lock targets to their buttons.
+var lockButtons = map[Macku.LockTarget]Macku.MouseButton{
+	Macku.LockLeft:   Macku.MouseButtonLeft,
+	Macku.LockRight:  Macku.MouseButtonRight,
+	Macku.LockMiddle: Macku.MouseButtonMiddle,
+	Macku.LockMouse4: Macku.MouseButton4,
+	Macku.LockMouse5: Macku.MouseButton5,
+}
+
+// Device is an emulated Makcu. The zero value is not usable; call New.
+type Device struct {
+	// Version is returned by km.version(); New sets DefaultVersion.
+	Version string
+	// Framing enables framed button reports on km.buttons(2); New sets it.
+	// Without it, km.buttons(2) enables raw mask bytes like older firmware.
+	Framing bool
+
+	mu         sync.Mutex
+	x, y       int
+	wheel      int
+	held       int // buttons pressed by commands
+	physical   int // buttons reported by SetButtons
+	locks      map[Macku.LockTarget]bool
+	caught     map[Macku.LockTarget]bool // buttons with catch enabled
+	catches    map[Macku.LockTarget]int  // caught presses since the last query
+	monitoring bool
+	framed     bool // reports are sent as frames
+	serial     string
+	commands   []string
+	sessions   map[*session]struct{}
+}
+
+// New creates an idle device.
+func New() *Device {
+	return &Device{
+		Version:  DefaultVersion,
+		Framing:  true,
+		locks:    make(map[Macku.LockTarget]bool),
+		caught:   make(map[Macku.LockTarget]bool),
+		catches:  make(map[Macku.LockTarget]int),
+		sessions: make(map[*session]struct{}),
+	}
+}
+
+// Open connects a new host to the device and returns the host's end of the
+// link, ready for Macku.NewStreamTransport.
+func (d *Device) Open() (Macku.Port, error) {
+	host, dev := net.Pipe()
+	go d.Serve(dev)
+	return Macku.NewConnPort(host), nil
+}
+
+// Serve runs the device on conn until it is closed.
+func (d *Device) Serve(conn net.Conn) {
+	s := &session{d: d, conn: conn}
+	d.mu.Lock()
+	d.sessions[s] = struct{}{}
+	d.mu.Unlock()
+	defer func() {
+		d.mu.Lock()
+		delete(d.sessions, s)
+		d.mu.Unlock()
+		conn.Close()
+	}()
+	s.run()
+}
+
+// SetButtons simulates the physical buttons changing to mask. While
+// monitoring is enabled the report is sent to every host. Presses of
+// locked buttons with catch enabled are counted for km.catch_* queries.
+func (d *Device) SetButtons(mask int) {
+	d.mu.Lock()
+	changed := d.physical != mask
+	for t, b := range lockButtons {
+		bit := 1 << uint(b)
+		if mask&bit != 0 && d.physical&bit == 0 && d.locks[t] && d.caught[t] {
+			d.catches[t]++
+		}
+	}
+	d.physical = mask
+	report := d.report()
+	var targets []*session
+	if changed && d.monitoring {
+		for s := range d.sessions {
+			targets = append(targets, s)
+		}
+	}
+	d.mu.Unlock()
+	for _, s := range targets {
+		s.write(report)
+	}
+}
+
+// report encodes the physical mask as a raw byte or a frame. Callers hold mu.
+func (d *Device) report() []byte {
+	if d.framed {
+		return []byte{Macku.ButtonFramePrefix, byte(d.physical)}
+	}
+	return []byte{byte(d.physical)}
+}
+
+// Commands returns every command line received, without "#id" tags.
+func (d *Device) Commands() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.commands...)
+}
+
+// Position returns the accumulated relative movement.
+func (d *Device) Position() (x, y int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.x, d.y
+}
+
+// Wheel returns the accumulated scroll delta.
+func (d *Device) Wheel() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.wheel
+}
+
+// Held reports whether button is held down by a press command.
+func (d *Device) Held(button Macku.MouseButton) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.held&(1<<uint(button)) != 0
+}
+
+// Locked reports the lock state of target.
+func (d *Device) Locked(target Macku.LockTarget) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.locks[target]
+}
+
+// Caught reports whether catch is enabled for target.
+func (d *Device) Caught(target Macku.LockTarget) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.caught[target]
+}
+
+// Monitoring reports whether button reports are enabled.
+func (d *Device) Monitoring() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.monitoring
+}
+
+// Framed reports whether button reports are sent as frames.
+func (d *Device) Framed() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.framed
+}
+
+// Serial returns the spoofed serial set by km.serial, or "".
+func (d *Device) Serial() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.serial
+}
+
+// exec applies one command and returns the bytes to send back, if any:
+// a query answer, or the frame acknowledging km.buttons(2).
+func (d *Device) exec(line string) []byte {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.commands = append(d.commands, line)
+
+	cmd, _, err := protocol.Decode(line)
+	if err != nil {
+		return nil
+	}
+	name, args := cmd.Name, cmd.Ints()
+	switch {
+	case name == "move" && len(args) >= 2:
+		d.x += args[0]
+		d.y += args[1]
+	case name == "wheel" && len(args) == 1:
+		d.wheel += args[0]
+	case name == "buttons" && len(args) == 1:
+		d.monitoring = args[0] != 0
+		d.framed = args[0] == 2 && d.Framing
+		if d.framed {
+			return d.report()
+		}
+	case name == "version":
+		return answer(d.Version)
+	case name == "serial":
+		d.serial = ""
+		if len(cmd.Args) == 1 && cmd.Args[0].Quoted {
+			d.serial = cmd.Args[0].Str
+		}
+	default:
+		if b, ok := buttonCmds[name]; ok {
+			bit := 1 << uint(b)
+			if len(args) == 0 {
+				return answer(boolString(d.held&bit != 0))
+			}
+			if args[0] != 0 {
+				d.held |= bit
+			} else {
+				d.held &^= bit
+			}
+		} else if t, ok := lockCmds[name]; ok {
+			if len(args) == 0 {
+				return answer(boolString(d.locks[t]))
+			}
+			d.locks[t] = args[0] != 0
+		} else if t, ok := catchCmds[name]; ok {
+			if len(args) == 0 {
+				n := d.catches[t]
+				d.catches[t] = 0
+				return answer(strconv.Itoa(n))
+			}
+			d.caught[t] = args[0] != 0
+		}
+	}
+	return nil
+}
+
+// answer formats a query answer.
+func answer(v string) []byte {
+	return []byte(">>> " + v + "\r\n")
+}
+
+func boolString(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+// --- session ---
+
+// session is one host connection.
+type session struct {
+	d       *Device
+	conn    net.Conn
+	writeMu sync.Mutex
+}
+
+func (s *session) write(b []byte) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.conn.Write(b)
+}
+
+func (s *session) run() {
+	buf := make([]byte, 4096)
+	var line []byte
+	magic := 0 // bytes of baudMagic matched so far
+	for {
+		n, err := s.conn.Read(buf)
+		if err != nil {
+			return
+		}
+		for _, b := range buf[:n] {
+			if len(line) == 0 && b == baudMagic[magic] {
+				if magic++; magic == len(baudMagic) {
+					magic = 0
+				}
+				continue
+			}
+			magic = 0
+			switch b {
+			case '\r', '\n':
+				if len(line) > 0 {
+					s.handle(string(line))
+					line = line[:0]
+				}
+			default:
+				line = append(line, b)
+			}
+		}
+	}
+}
+
+func (s *session) handle(line string) {
+	if i := strings.LastIndex(line, "#"); i >= 0 {
+		if _, err := strconv.Atoi(line[i+1:]); err == nil {
+			line = line[:i]
+		}
+	}
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return
+	}
+	if reply := s.d.exec(line); reply != nil {
+		s.write(reply)
+	}
+}
@@ -1,6 +1,12 @@
 package Macku
 
-// MouseButton represents a mouse button identifier.
+import (
+	"fmt"
+	"strings"
+)
+
+// MouseButton represents a mouse button identifier. Its value is the
+// button's bit in the device's button mask.
 type MouseButton int
 
 const (
@@ -9,6 +15,20 @@ const (
 	MouseButtonMiddle MouseButton = 2
 	MouseButton4      MouseButton = 3
 	MouseButton5      MouseButton = 4
+
+	// Mask bits 5-7. They are reported in button states and events,
+	// but the firmware documents no command for them, so pressing,
+	// locking or catching them returns ErrUnsupported.
+	MouseButton6 MouseButton = 5
+	MouseButton7 MouseButton = 6
+	MouseButton8 MouseButton = 7
+)
+
+// StandardButtons is the number of buttons with commands (left through
+// mouse5); MaxButtons covers all eight mask bits.
+const (
+	StandardButtons = 5
+	MaxButtons      = 8
 )
 
 // String returns the lowercase name of the mouse button.
@@ -24,11 +44,28 @@ func (b MouseButton) String() string {
 		return "mouse4"
 	case MouseButton5:
 		return "mouse5"
+	case MouseButton6:
+		return "mouse6"
+	case MouseButton7:
+		return "mouse7"
+	case MouseButton8:
+		return "mouse8"
 	default:
 		return "unknown"
 	}
 }
 
+// ParseMouseButton returns the MouseButton with the given name (as returned
+// by String), case-insensitively.
+func ParseMouseButton(name string) (MouseButton, error) {
+	for b := MouseButtonLeft; b <= MouseButton8; b++ {
+		if strings.EqualFold(name, b.String()) {
+			return b, nil
+		}
+	}
+	return 0, NewCommandError(fmt.Sprintf("unknown mouse button: %q", name))
+}
+
 // LockTarget identifies a lockable target (button or axis).
 type LockTarget int
 
@@ -42,6 +79,39 @@ const (
 	LockY
 )
 
+// String returns the lowercase name of the lock target.
+func (t LockTarget) String() string {
+	switch t {
+	case LockLeft:
+		return "left"
+	case LockRight:
+		return "right"
+	case LockMiddle:
+		return "middle"
+	case LockMouse4:
+		return "mouse4"
+	case LockMouse5:
+		return "mouse5"
+	case LockX:
+		return "x"
+	case LockY:
+		return "y"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseLockTarget returns the LockTarget with the given name (as returned by
+// String), case-insensitively.
+func ParseLockTarget(name string) (LockTarget, error) {
+	for t := LockLeft; t <= LockY; t++ {
+		if strings.EqualFold(name, t.String()) {
+			return t, nil
+		}
+	}
+	return 0, NewCommandError(fmt.Sprintf("unknown lock target: %q", name))
+}
+
 // ClickProfile defines a timing profile for human-like clicks.
 type ClickProfile string
 
@@ -8,6 +8,19 @@ var (
 	ErrCommand    = errors.New("macku: command error")
 	ErrTimeout    = errors.New("macku: timeout")
 	ErrResponse   = errors.New("macku: response error")
+
+	// ErrUnsupported marks a command the connected firmware lacks. Such
+	// errors also match ErrCommand.
+	ErrUnsupported = errors.New("macku: unsupported by firmware")
+
+	// ErrDevice marks an error message the firmware sent in place of a
+	// value. Such errors also match ErrResponse.
+	ErrDevice = errors.New("macku: device error")
+)
+
+var (
+	errUnsupportedCommand = errors.Join(ErrUnsupported, ErrCommand)
+	errDeviceResponse     = errors.Join(ErrDevice, ErrResponse)
 )
 
 // MakcuError wraps a sentinel error with a descriptive message.
@@ -43,3 +56,8 @@ func NewTimeoutError(msg string) error {
 func NewResponseError(msg string) error {
 	return &MakcuError{Base: ErrResponse, Message: msg}
 }
+
+// NewUnsupportedError creates an error for a command the firmware lacks.
+func NewUnsupportedError(msg string) error {
+	return &MakcuError{Base: errUnsupportedCommand, Message: msg}
+}
@@ -0,0 +1,326 @@
+// Package fault injects failures into the byte stream between a
+// SerialTransport and its device, so reconnects, SendCommand timeouts and
+// pending-command cleanup can be exercised under realistic link faults.
+//
+// An Injector applies a Scenario to every Port it wraps: it can drop,
+// corrupt and duplicate bytes read from the device; drop, duplicate, delay
+// and reorder whole response lines; fail reads mid-stream; and stall, drop
+// or fail writes. Decisions come from random sources seeded by
+// Scenario.Seed, one for device output and one for host writes, so the
+// same seed and the same traffic produce the same faults.
+//
+//	t := fault.NewTransport(dev.Open, fault.Scenario{Seed: 1, DropResponseRate: 0.2}, false, true, true)
+//	c := Macku.NewControllerWithTransport(t)
+package fault
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	Macku "github.com/Auchrio/Makcu-go-lib/v3"
+)
+
+// ErrInjected is returned by reads and writes the scenario fails.
+var ErrInjected = errors.New("fault: injected I/O error")
+
+// maxHeldLine bounds a partial response line held back for line faults.
+const maxHeldLine = 256
+
+// Scenario configures the faults. Rates are probabilities in [0, 1]; the
+// zero Scenario passes traffic through untouched.
+type Scenario struct {
+	Seed int64
+
+	// Device output, decided per byte after the line faults below.
+	DropRate      float64 // discard a byte
+	CorruptRate   float64 // flip one random bit of a byte
+	DuplicateRate float64 // deliver a byte twice
+
+	// Response lines (">>> ...\n"), decided per line.
+	DropResponseRate      float64
+	DuplicateResponseRate float64
+	ReorderResponseRate   float64       // hold a response back until after the next one
+	DelayResponseRate     float64       // hold a response back for ResponseDelay
+	ResponseDelay         time.Duration // later output queues behind it
+
+	// Read errors: ReadErrorRate is decided per byte read from the device;
+	// ReadErrorAfter fails the read after that many bytes on each port
+	// (0 disables it). Bytes before the failure are still delivered.
+	ReadErrorRate  float64
+	ReadErrorAfter int
+
+	// Host writes, decided per Write call.
+	WriteStallRate float64       // sleep WriteStall before writing
+	WriteStall     time.Duration // e.g. a full USB buffer
+	WriteDropRate  float64       // report success without writing
+	WriteErrorRate float64       // fail with ErrInjected without writing
+}
+
+// Stats counts the faults injected so far.
+type Stats struct {
+	BytesDropped        int
+	BytesCorrupted      int
+	BytesDuplicated     int
+	ResponsesDropped    int
+	ResponsesDuplicated int
+	ResponsesReordered  int
+	ResponsesDelayed    int
+	ReadErrors          int
+	WritesStalled       int
+	WritesDropped       int
+	WriteErrors         int
+}
+
+// Injector holds a scenario, its random sources and the fault counts. It
+// is shared by every Port it wraps, so faults continue across reconnects.
+type Injector struct {
+	mu    sync.Mutex
+	sc    Scenario
+	read  *rand.Rand
+	write *rand.Rand
+	stats Stats
+}
+
+// New returns an Injector running sc.
+func New(sc Scenario) *Injector {
+	inj := &Injector{}
+	inj.SetScenario(sc)
+	return inj
+}
+
+// SetScenario switches to sc and reseeds the random sources. Ports already
+// wrapped pick it up on their next read or write; output already held back
+// by the previous scenario is still delivered.
+func (inj *Injector) SetScenario(sc Scenario) {
+	inj.mu.Lock()
+	defer inj.mu.Unlock()
+	inj.sc = sc
+	inj.read = rand.New(rand.NewSource(sc.Seed))
+	inj.write = rand.New(rand.NewSource(sc.Seed + 1))
+}
+
+// Stats returns the faults injected so far.
+func (inj *Injector) Stats() Stats {
+	inj.mu.Lock()
+	defer inj.mu.Unlock()
+	return inj.stats
+}
+
+// Wrap returns p with the scenario applied.
+func (inj *Injector) Wrap(p Macku.Port) Macku.Port {
+	return &port{inner: p, inj: inj, buf: make([]byte, 4096)}
+}
+
+// hit reports whether an event with probability rate happens. Callers hold mu.
+func hit(r *rand.Rand, rate float64) bool {
+	return rate > 0 && r.Float64() < rate
+}
+
+// --- transport ---
+
+// Transport is a SerialTransport whose ports are wrapped by Faults.
+type Transport struct {
+	*Macku.SerialTransport
+	Faults *Injector
+}
+
+// NewTransport creates (but does not connect) a stream transport over the
+// ports returned by open, with sc applied to each of them.
+func NewTransport(open func() (Macku.Port, error), sc Scenario, debug, sendInit, autoReconnect bool) *Transport {
+	t := &Transport{Faults: New(sc)}
+	t.SerialTransport = Macku.NewStreamTransport("fault", func() (Macku.Port, error) {
+		p, err := open()
+		if err != nil {
+			return nil, err
+		}
+		return t.Faults.Wrap(p), nil
+	}, debug, sendInit, autoReconnect)
+	return t
+}
+
+// --- port ---
+
+// chunk is device output released to the host at a given time.
+type chunk struct {
+	data []byte
+	at   time.Time
+}
+
+type port struct {
+	inner Macku.Port
+	inj   *Injector
+	buf   []byte
+
+	mu      sync.Mutex
+	out     []chunk // output in delivery order
+	line    []byte  // partial response line
+	held    []byte  // reordered response waiting for the next one
+	read    int     // bytes read from inner
+	readErr bool    // fail the next Read
+}
+
+func (p *port) Read(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if n := p.deliver(b); n > 0 {
+		return n, nil
+	}
+	if p.readErr {
+		p.readErr = false
+		return 0, ErrInjected
+	}
+
+	n, err := p.inner.Read(p.buf)
+	if n > 0 {
+		p.inj.mu.Lock()
+		for _, c := range p.buf[:n] {
+			if p.readErr {
+				break // the rest of the read is lost with the failure
+			}
+			p.read++
+			sc := p.inj.sc
+			if sc.ReadErrorAfter > 0 && p.read == sc.ReadErrorAfter || hit(p.inj.read, sc.ReadErrorRate) {
+				p.inj.stats.ReadErrors++
+				p.readErr = true
+			}
+			p.lineStage(c)
+		}
+		p.inj.mu.Unlock()
+	}
+	if err != nil {
+		return 0, err
+	}
+	if n := p.deliver(b); n > 0 {
+		return n, nil
+	}
+	if p.readErr {
+		p.readErr = false
+		return 0, ErrInjected
+	}
+	return 0, nil
+}
+
+// deliver copies due output into b.
+func (p *port) deliver(b []byte) int {
+	n := 0
+	now := time.Now()
+	for len(p.out) > 0 && n < len(b) && !p.out[0].at.After(now) {
+		c := copy(b[n:], p.out[0].data)
+		n += c
+		if c == len(p.out[0].data) {
+			p.out = p.out[1:]
+		} else {
+			p.out[0].data = p.out[0].data[c:]
+		}
+	}
+	return n
+}
+
+// lineStage collects response lines for the line faults and passes other
+// bytes, such as button reports, straight to the byte faults. Callers hold
+// p.mu and inj.mu.
+func (p *port) lineStage(c byte) {
+	if len(p.line) == 0 && c != '>' {
+		p.emit([]byte{c}, 0)
+		return
+	}
+	p.line = append(p.line, c)
+	if c != '\n' && len(p.line) < maxHeldLine {
+		return
+	}
+	line := p.line
+	p.line = nil
+
+	sc, r, stats := p.inj.sc, p.inj.read, &p.inj.stats
+	held := p.held
+	switch {
+	case hit(r, sc.DropResponseRate):
+		stats.ResponsesDropped++
+	case p.held == nil && hit(r, sc.ReorderResponseRate):
+		stats.ResponsesReordered++
+		p.held = line
+	case hit(r, sc.DelayResponseRate):
+		stats.ResponsesDelayed++
+		p.emit(line, sc.ResponseDelay)
+	case hit(r, sc.DuplicateResponseRate):
+		stats.ResponsesDuplicated++
+		p.emit(line, 0)
+		p.emit(line, 0)
+	default:
+		p.emit(line, 0)
+	}
+	if held != nil {
+		p.emit(held, 0)
+		p.held = nil
+	}
+}
+
+// emit applies the byte faults to data and queues it delay from now,
+// behind any output still queued. Callers hold p.mu and inj.mu.
+func (p *port) emit(data []byte, delay time.Duration) {
+	sc, r, stats := p.inj.sc, p.inj.read, &p.inj.stats
+	out := make([]byte, 0, len(data))
+	for _, c := range data {
+		switch {
+		case hit(r, sc.DropRate):
+			stats.BytesDropped++
+			continue
+		case hit(r, sc.CorruptRate):
+			stats.BytesCorrupted++
+			c ^= 1 << uint(r.Intn(8))
+		}
+		out = append(out, c)
+		if hit(r, sc.DuplicateRate) {
+			stats.BytesDuplicated++
+			out = append(out, c)
+		}
+	}
+	if len(out) == 0 {
+		return
+	}
+	at := time.Now().Add(delay)
+	if last := len(p.out) - 1; last >= 0 && p.out[last].at.After(at) {
+		at = p.out[last].at
+	}
+	p.out = append(p.out, chunk{data: out, at: at})
+}
+
+func (p *port) Write(b []byte) (int, error) {
+	inj := p.inj
+	inj.mu.Lock()
+	sc, r := inj.sc, inj.write
+	stall := hit(r, sc.WriteStallRate)
+	drop := hit(r, sc.WriteDropRate)
+	fail := !drop && hit(r, sc.WriteErrorRate)
+	if stall {
+		inj.stats.WritesStalled++
+	}
+	switch {
+	case drop:
+		inj.stats.WritesDropped++
+	case fail:
+		inj.stats.WriteErrors++
+	}
+	inj.mu.Unlock()
+
+	if stall {
+		time.Sleep(sc.WriteStall)
+	}
+	switch {
+	case drop:
+		return len(b), nil
+	case fail:
+		return 0, ErrInjected
+	}
+	return p.inner.Write(b)
+}
+
+func (p *port) Close() error {
+	return p.inner.Close()
+}
+
+func (p *port) SetReadTimeout(t time.Duration) error {
+	return p.inner.SetReadTimeout(t)
+}
@@ -0,0 +1,143 @@
+package Macku
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// FirmwareVersion is a parsed km.version() reply, such as
+// "km.MAKCU v3.4.1 build 20250612 (right)". Firmware that reports only its
+// name ("km.MAKCU") parses with Known false.
+type FirmwareVersion struct {
+	Raw   string // the reply as received
+	Major int
+	Minor int
+	Patch int
+	Build string // build identifier, or ""
+	Board string // "left" or "right" side of the MAKCU, or ""
+	Known bool   // a version number was found
+}
+
+var (
+	versionNumber = regexp.MustCompile(`(?:^|[^\w.])v?(\d+)\.(\d+)(?:\.(\d+))?`)
+	versionBuild  = regexp.MustCompile(`(?i)(?:\bbuild[\s:#]*|\+)([\w.-]+)`)
+	versionBoard  = regexp.MustCompile(`(?i)\b(left|right)\b`)
+)
+
+// ParseFirmwareVersion extracts the version number, build and board side
+// from a km.version() reply.
+func ParseFirmwareVersion(s string) FirmwareVersion {
+	v := FirmwareVersion{Raw: strings.TrimSpace(s)}
+	if m := versionNumber.FindStringSubmatch(v.Raw); m != nil {
+		v.Major, _ = strconv.Atoi(m[1])
+		v.Minor, _ = strconv.Atoi(m[2])
+		v.Patch, _ = strconv.Atoi(m[3]) // 0 when absent
+		v.Known = true
+	}
+	if m := versionBuild.FindStringSubmatch(v.Raw); m != nil {
+		v.Build = m[1]
+	}
+	if m := versionBoard.FindStringSubmatch(v.Raw); m != nil {
+		v.Board = strings.ToLower(m[1])
+	}
+	return v
+}
+
+// AtLeast reports whether v is a known version no older than
+// major.minor.patch.
+func (v FirmwareVersion) AtLeast(major, minor, patch int) bool {
+	if !v.Known {
+		return false
+	}
+	if v.Major != major {
+		return v.Major > major
+	}
+	if v.Minor != minor {
+		return v.Minor > minor
+	}
+	return v.Patch >= patch
+}
+
+// String returns "major.minor.patch", with the build and board if known,
+// or the raw reply for firmware without a version number.
+func (v FirmwareVersion) String() string {
+	if !v.Known {
+		return v.Raw
+	}
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Build != "" {
+		s += " build " + v.Build
+	}
+	if v.Board != "" {
+		s += " (" + v.Board + ")"
+	}
+	return s
+}
+
+// Capabilities describes what the connected firmware supports.
+type Capabilities struct {
+	Firmware        FirmwareVersion
+	Commands        map[string]bool // km.* command names, e.g. "move", "lock_ms1"; read-only
+	MaxMoveSegments int             // largest segment count for smooth and bezier moves
+	FramedButtons   bool            // framed button reports negotiated with km.buttons(2)
+	BaudRates       []int
+	Resolved        bool // a version number was read; nothing is gated until then
+}
+
+// Supports reports whether the firmware accepts command, given as a name
+// ("lock_ms1") or a call ("km.lock_ms1(1)").
+func (c Capabilities) Supports(command string) bool {
+	name := strings.TrimPrefix(command, "km.")
+	if i := strings.IndexByte(name, '('); i >= 0 {
+		name = name[:i]
+	}
+	return c.Commands[name]
+}
+
+// firmwareRelease is what one firmware release added to the ones before
+// it.
+type firmwareRelease struct {
+	major, minor, patch int
+	commands            []string // km.* commands added
+	maxMoveSegments     int      // new segment limit, or 0 to keep the previous one
+}
+
+// firmwareReleases is the capability table, oldest release first. The
+// first entry is what every MAKCU firmware accepts.
+var firmwareReleases = []firmwareRelease{
+	{0, 0, 0, []string{
+		"move", "wheel", "left", "right", "middle", "ms1", "ms2",
+		"lock_ml", "lock_mr", "lock_mm", "lock_ms1", "lock_ms2", "lock_mx", "lock_my",
+		"buttons", "version",
+	}, 512},
+	// 3.2 added button catch and serial spoofing.
+	{3, 2, 0, []string{"catch_ml", "catch_mr", "catch_mm", "catch_ms1", "catch_ms2", "serial"}, 0},
+	// 3.5 doubled the segments of a smooth or bezier move.
+	{3, 5, 0, nil, 1024},
+}
+
+// capabilitiesFor returns what firmware v supports according to
+// firmwareReleases. Firmware without a version number gets every release's
+// commands, since nothing is gated for it; it is not Resolved.
+func capabilitiesFor(v FirmwareVersion) Capabilities {
+	c := Capabilities{
+		Firmware:  v,
+		Commands:  make(map[string]bool),
+		BaudRates: []int{115200, 4000000},
+		Resolved:  v.Known,
+	}
+	for i, r := range firmwareReleases {
+		if i > 0 && v.Known && !v.AtLeast(r.major, r.minor, r.patch) {
+			break
+		}
+		for _, name := range r.commands {
+			c.Commands[name] = true
+		}
+		if r.maxMoveSegments > 0 {
+			c.MaxMoveSegments = r.maxMoveSegments
+		}
+	}
+	return c
+}
@@ -1,9 +1,19 @@
-module github.com/Auchrio/Makcu-go-lib
+module github.com/Auchrio/Makcu-go-lib/v3
 
 go 1.25.6
 
+require (
+	go.bug.st/serial v1.6.4
+	go.starlark.net v0.0.0-20260908191801-89a6a09411d5
+	golang.org/x/net v0.49.0
+	golang.org/x/term v0.41.0
+	google.golang.org/grpc v1.80.0
+	google.golang.org/protobuf v1.36.11
+)
+
 require (
 	github.com/creack/goselect v0.1.2 // indirect
-	go.bug.st/serial v1.6.4 // indirect
-	golang.org/x/sys v0.19.0 // indirect
+	golang.org/x/sys v0.42.0 // indirect
+	golang.org/x/text v0.33.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20260120221211-b8f7ae30c516 // indirect
 )
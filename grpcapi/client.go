@@ -0,0 +1,283 @@
+package grpcapi
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	Macku "github.com/Auchrio/Makcu-go-lib/v3"
+	"github.com/Auchrio/Makcu-go-lib/v3/grpcapi/makcupb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// DefaultCallTimeout bounds each unary call made by a Client.
+const DefaultCallTimeout = 5 * time.Second
+
+// Client drives a remote device with the same methods as MakcuController.
+type Client struct {
+	Timeout time.Duration // per-call deadline; 0 means DefaultCallTimeout
+
+	rpc   makcupb.MakcuClient
+	conn  *grpc.ClientConn // owned connection, nil for NewClient
+	ctx   context.Context
+	close context.CancelFunc
+
+	mu          sync.Mutex
+	stopButtons context.CancelFunc
+}
+
+// Dial connects to a grpcapi server at target (e.g. "lab-pc:7710"). Without
+// options the connection is plaintext; pass grpc.WithTransportCredentials
+// for TLS.
+func Dial(target string, opts ...grpc.DialOption) (*Client, error) {
+	if len(opts) == 0 {
+		opts = []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	}
+	conn, err := grpc.NewClient(target, opts...)
+	if err != nil {
+		return nil, Macku.NewConnectionError(err.Error())
+	}
+	c := NewClient(conn)
+	c.conn = conn
+	return c, nil
+}
+
+// NewClient wraps an existing connection. Close does not close conn.
+func NewClient(conn grpc.ClientConnInterface) *Client {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Client{rpc: makcupb.NewMakcuClient(conn), ctx: ctx, close: cancel}
+}
+
+// Close stops event streams and closes the connection opened by Dial.
+func (c *Client) Close() error {
+	c.close()
+	if c.conn != nil {
+		return c.conn.Close()
+	}
+	return nil
+}
+
+func (c *Client) callCtx() (context.Context, context.CancelFunc) {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultCallTimeout
+	}
+	return context.WithTimeout(c.ctx, timeout)
+}
+
+// --- basic mouse actions ---
+
+// Move moves the cursor by (dx, dy).
+func (c *Client) Move(dx, dy int) error {
+	ctx, cancel := c.callCtx()
+	defer cancel()
+	_, err := c.rpc.Move(ctx, &makcupb.MoveRequest{Dx: int32(dx), Dy: int32(dy)})
+	return fromStatus(err)
+}
+
+// MoveSmooth moves by (dx, dy) in the given number of segments.
+func (c *Client) MoveSmooth(dx, dy, segments int) error {
+	ctx, cancel := c.callCtx()
+	defer cancel()
+	_, err := c.rpc.MoveSmooth(ctx, &makcupb.MoveRequest{Dx: int32(dx), Dy: int32(dy), Segments: int32(segments)})
+	return fromStatus(err)
+}
+
+// Click presses and releases a mouse button.
+func (c *Client) Click(button Macku.MouseButton) error {
+	ctx, cancel := c.callCtx()
+	defer cancel()
+	_, err := c.rpc.Click(ctx, &makcupb.ButtonRequest{Button: buttonToProto(button)})
+	return fromStatus(err)
+}
+
+// Press presses and holds a mouse button.
+func (c *Client) Press(button Macku.MouseButton) error {
+	ctx, cancel := c.callCtx()
+	defer cancel()
+	_, err := c.rpc.Press(ctx, &makcupb.ButtonRequest{Button: buttonToProto(button)})
+	return fromStatus(err)
+}
+
+// Release releases a held mouse button.
+func (c *Client) Release(button Macku.MouseButton) error {
+	ctx, cancel := c.callCtx()
+	defer cancel()
+	_, err := c.rpc.Release(ctx, &makcupb.ButtonRequest{Button: buttonToProto(button)})
+	return fromStatus(err)
+}
+
+// Scroll scrolls the mouse wheel.
+func (c *Client) Scroll(delta int) error {
+	ctx, cancel := c.callCtx()
+	defer cancel()
+	_, err := c.rpc.Scroll(ctx, &makcupb.ScrollRequest{Delta: int32(delta)})
+	return fromStatus(err)
+}
+
+// --- locking ---
+
+// Lock locks the given target.
+func (c *Client) Lock(target Macku.LockTarget) error {
+	return c.setLock(target, true)
+}
+
+// Unlock unlocks the given target.
+func (c *Client) Unlock(target Macku.LockTarget) error {
+	return c.setLock(target, false)
+}
+
+func (c *Client) setLock(target Macku.LockTarget, locked bool) error {
+	ctx, cancel := c.callCtx()
+	defer cancel()
+	_, err := c.rpc.SetLock(ctx, &makcupb.SetLockRequest{Target: lockToProto(target), Locked: locked})
+	return fromStatus(err)
+}
+
+// IsLocked checks whether the given button is currently locked.
+func (c *Client) IsLocked(button Macku.MouseButton) (bool, error) {
+	states, err := c.GetAllLockStates()
+	if err != nil {
+		return false, err
+	}
+	return states[strings.ToUpper(button.String())], nil
+}
+
+// GetAllLockStates returns the lock state for every button and axis.
+func (c *Client) GetAllLockStates() (map[string]bool, error) {
+	ctx, cancel := c.callCtx()
+	defer cancel()
+	resp, err := c.rpc.GetLockStates(ctx, empty)
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	return resp.States, nil
+}
+
+// --- device info ---
+
+// GetDeviceInfo returns information about the remote device.
+func (c *Client) GetDeviceInfo() (Macku.DeviceInfo, error) {
+	ctx, cancel := c.callCtx()
+	defer cancel()
+	resp, err := c.rpc.GetDeviceInfo(ctx, empty)
+	if err != nil {
+		return Macku.DeviceInfo{}, fromStatus(err)
+	}
+	return Macku.DeviceInfo{
+		Port:        resp.Port,
+		Description: resp.Description,
+		VID:         resp.Vid,
+		PID:         resp.Pid,
+		IsConnected: resp.Connected,
+	}, nil
+}
+
+// GetFirmwareVersion queries the remote device for its firmware version string.
+func (c *Client) GetFirmwareVersion() (string, error) {
+	ctx, cancel := c.callCtx()
+	defer cancel()
+	resp, err := c.rpc.GetFirmwareVersion(ctx, empty)
+	if err != nil {
+		return "", fromStatus(err)
+	}
+	return resp.Version, nil
+}
+
+// --- button monitoring ---
+
+// GetButtonMask returns the raw button bitmask.
+func (c *Client) GetButtonMask() (int, error) {
+	ctx, cancel := c.callCtx()
+	defer cancel()
+	resp, err := c.rpc.GetButtonStates(ctx, empty)
+	if err != nil {
+		return 0, fromStatus(err)
+	}
+	return int(resp.Mask), nil
+}
+
+// GetButtonStates returns a map of button name to pressed state.
+func (c *Client) GetButtonStates() (map[string]bool, error) {
+	ctx, cancel := c.callCtx()
+	defer cancel()
+	resp, err := c.rpc.GetButtonStates(ctx, empty)
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	return resp.States, nil
+}
+
+// IsPressed checks whether the given button is currently held down.
+func (c *Client) IsPressed(button Macku.MouseButton) (bool, error) {
+	states, err := c.GetButtonStates()
+	if err != nil {
+		return false, err
+	}
+	return states[button.String()], nil
+}
+
+// SetButtonCallback streams button events from the server into cb. It
+// returns once the server has subscribed, so no later event is missed.
+// Pass nil to stop the stream.
+func (c *Client) SetButtonCallback(cb func(Macku.MouseButton, bool)) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stopButtons != nil {
+		c.stopButtons()
+		c.stopButtons = nil
+	}
+	if cb == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(c.ctx)
+	stream, err := c.rpc.WatchButtons(ctx, empty)
+	if err == nil {
+		_, err = stream.Header()
+	}
+	if err != nil {
+		cancel()
+		return fromStatus(err)
+	}
+	c.stopButtons = cancel
+	go func() {
+		defer cancel()
+		for {
+			ev, err := stream.Recv()
+			if err != nil {
+				return
+			}
+			b, err := buttonFromProto(ev.Button)
+			if err != nil {
+				continue
+			}
+			cb(b, ev.Pressed)
+		}
+	}()
+	return nil
+}
+
+// OnConnectionChange streams the remote controller's connection state into
+// cb, starting with the current state. The stream ends when the returned
+// function is called, the Client is closed or the server goes away.
+func (c *Client) OnConnectionChange(cb func(bool)) (remove func()) {
+	ctx, cancel := context.WithCancel(c.ctx)
+	stream, err := c.rpc.WatchConnection(ctx, empty)
+	if err != nil {
+		return cancel
+	}
+	go func() {
+		defer cancel()
+		for {
+			st, err := stream.Recv()
+			if err != nil {
+				return
+			}
+			cb(st.Connected)
+		}
+	}()
+	return cancel
+}
@@ -0,0 +1,861 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: makcupb/makcu.proto
+
+// Remote control of a Makcu device. Mirrors the MakcuController API of
+// github.com/Auchrio/Makcu-go-lib.
+
+package makcupb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Values are the library's MouseButton plus one; 0 is invalid.
+type MouseButton int32
+
+const (
+	MouseButton_MOUSE_BUTTON_UNSPECIFIED MouseButton = 0
+	MouseButton_MOUSE_BUTTON_LEFT        MouseButton = 1
+	MouseButton_MOUSE_BUTTON_RIGHT       MouseButton = 2
+	MouseButton_MOUSE_BUTTON_MIDDLE      MouseButton = 3
+	MouseButton_MOUSE_BUTTON_MOUSE4      MouseButton = 4
+	MouseButton_MOUSE_BUTTON_MOUSE5      MouseButton = 5
+)
+
+// Enum value maps for MouseButton.
+var (
+	MouseButton_name = map[int32]string{
+		0: "MOUSE_BUTTON_UNSPECIFIED",
+		1: "MOUSE_BUTTON_LEFT",
+		2: "MOUSE_BUTTON_RIGHT",
+		3: "MOUSE_BUTTON_MIDDLE",
+		4: "MOUSE_BUTTON_MOUSE4",
+		5: "MOUSE_BUTTON_MOUSE5",
+	}
+	MouseButton_value = map[string]int32{
+		"MOUSE_BUTTON_UNSPECIFIED": 0,
+		"MOUSE_BUTTON_LEFT":        1,
+		"MOUSE_BUTTON_RIGHT":       2,
+		"MOUSE_BUTTON_MIDDLE":      3,
+		"MOUSE_BUTTON_MOUSE4":      4,
+		"MOUSE_BUTTON_MOUSE5":      5,
+	}
+)
+
+func (x MouseButton) Enum() *MouseButton {
+	p := new(MouseButton)
+	*p = x
+	return p
+}
+
+func (x MouseButton) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (MouseButton) Descriptor() protoreflect.EnumDescriptor {
+	return file_makcupb_makcu_proto_enumTypes[0].Descriptor()
+}
+
+func (MouseButton) Type() protoreflect.EnumType {
+	return &file_makcupb_makcu_proto_enumTypes[0]
+}
+
+func (x MouseButton) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use MouseButton.Descriptor instead.
+func (MouseButton) EnumDescriptor() ([]byte, []int) {
+	return file_makcupb_makcu_proto_rawDescGZIP(), []int{0}
+}
+
+// Values are the library's LockTarget plus one; 0 is invalid.
+type LockTarget int32
+
+const (
+	LockTarget_LOCK_TARGET_UNSPECIFIED LockTarget = 0
+	LockTarget_LOCK_TARGET_LEFT        LockTarget = 1
+	LockTarget_LOCK_TARGET_RIGHT       LockTarget = 2
+	LockTarget_LOCK_TARGET_MIDDLE      LockTarget = 3
+	LockTarget_LOCK_TARGET_MOUSE4      LockTarget = 4
+	LockTarget_LOCK_TARGET_MOUSE5      LockTarget = 5
+	LockTarget_LOCK_TARGET_X           LockTarget = 6
+	LockTarget_LOCK_TARGET_Y           LockTarget = 7
+)
+
+// Enum value maps for LockTarget.
+var (
+	LockTarget_name = map[int32]string{
+		0: "LOCK_TARGET_UNSPECIFIED",
+		1: "LOCK_TARGET_LEFT",
+		2: "LOCK_TARGET_RIGHT",
+		3: "LOCK_TARGET_MIDDLE",
+		4: "LOCK_TARGET_MOUSE4",
+		5: "LOCK_TARGET_MOUSE5",
+		6: "LOCK_TARGET_X",
+		7: "LOCK_TARGET_Y",
+	}
+	LockTarget_value = map[string]int32{
+		"LOCK_TARGET_UNSPECIFIED": 0,
+		"LOCK_TARGET_LEFT":        1,
+		"LOCK_TARGET_RIGHT":       2,
+		"LOCK_TARGET_MIDDLE":      3,
+		"LOCK_TARGET_MOUSE4":      4,
+		"LOCK_TARGET_MOUSE5":      5,
+		"LOCK_TARGET_X":           6,
+		"LOCK_TARGET_Y":           7,
+	}
+)
+
+func (x LockTarget) Enum() *LockTarget {
+	p := new(LockTarget)
+	*p = x
+	return p
+}
+
+func (x LockTarget) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (LockTarget) Descriptor() protoreflect.EnumDescriptor {
+	return file_makcupb_makcu_proto_enumTypes[1].Descriptor()
+}
+
+func (LockTarget) Type() protoreflect.EnumType {
+	return &file_makcupb_makcu_proto_enumTypes[1]
+}
+
+func (x LockTarget) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use LockTarget.Descriptor instead.
+func (LockTarget) EnumDescriptor() ([]byte, []int) {
+	return file_makcupb_makcu_proto_rawDescGZIP(), []int{1}
+}
+
+type MoveRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Dx            int32                  `protobuf:"varint,1,opt,name=dx,proto3" json:"dx,omitempty"`
+	Dy            int32                  `protobuf:"varint,2,opt,name=dy,proto3" json:"dy,omitempty"`
+	Segments      int32                  `protobuf:"varint,3,opt,name=segments,proto3" json:"segments,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MoveRequest) Reset() {
+	*x = MoveRequest{}
+	mi := &file_makcupb_makcu_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MoveRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MoveRequest) ProtoMessage() {}
+
+func (x *MoveRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_makcupb_makcu_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MoveRequest.ProtoReflect.Descriptor instead.
+func (*MoveRequest) Descriptor() ([]byte, []int) {
+	return file_makcupb_makcu_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *MoveRequest) GetDx() int32 {
+	if x != nil {
+		return x.Dx
+	}
+	return 0
+}
+
+func (x *MoveRequest) GetDy() int32 {
+	if x != nil {
+		return x.Dy
+	}
+	return 0
+}
+
+func (x *MoveRequest) GetSegments() int32 {
+	if x != nil {
+		return x.Segments
+	}
+	return 0
+}
+
+type ButtonRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Button        MouseButton            `protobuf:"varint,1,opt,name=button,proto3,enum=makcu.v1.MouseButton" json:"button,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ButtonRequest) Reset() {
+	*x = ButtonRequest{}
+	mi := &file_makcupb_makcu_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ButtonRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ButtonRequest) ProtoMessage() {}
+
+func (x *ButtonRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_makcupb_makcu_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ButtonRequest.ProtoReflect.Descriptor instead.
+func (*ButtonRequest) Descriptor() ([]byte, []int) {
+	return file_makcupb_makcu_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ButtonRequest) GetButton() MouseButton {
+	if x != nil {
+		return x.Button
+	}
+	return MouseButton_MOUSE_BUTTON_UNSPECIFIED
+}
+
+type ScrollRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Delta         int32                  `protobuf:"varint,1,opt,name=delta,proto3" json:"delta,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ScrollRequest) Reset() {
+	*x = ScrollRequest{}
+	mi := &file_makcupb_makcu_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScrollRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScrollRequest) ProtoMessage() {}
+
+func (x *ScrollRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_makcupb_makcu_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScrollRequest.ProtoReflect.Descriptor instead.
+func (*ScrollRequest) Descriptor() ([]byte, []int) {
+	return file_makcupb_makcu_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ScrollRequest) GetDelta() int32 {
+	if x != nil {
+		return x.Delta
+	}
+	return 0
+}
+
+type SetLockRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Target        LockTarget             `protobuf:"varint,1,opt,name=target,proto3,enum=makcu.v1.LockTarget" json:"target,omitempty"`
+	Locked        bool                   `protobuf:"varint,2,opt,name=locked,proto3" json:"locked,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetLockRequest) Reset() {
+	*x = SetLockRequest{}
+	mi := &file_makcupb_makcu_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetLockRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetLockRequest) ProtoMessage() {}
+
+func (x *SetLockRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_makcupb_makcu_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetLockRequest.ProtoReflect.Descriptor instead.
+func (*SetLockRequest) Descriptor() ([]byte, []int) {
+	return file_makcupb_makcu_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *SetLockRequest) GetTarget() LockTarget {
+	if x != nil {
+		return x.Target
+	}
+	return LockTarget_LOCK_TARGET_UNSPECIFIED
+}
+
+func (x *SetLockRequest) GetLocked() bool {
+	if x != nil {
+		return x.Locked
+	}
+	return false
+}
+
+type LockStates struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Keyed by LEFT, RIGHT, MIDDLE, MOUSE4, MOUSE5, X, Y.
+	States        map[string]bool `protobuf:"bytes,1,rep,name=states,proto3" json:"states,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LockStates) Reset() {
+	*x = LockStates{}
+	mi := &file_makcupb_makcu_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LockStates) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LockStates) ProtoMessage() {}
+
+func (x *LockStates) ProtoReflect() protoreflect.Message {
+	mi := &file_makcupb_makcu_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LockStates.ProtoReflect.Descriptor instead.
+func (*LockStates) Descriptor() ([]byte, []int) {
+	return file_makcupb_makcu_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *LockStates) GetStates() map[string]bool {
+	if x != nil {
+		return x.States
+	}
+	return nil
+}
+
+type DeviceInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Port          string                 `protobuf:"bytes,1,opt,name=port,proto3" json:"port,omitempty"`
+	Description   string                 `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	Vid           string                 `protobuf:"bytes,3,opt,name=vid,proto3" json:"vid,omitempty"`
+	Pid           string                 `protobuf:"bytes,4,opt,name=pid,proto3" json:"pid,omitempty"`
+	Connected     bool                   `protobuf:"varint,5,opt,name=connected,proto3" json:"connected,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeviceInfo) Reset() {
+	*x = DeviceInfo{}
+	mi := &file_makcupb_makcu_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeviceInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeviceInfo) ProtoMessage() {}
+
+func (x *DeviceInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_makcupb_makcu_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeviceInfo.ProtoReflect.Descriptor instead.
+func (*DeviceInfo) Descriptor() ([]byte, []int) {
+	return file_makcupb_makcu_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *DeviceInfo) GetPort() string {
+	if x != nil {
+		return x.Port
+	}
+	return ""
+}
+
+func (x *DeviceInfo) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *DeviceInfo) GetVid() string {
+	if x != nil {
+		return x.Vid
+	}
+	return ""
+}
+
+func (x *DeviceInfo) GetPid() string {
+	if x != nil {
+		return x.Pid
+	}
+	return ""
+}
+
+func (x *DeviceInfo) GetConnected() bool {
+	if x != nil {
+		return x.Connected
+	}
+	return false
+}
+
+type FirmwareVersion struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Version       string                 `protobuf:"bytes,1,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FirmwareVersion) Reset() {
+	*x = FirmwareVersion{}
+	mi := &file_makcupb_makcu_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FirmwareVersion) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FirmwareVersion) ProtoMessage() {}
+
+func (x *FirmwareVersion) ProtoReflect() protoreflect.Message {
+	mi := &file_makcupb_makcu_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FirmwareVersion.ProtoReflect.Descriptor instead.
+func (*FirmwareVersion) Descriptor() ([]byte, []int) {
+	return file_makcupb_makcu_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *FirmwareVersion) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+type ButtonStates struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Keyed by button name: left, right, middle, mouse4, mouse5.
+	States        map[string]bool `protobuf:"bytes,1,rep,name=states,proto3" json:"states,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	Mask          int32           `protobuf:"varint,2,opt,name=mask,proto3" json:"mask,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ButtonStates) Reset() {
+	*x = ButtonStates{}
+	mi := &file_makcupb_makcu_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ButtonStates) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ButtonStates) ProtoMessage() {}
+
+func (x *ButtonStates) ProtoReflect() protoreflect.Message {
+	mi := &file_makcupb_makcu_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ButtonStates.ProtoReflect.Descriptor instead.
+func (*ButtonStates) Descriptor() ([]byte, []int) {
+	return file_makcupb_makcu_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ButtonStates) GetStates() map[string]bool {
+	if x != nil {
+		return x.States
+	}
+	return nil
+}
+
+func (x *ButtonStates) GetMask() int32 {
+	if x != nil {
+		return x.Mask
+	}
+	return 0
+}
+
+type ButtonEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Button        MouseButton            `protobuf:"varint,1,opt,name=button,proto3,enum=makcu.v1.MouseButton" json:"button,omitempty"`
+	Pressed       bool                   `protobuf:"varint,2,opt,name=pressed,proto3" json:"pressed,omitempty"`
+	Mask          int32                  `protobuf:"varint,3,opt,name=mask,proto3" json:"mask,omitempty"`
+	UnixNano      int64                  `protobuf:"varint,4,opt,name=unix_nano,json=unixNano,proto3" json:"unix_nano,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ButtonEvent) Reset() {
+	*x = ButtonEvent{}
+	mi := &file_makcupb_makcu_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ButtonEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ButtonEvent) ProtoMessage() {}
+
+func (x *ButtonEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_makcupb_makcu_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ButtonEvent.ProtoReflect.Descriptor instead.
+func (*ButtonEvent) Descriptor() ([]byte, []int) {
+	return file_makcupb_makcu_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ButtonEvent) GetButton() MouseButton {
+	if x != nil {
+		return x.Button
+	}
+	return MouseButton_MOUSE_BUTTON_UNSPECIFIED
+}
+
+func (x *ButtonEvent) GetPressed() bool {
+	if x != nil {
+		return x.Pressed
+	}
+	return false
+}
+
+func (x *ButtonEvent) GetMask() int32 {
+	if x != nil {
+		return x.Mask
+	}
+	return 0
+}
+
+func (x *ButtonEvent) GetUnixNano() int64 {
+	if x != nil {
+		return x.UnixNano
+	}
+	return 0
+}
+
+type ConnectionState struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Connected     bool                   `protobuf:"varint,1,opt,name=connected,proto3" json:"connected,omitempty"`
+	UnixNano      int64                  `protobuf:"varint,2,opt,name=unix_nano,json=unixNano,proto3" json:"unix_nano,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConnectionState) Reset() {
+	*x = ConnectionState{}
+	mi := &file_makcupb_makcu_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConnectionState) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConnectionState) ProtoMessage() {}
+
+func (x *ConnectionState) ProtoReflect() protoreflect.Message {
+	mi := &file_makcupb_makcu_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConnectionState.ProtoReflect.Descriptor instead.
+func (*ConnectionState) Descriptor() ([]byte, []int) {
+	return file_makcupb_makcu_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ConnectionState) GetConnected() bool {
+	if x != nil {
+		return x.Connected
+	}
+	return false
+}
+
+func (x *ConnectionState) GetUnixNano() int64 {
+	if x != nil {
+		return x.UnixNano
+	}
+	return 0
+}
+
+var File_makcupb_makcu_proto protoreflect.FileDescriptor
+
+const file_makcupb_makcu_proto_rawDesc = "" +
+	"\n" +
+	"\x13makcupb/makcu.proto\x12\bmakcu.v1\x1a\x1bgoogle/protobuf/empty.proto\"I\n" +
+	"\vMoveRequest\x12\x0e\n" +
+	"\x02dx\x18\x01 \x01(\x05R\x02dx\x12\x0e\n" +
+	"\x02dy\x18\x02 \x01(\x05R\x02dy\x12\x1a\n" +
+	"\bsegments\x18\x03 \x01(\x05R\bsegments\">\n" +
+	"\rButtonRequest\x12-\n" +
+	"\x06button\x18\x01 \x01(\x0e2\x15.makcu.v1.MouseButtonR\x06button\"%\n" +
+	"\rScrollRequest\x12\x14\n" +
+	"\x05delta\x18\x01 \x01(\x05R\x05delta\"V\n" +
+	"\x0eSetLockRequest\x12,\n" +
+	"\x06target\x18\x01 \x01(\x0e2\x14.makcu.v1.LockTargetR\x06target\x12\x16\n" +
+	"\x06locked\x18\x02 \x01(\bR\x06locked\"\x81\x01\n" +
+	"\n" +
+	"LockStates\x128\n" +
+	"\x06states\x18\x01 \x03(\v2 .makcu.v1.LockStates.StatesEntryR\x06states\x1a9\n" +
+	"\vStatesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\bR\x05value:\x028\x01\"\x84\x01\n" +
+	"\n" +
+	"DeviceInfo\x12\x12\n" +
+	"\x04port\x18\x01 \x01(\tR\x04port\x12 \n" +
+	"\vdescription\x18\x02 \x01(\tR\vdescription\x12\x10\n" +
+	"\x03vid\x18\x03 \x01(\tR\x03vid\x12\x10\n" +
+	"\x03pid\x18\x04 \x01(\tR\x03pid\x12\x1c\n" +
+	"\tconnected\x18\x05 \x01(\bR\tconnected\"+\n" +
+	"\x0fFirmwareVersion\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\tR\aversion\"\x99\x01\n" +
+	"\fButtonStates\x12:\n" +
+	"\x06states\x18\x01 \x03(\v2\".makcu.v1.ButtonStates.StatesEntryR\x06states\x12\x12\n" +
+	"\x04mask\x18\x02 \x01(\x05R\x04mask\x1a9\n" +
+	"\vStatesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\bR\x05value:\x028\x01\"\x87\x01\n" +
+	"\vButtonEvent\x12-\n" +
+	"\x06button\x18\x01 \x01(\x0e2\x15.makcu.v1.MouseButtonR\x06button\x12\x18\n" +
+	"\apressed\x18\x02 \x01(\bR\apressed\x12\x12\n" +
+	"\x04mask\x18\x03 \x01(\x05R\x04mask\x12\x1b\n" +
+	"\tunix_nano\x18\x04 \x01(\x03R\bunixNano\"L\n" +
+	"\x0fConnectionState\x12\x1c\n" +
+	"\tconnected\x18\x01 \x01(\bR\tconnected\x12\x1b\n" +
+	"\tunix_nano\x18\x02 \x01(\x03R\bunixNano*\xa5\x01\n" +
+	"\vMouseButton\x12\x1c\n" +
+	"\x18MOUSE_BUTTON_UNSPECIFIED\x10\x00\x12\x15\n" +
+	"\x11MOUSE_BUTTON_LEFT\x10\x01\x12\x16\n" +
+	"\x12MOUSE_BUTTON_RIGHT\x10\x02\x12\x17\n" +
+	"\x13MOUSE_BUTTON_MIDDLE\x10\x03\x12\x17\n" +
+	"\x13MOUSE_BUTTON_MOUSE4\x10\x04\x12\x17\n" +
+	"\x13MOUSE_BUTTON_MOUSE5\x10\x05*\xc4\x01\n" +
+	"\n" +
+	"LockTarget\x12\x1b\n" +
+	"\x17LOCK_TARGET_UNSPECIFIED\x10\x00\x12\x14\n" +
+	"\x10LOCK_TARGET_LEFT\x10\x01\x12\x15\n" +
+	"\x11LOCK_TARGET_RIGHT\x10\x02\x12\x16\n" +
+	"\x12LOCK_TARGET_MIDDLE\x10\x03\x12\x16\n" +
+	"\x12LOCK_TARGET_MOUSE4\x10\x04\x12\x16\n" +
+	"\x12LOCK_TARGET_MOUSE5\x10\x05\x12\x11\n" +
+	"\rLOCK_TARGET_X\x10\x06\x12\x11\n" +
+	"\rLOCK_TARGET_Y\x10\a2\xb6\x06\n" +
+	"\x05Makcu\x125\n" +
+	"\x04Move\x12\x15.makcu.v1.MoveRequest\x1a\x16.google.protobuf.Empty\x12;\n" +
+	"\n" +
+	"MoveSmooth\x12\x15.makcu.v1.MoveRequest\x1a\x16.google.protobuf.Empty\x128\n" +
+	"\x05Click\x12\x17.makcu.v1.ButtonRequest\x1a\x16.google.protobuf.Empty\x128\n" +
+	"\x05Press\x12\x17.makcu.v1.ButtonRequest\x1a\x16.google.protobuf.Empty\x12:\n" +
+	"\aRelease\x12\x17.makcu.v1.ButtonRequest\x1a\x16.google.protobuf.Empty\x129\n" +
+	"\x06Scroll\x12\x17.makcu.v1.ScrollRequest\x1a\x16.google.protobuf.Empty\x12;\n" +
+	"\aSetLock\x12\x18.makcu.v1.SetLockRequest\x1a\x16.google.protobuf.Empty\x12=\n" +
+	"\rGetLockStates\x12\x16.google.protobuf.Empty\x1a\x14.makcu.v1.LockStates\x12=\n" +
+	"\rGetDeviceInfo\x12\x16.google.protobuf.Empty\x1a\x14.makcu.v1.DeviceInfo\x12G\n" +
+	"\x12GetFirmwareVersion\x12\x16.google.protobuf.Empty\x1a\x19.makcu.v1.FirmwareVersion\x12A\n" +
+	"\x0fGetButtonStates\x12\x16.google.protobuf.Empty\x1a\x16.makcu.v1.ButtonStates\x12?\n" +
+	"\fWatchButtons\x12\x16.google.protobuf.Empty\x1a\x15.makcu.v1.ButtonEvent0\x01\x12F\n" +
+	"\x0fWatchConnection\x12\x16.google.protobuf.Empty\x1a\x19.makcu.v1.ConnectionState0\x01B4Z2github.com/Auchrio/Makcu-go-lib/v3/grpcapi/makcupbb\x06proto3"
+
+var (
+	file_makcupb_makcu_proto_rawDescOnce sync.Once
+	file_makcupb_makcu_proto_rawDescData []byte
+)
+
+func file_makcupb_makcu_proto_rawDescGZIP() []byte {
+	file_makcupb_makcu_proto_rawDescOnce.Do(func() {
+		file_makcupb_makcu_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_makcupb_makcu_proto_rawDesc), len(file_makcupb_makcu_proto_rawDesc)))
+	})
+	return file_makcupb_makcu_proto_rawDescData
+}
+
+var file_makcupb_makcu_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_makcupb_makcu_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
+var file_makcupb_makcu_proto_goTypes = []any{
+	(MouseButton)(0),        // 0: makcu.v1.MouseButton
+	(LockTarget)(0),         // 1: makcu.v1.LockTarget
+	(*MoveRequest)(nil),     // 2: makcu.v1.MoveRequest
+	(*ButtonRequest)(nil),   // 3: makcu.v1.ButtonRequest
+	(*ScrollRequest)(nil),   // 4: makcu.v1.ScrollRequest
+	(*SetLockRequest)(nil),  // 5: makcu.v1.SetLockRequest
+	(*LockStates)(nil),      // 6: makcu.v1.LockStates
+	(*DeviceInfo)(nil),      // 7: makcu.v1.DeviceInfo
+	(*FirmwareVersion)(nil), // 8: makcu.v1.FirmwareVersion
+	(*ButtonStates)(nil),    // 9: makcu.v1.ButtonStates
+	(*ButtonEvent)(nil),     // 10: makcu.v1.ButtonEvent
+	(*ConnectionState)(nil), // 11: makcu.v1.ConnectionState
+	nil,                     // 12: makcu.v1.LockStates.StatesEntry
+	nil,                     // 13: makcu.v1.ButtonStates.StatesEntry
+	(*emptypb.Empty)(nil),   // 14: google.protobuf.Empty
+}
+var file_makcupb_makcu_proto_depIdxs = []int32{
+	0,  // 0: makcu.v1.ButtonRequest.button:type_name -> makcu.v1.MouseButton
+	1,  // 1: makcu.v1.SetLockRequest.target:type_name -> makcu.v1.LockTarget
+	12, // 2: makcu.v1.LockStates.states:type_name -> makcu.v1.LockStates.StatesEntry
+	13, // 3: makcu.v1.ButtonStates.states:type_name -> makcu.v1.ButtonStates.StatesEntry
+	0,  // 4: makcu.v1.ButtonEvent.button:type_name -> makcu.v1.MouseButton
+	2,  // 5: makcu.v1.Makcu.Move:input_type -> makcu.v1.MoveRequest
+	2,  // 6: makcu.v1.Makcu.MoveSmooth:input_type -> makcu.v1.MoveRequest
+	3,  // 7: makcu.v1.Makcu.Click:input_type -> makcu.v1.ButtonRequest
+	3,  // 8: makcu.v1.Makcu.Press:input_type -> makcu.v1.ButtonRequest
+	3,  // 9: makcu.v1.Makcu.Release:input_type -> makcu.v1.ButtonRequest
+	4,  // 10: makcu.v1.Makcu.Scroll:input_type -> makcu.v1.ScrollRequest
+	5,  // 11: makcu.v1.Makcu.SetLock:input_type -> makcu.v1.SetLockRequest
+	14, // 12: makcu.v1.Makcu.GetLockStates:input_type -> google.protobuf.Empty
+	14, // 13: makcu.v1.Makcu.GetDeviceInfo:input_type -> google.protobuf.Empty
+	14, // 14: makcu.v1.Makcu.GetFirmwareVersion:input_type -> google.protobuf.Empty
+	14, // 15: makcu.v1.Makcu.GetButtonStates:input_type -> google.protobuf.Empty
+	14, // 16: makcu.v1.Makcu.WatchButtons:input_type -> google.protobuf.Empty
+	14, // 17: makcu.v1.Makcu.WatchConnection:input_type -> google.protobuf.Empty
+	14, // 18: makcu.v1.Makcu.Move:output_type -> google.protobuf.Empty
+	14, // 19: makcu.v1.Makcu.MoveSmooth:output_type -> google.protobuf.Empty
+	14, // 20: makcu.v1.Makcu.Click:output_type -> google.protobuf.Empty
+	14, // 21: makcu.v1.Makcu.Press:output_type -> google.protobuf.Empty
+	14, // 22: makcu.v1.Makcu.Release:output_type -> google.protobuf.Empty
+	14, // 23: makcu.v1.Makcu.Scroll:output_type -> google.protobuf.Empty
+	14, // 24: makcu.v1.Makcu.SetLock:output_type -> google.protobuf.Empty
+	6,  // 25: makcu.v1.Makcu.GetLockStates:output_type -> makcu.v1.LockStates
+	7,  // 26: makcu.v1.Makcu.GetDeviceInfo:output_type -> makcu.v1.DeviceInfo
+	8,  // 27: makcu.v1.Makcu.GetFirmwareVersion:output_type -> makcu.v1.FirmwareVersion
+	9,  // 28: makcu.v1.Makcu.GetButtonStates:output_type -> makcu.v1.ButtonStates
+	10, // 29: makcu.v1.Makcu.WatchButtons:output_type -> makcu.v1.ButtonEvent
+	11, // 30: makcu.v1.Makcu.WatchConnection:output_type -> makcu.v1.ConnectionState
+	18, // [18:31] is the sub-list for method output_type
+	5,  // [5:18] is the sub-list for method input_type
+	5,  // [5:5] is the sub-list for extension type_name
+	5,  // [5:5] is the sub-list for extension extendee
+	0,  // [0:5] is the sub-list for field type_name
+}
+
+func init() { file_makcupb_makcu_proto_init() }
+func file_makcupb_makcu_proto_init() {
+	if File_makcupb_makcu_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_makcupb_makcu_proto_rawDesc), len(file_makcupb_makcu_proto_rawDesc)),
+			NumEnums:      2,
+			NumMessages:   12,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_makcupb_makcu_proto_goTypes,
+		DependencyIndexes: file_makcupb_makcu_proto_depIdxs,
+		EnumInfos:         file_makcupb_makcu_proto_enumTypes,
+		MessageInfos:      file_makcupb_makcu_proto_msgTypes,
+	}.Build()
+	File_makcupb_makcu_proto = out.File
+	file_makcupb_makcu_proto_goTypes = nil
+	file_makcupb_makcu_proto_depIdxs = nil
+}
@@ -0,0 +1,598 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: makcupb/makcu.proto
+
+// Remote control of a Makcu device. Mirrors the MakcuController API of
+// github.com/Auchrio/Makcu-go-lib.
+
+package makcupb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Makcu_Move_FullMethodName               = "/makcu.v1.Makcu/Move"
+	Makcu_MoveSmooth_FullMethodName         = "/makcu.v1.Makcu/MoveSmooth"
+	Makcu_Click_FullMethodName              = "/makcu.v1.Makcu/Click"
+	Makcu_Press_FullMethodName              = "/makcu.v1.Makcu/Press"
+	Makcu_Release_FullMethodName            = "/makcu.v1.Makcu/Release"
+	Makcu_Scroll_FullMethodName             = "/makcu.v1.Makcu/Scroll"
+	Makcu_SetLock_FullMethodName            = "/makcu.v1.Makcu/SetLock"
+	Makcu_GetLockStates_FullMethodName      = "/makcu.v1.Makcu/GetLockStates"
+	Makcu_GetDeviceInfo_FullMethodName      = "/makcu.v1.Makcu/GetDeviceInfo"
+	Makcu_GetFirmwareVersion_FullMethodName = "/makcu.v1.Makcu/GetFirmwareVersion"
+	Makcu_GetButtonStates_FullMethodName    = "/makcu.v1.Makcu/GetButtonStates"
+	Makcu_WatchButtons_FullMethodName       = "/makcu.v1.Makcu/WatchButtons"
+	Makcu_WatchConnection_FullMethodName    = "/makcu.v1.Makcu/WatchConnection"
+)
+
+// MakcuClient is the client API for Makcu service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type MakcuClient interface {
+	// Relative movement.
+	Move(ctx context.Context, in *MoveRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// Relative movement split into segments (segments = 0 behaves like Move).
+	MoveSmooth(ctx context.Context, in *MoveRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	Click(ctx context.Context, in *ButtonRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	Press(ctx context.Context, in *ButtonRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	Release(ctx context.Context, in *ButtonRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	Scroll(ctx context.Context, in *ScrollRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// Locks or unlocks a button or axis.
+	SetLock(ctx context.Context, in *SetLockRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	GetLockStates(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*LockStates, error)
+	GetDeviceInfo(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*DeviceInfo, error)
+	GetFirmwareVersion(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*FirmwareVersion, error)
+	GetButtonStates(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ButtonStates, error)
+	// Streams button changes until the client cancels.
+	WatchButtons(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ButtonEvent], error)
+	// Streams connection-state changes, starting with the current state.
+	WatchConnection(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ConnectionState], error)
+}
+
+type makcuClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewMakcuClient(cc grpc.ClientConnInterface) MakcuClient {
+	return &makcuClient{cc}
+}
+
+func (c *makcuClient) Move(ctx context.Context, in *MoveRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Makcu_Move_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *makcuClient) MoveSmooth(ctx context.Context, in *MoveRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Makcu_MoveSmooth_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *makcuClient) Click(ctx context.Context, in *ButtonRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Makcu_Click_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *makcuClient) Press(ctx context.Context, in *ButtonRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Makcu_Press_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *makcuClient) Release(ctx context.Context, in *ButtonRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Makcu_Release_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *makcuClient) Scroll(ctx context.Context, in *ScrollRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Makcu_Scroll_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *makcuClient) SetLock(ctx context.Context, in *SetLockRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Makcu_SetLock_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *makcuClient) GetLockStates(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*LockStates, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LockStates)
+	err := c.cc.Invoke(ctx, Makcu_GetLockStates_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *makcuClient) GetDeviceInfo(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*DeviceInfo, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeviceInfo)
+	err := c.cc.Invoke(ctx, Makcu_GetDeviceInfo_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *makcuClient) GetFirmwareVersion(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*FirmwareVersion, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FirmwareVersion)
+	err := c.cc.Invoke(ctx, Makcu_GetFirmwareVersion_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *makcuClient) GetButtonStates(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ButtonStates, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ButtonStates)
+	err := c.cc.Invoke(ctx, Makcu_GetButtonStates_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *makcuClient) WatchButtons(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ButtonEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Makcu_ServiceDesc.Streams[0], Makcu_WatchButtons_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[emptypb.Empty, ButtonEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Makcu_WatchButtonsClient = grpc.ServerStreamingClient[ButtonEvent]
+
+func (c *makcuClient) WatchConnection(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ConnectionState], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Makcu_ServiceDesc.Streams[1], Makcu_WatchConnection_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[emptypb.Empty, ConnectionState]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Makcu_WatchConnectionClient = grpc.ServerStreamingClient[ConnectionState]
+
+// MakcuServer is the server API for Makcu service.
+// All implementations must embed UnimplementedMakcuServer
+// for forward compatibility.
+type MakcuServer interface {
+	// Relative movement.
+	Move(context.Context, *MoveRequest) (*emptypb.Empty, error)
+	// Relative movement split into segments (segments = 0 behaves like Move).
+	MoveSmooth(context.Context, *MoveRequest) (*emptypb.Empty, error)
+	Click(context.Context, *ButtonRequest) (*emptypb.Empty, error)
+	Press(context.Context, *ButtonRequest) (*emptypb.Empty, error)
+	Release(context.Context, *ButtonRequest) (*emptypb.Empty, error)
+	Scroll(context.Context, *ScrollRequest) (*emptypb.Empty, error)
+	// Locks or unlocks a button or axis.
+	SetLock(context.Context, *SetLockRequest) (*emptypb.Empty, error)
+	GetLockStates(context.Context, *emptypb.Empty) (*LockStates, error)
+	GetDeviceInfo(context.Context, *emptypb.Empty) (*DeviceInfo, error)
+	GetFirmwareVersion(context.Context, *emptypb.Empty) (*FirmwareVersion, error)
+	GetButtonStates(context.Context, *emptypb.Empty) (*ButtonStates, error)
+	// Streams button changes until the client cancels.
+	WatchButtons(*emptypb.Empty, grpc.ServerStreamingServer[ButtonEvent]) error
+	// Streams connection-state changes, starting with the current state.
+	WatchConnection(*emptypb.Empty, grpc.ServerStreamingServer[ConnectionState]) error
+	mustEmbedUnimplementedMakcuServer()
+}
+
+// UnimplementedMakcuServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedMakcuServer struct{}
+
+func (UnimplementedMakcuServer) Move(context.Context, *MoveRequest) (*emptypb.Empty, error) {
+	return nil, status.Error(codes.Unimplemented, "method Move not implemented")
+}
+func (UnimplementedMakcuServer) MoveSmooth(context.Context, *MoveRequest) (*emptypb.Empty, error) {
+	return nil, status.Error(codes.Unimplemented, "method MoveSmooth not implemented")
+}
+func (UnimplementedMakcuServer) Click(context.Context, *ButtonRequest) (*emptypb.Empty, error) {
+	return nil, status.Error(codes.Unimplemented, "method Click not implemented")
+}
+func (UnimplementedMakcuServer) Press(context.Context, *ButtonRequest) (*emptypb.Empty, error) {
+	return nil, status.Error(codes.Unimplemented, "method Press not implemented")
+}
+func (UnimplementedMakcuServer) Release(context.Context, *ButtonRequest) (*emptypb.Empty, error) {
+	return nil, status.Error(codes.Unimplemented, "method Release not implemented")
+}
+func (UnimplementedMakcuServer) Scroll(context.Context, *ScrollRequest) (*emptypb.Empty, error) {
+	return nil, status.Error(codes.Unimplemented, "method Scroll not implemented")
+}
+func (UnimplementedMakcuServer) SetLock(context.Context, *SetLockRequest) (*emptypb.Empty, error) {
+	return nil, status.Error(codes.Unimplemented, "method SetLock not implemented")
+}
+func (UnimplementedMakcuServer) GetLockStates(context.Context, *emptypb.Empty) (*LockStates, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetLockStates not implemented")
+}
+func (UnimplementedMakcuServer) GetDeviceInfo(context.Context, *emptypb.Empty) (*DeviceInfo, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetDeviceInfo not implemented")
+}
+func (UnimplementedMakcuServer) GetFirmwareVersion(context.Context, *emptypb.Empty) (*FirmwareVersion, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetFirmwareVersion not implemented")
+}
+func (UnimplementedMakcuServer) GetButtonStates(context.Context, *emptypb.Empty) (*ButtonStates, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetButtonStates not implemented")
+}
+func (UnimplementedMakcuServer) WatchButtons(*emptypb.Empty, grpc.ServerStreamingServer[ButtonEvent]) error {
+	return status.Error(codes.Unimplemented, "method WatchButtons not implemented")
+}
+func (UnimplementedMakcuServer) WatchConnection(*emptypb.Empty, grpc.ServerStreamingServer[ConnectionState]) error {
+	return status.Error(codes.Unimplemented, "method WatchConnection not implemented")
+}
+func (UnimplementedMakcuServer) mustEmbedUnimplementedMakcuServer() {}
+func (UnimplementedMakcuServer) testEmbeddedByValue()               {}
+
+// UnsafeMakcuServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to MakcuServer will
+// result in compilation errors.
+type UnsafeMakcuServer interface {
+	mustEmbedUnimplementedMakcuServer()
+}
+
+func RegisterMakcuServer(s grpc.ServiceRegistrar, srv MakcuServer) {
+	// If the following call panics, it indicates UnimplementedMakcuServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Makcu_ServiceDesc, srv)
+}
+
+func _Makcu_Move_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MoveRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MakcuServer).Move(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Makcu_Move_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MakcuServer).Move(ctx, req.(*MoveRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Makcu_MoveSmooth_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MoveRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MakcuServer).MoveSmooth(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Makcu_MoveSmooth_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MakcuServer).MoveSmooth(ctx, req.(*MoveRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Makcu_Click_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ButtonRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MakcuServer).Click(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Makcu_Click_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MakcuServer).Click(ctx, req.(*ButtonRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Makcu_Press_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ButtonRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MakcuServer).Press(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Makcu_Press_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MakcuServer).Press(ctx, req.(*ButtonRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Makcu_Release_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ButtonRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MakcuServer).Release(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Makcu_Release_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MakcuServer).Release(ctx, req.(*ButtonRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Makcu_Scroll_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ScrollRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MakcuServer).Scroll(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Makcu_Scroll_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MakcuServer).Scroll(ctx, req.(*ScrollRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Makcu_SetLock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetLockRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MakcuServer).SetLock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Makcu_SetLock_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MakcuServer).SetLock(ctx, req.(*SetLockRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Makcu_GetLockStates_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MakcuServer).GetLockStates(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Makcu_GetLockStates_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MakcuServer).GetLockStates(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Makcu_GetDeviceInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MakcuServer).GetDeviceInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Makcu_GetDeviceInfo_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MakcuServer).GetDeviceInfo(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Makcu_GetFirmwareVersion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MakcuServer).GetFirmwareVersion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Makcu_GetFirmwareVersion_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MakcuServer).GetFirmwareVersion(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Makcu_GetButtonStates_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MakcuServer).GetButtonStates(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Makcu_GetButtonStates_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MakcuServer).GetButtonStates(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Makcu_WatchButtons_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(emptypb.Empty)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MakcuServer).WatchButtons(m, &grpc.GenericServerStream[emptypb.Empty, ButtonEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Makcu_WatchButtonsServer = grpc.ServerStreamingServer[ButtonEvent]
+
+func _Makcu_WatchConnection_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(emptypb.Empty)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MakcuServer).WatchConnection(m, &grpc.GenericServerStream[emptypb.Empty, ConnectionState]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Makcu_WatchConnectionServer = grpc.ServerStreamingServer[ConnectionState]
+
+// Makcu_ServiceDesc is the grpc.ServiceDesc for Makcu service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Makcu_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "makcu.v1.Makcu",
+	HandlerType: (*MakcuServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Move",
+			Handler:    _Makcu_Move_Handler,
+		},
+		{
+			MethodName: "MoveSmooth",
+			Handler:    _Makcu_MoveSmooth_Handler,
+		},
+		{
+			MethodName: "Click",
+			Handler:    _Makcu_Click_Handler,
+		},
+		{
+			MethodName: "Press",
+			Handler:    _Makcu_Press_Handler,
+		},
+		{
+			MethodName: "Release",
+			Handler:    _Makcu_Release_Handler,
+		},
+		{
+			MethodName: "Scroll",
+			Handler:    _Makcu_Scroll_Handler,
+		},
+		{
+			MethodName: "SetLock",
+			Handler:    _Makcu_SetLock_Handler,
+		},
+		{
+			MethodName: "GetLockStates",
+			Handler:    _Makcu_GetLockStates_Handler,
+		},
+		{
+			MethodName: "GetDeviceInfo",
+			Handler:    _Makcu_GetDeviceInfo_Handler,
+		},
+		{
+			MethodName: "GetFirmwareVersion",
+			Handler:    _Makcu_GetFirmwareVersion_Handler,
+		},
+		{
+			MethodName: "GetButtonStates",
+			Handler:    _Makcu_GetButtonStates_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchButtons",
+			Handler:       _Makcu_WatchButtons_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "WatchConnection",
+			Handler:       _Makcu_WatchConnection_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "makcupb/makcu.proto",
+}
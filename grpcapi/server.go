@@ -0,0 +1,284 @@
+// Package grpcapi serves a MakcuController over gRPC so a device can be
+// driven from another machine, and provides a Client with the controller's
+// Go API. The service is defined in makcupb/makcu.proto.
+//
+// Library errors map onto status codes and back: ErrConnection is
+// Unavailable, ErrTimeout is DeadlineExceeded, ErrCommand is
+// InvalidArgument and ErrResponse is Internal.
+package grpcapi
+
+//go:generate buf generate
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	Macku "github.com/Auchrio/Makcu-go-lib/v3"
+	"github.com/Auchrio/Makcu-go-lib/v3/grpcapi/makcupb"
+	"github.com/Auchrio/Makcu-go-lib/v3/internal/events"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// eventBuffer is the number of events queued per stream before dropping.
+const eventBuffer = 64
+
+// Server implements makcupb.MakcuServer over a connected MakcuController.
+// It takes over the controller's button callback to feed event streams.
+type Server struct {
+	makcupb.UnimplementedMakcuServer
+
+	c    *Macku.MakcuController
+	hub  *events.Hub
+	done chan struct{}
+	once sync.Once
+}
+
+// NewServer creates a Server for an already-connected controller.
+func NewServer(c *Macku.MakcuController) *Server {
+	return &Server{c: c, hub: events.Attach(c), done: make(chan struct{})}
+}
+
+// Register creates a Server for c and registers it with g.
+func Register(g grpc.ServiceRegistrar, c *Macku.MakcuController) *Server {
+	s := NewServer(c)
+	makcupb.RegisterMakcuServer(g, s)
+	return s
+}
+
+// Close ends open streams and releases the controller's button callback.
+func (s *Server) Close() {
+	s.once.Do(func() {
+		close(s.done)
+		s.hub.Detach()
+	})
+}
+
+var empty = &emptypb.Empty{}
+
+func reply(err error) (*emptypb.Empty, error) {
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return empty, nil
+}
+
+func (s *Server) Move(ctx context.Context, req *makcupb.MoveRequest) (*emptypb.Empty, error) {
+	return reply(s.c.Move(int(req.Dx), int(req.Dy)))
+}
+
+func (s *Server) MoveSmooth(ctx context.Context, req *makcupb.MoveRequest) (*emptypb.Empty, error) {
+	if req.Segments <= 0 {
+		return s.Move(ctx, req)
+	}
+	return reply(s.c.MoveSmooth(int(req.Dx), int(req.Dy), int(req.Segments)))
+}
+
+func (s *Server) Click(ctx context.Context, req *makcupb.ButtonRequest) (*emptypb.Empty, error) {
+	b, err := buttonFromProto(req.Button)
+	if err != nil {
+		return nil, err
+	}
+	return reply(s.c.Click(b))
+}
+
+func (s *Server) Press(ctx context.Context, req *makcupb.ButtonRequest) (*emptypb.Empty, error) {
+	b, err := buttonFromProto(req.Button)
+	if err != nil {
+		return nil, err
+	}
+	return reply(s.c.Press(b))
+}
+
+func (s *Server) Release(ctx context.Context, req *makcupb.ButtonRequest) (*emptypb.Empty, error) {
+	b, err := buttonFromProto(req.Button)
+	if err != nil {
+		return nil, err
+	}
+	return reply(s.c.Release(b))
+}
+
+func (s *Server) Scroll(ctx context.Context, req *makcupb.ScrollRequest) (*emptypb.Empty, error) {
+	return reply(s.c.Scroll(int(req.Delta)))
+}
+
+func (s *Server) SetLock(ctx context.Context, req *makcupb.SetLockRequest) (*emptypb.Empty, error) {
+	t, err := lockFromProto(req.Target)
+	if err != nil {
+		return nil, err
+	}
+	if req.Locked {
+		return reply(s.c.Lock(t))
+	}
+	return reply(s.c.Unlock(t))
+}
+
+func (s *Server) GetLockStates(ctx context.Context, _ *emptypb.Empty) (*makcupb.LockStates, error) {
+	states, err := s.c.GetAllLockStates()
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &makcupb.LockStates{States: states}, nil
+}
+
+func (s *Server) GetDeviceInfo(ctx context.Context, _ *emptypb.Empty) (*makcupb.DeviceInfo, error) {
+	info, err := s.c.GetDeviceInfo()
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &makcupb.DeviceInfo{
+		Port:        info.Port,
+		Description: info.Description,
+		Vid:         info.VID,
+		Pid:         info.PID,
+		Connected:   info.IsConnected,
+	}, nil
+}
+
+func (s *Server) GetFirmwareVersion(ctx context.Context, _ *emptypb.Empty) (*makcupb.FirmwareVersion, error) {
+	v, err := s.c.GetFirmwareVersion()
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &makcupb.FirmwareVersion{Version: v}, nil
+}
+
+func (s *Server) GetButtonStates(ctx context.Context, _ *emptypb.Empty) (*makcupb.ButtonStates, error) {
+	states, err := s.c.GetButtonStates()
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	mask, err := s.c.GetButtonMask()
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &makcupb.ButtonStates{States: states, Mask: int32(mask)}, nil
+}
+
+// WatchButtons streams button changes. Headers are sent once the
+// subscription is in place, so a client that waits for them sees every
+// later event.
+func (s *Server) WatchButtons(_ *emptypb.Empty, stream makcupb.Makcu_WatchButtonsServer) error {
+	sub := s.hub.Subscribe(eventBuffer)
+	defer sub.Close()
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-s.done:
+			return status.Error(codes.Unavailable, "server shutting down")
+		case ev, ok := <-sub.C():
+			if !ok {
+				return status.Error(codes.Unavailable, "server shutting down")
+			}
+			if ev.Kind != events.Button {
+				continue
+			}
+			if err := stream.Send(&makcupb.ButtonEvent{
+				Button:   buttonToProto(ev.Button),
+				Pressed:  ev.Pressed,
+				Mask:     int32(ev.Mask),
+				UnixNano: ev.Time.UnixNano(),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// WatchConnection sends the current connection state, then every change.
+func (s *Server) WatchConnection(_ *emptypb.Empty, stream makcupb.Makcu_WatchConnectionServer) error {
+	sub := s.hub.Subscribe(eventBuffer)
+	defer sub.Close()
+	if err := stream.Send(&makcupb.ConnectionState{Connected: s.c.IsConnected()}); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-s.done:
+			return status.Error(codes.Unavailable, "server shutting down")
+		case ev, ok := <-sub.C():
+			if !ok {
+				return status.Error(codes.Unavailable, "server shutting down")
+			}
+			if ev.Kind != events.Connection {
+				continue
+			}
+			if err := stream.Send(&makcupb.ConnectionState{
+				Connected: ev.Connected,
+				UnixNano:  ev.Time.UnixNano(),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// --- conversions ---
+
+func buttonFromProto(b makcupb.MouseButton) (Macku.MouseButton, error) {
+	if b <= makcupb.MouseButton_MOUSE_BUTTON_UNSPECIFIED || b > makcupb.MouseButton_MOUSE_BUTTON_MOUSE5 {
+		return 0, status.Errorf(codes.InvalidArgument, "invalid mouse button %v", b)
+	}
+	return Macku.MouseButton(b - 1), nil
+}
+
+func buttonToProto(b Macku.MouseButton) makcupb.MouseButton {
+	return makcupb.MouseButton(b + 1)
+}
+
+func lockFromProto(t makcupb.LockTarget) (Macku.LockTarget, error) {
+	if t <= makcupb.LockTarget_LOCK_TARGET_UNSPECIFIED || t > makcupb.LockTarget_LOCK_TARGET_Y {
+		return 0, status.Errorf(codes.InvalidArgument, "invalid lock target %v", t)
+	}
+	return Macku.LockTarget(t - 1), nil
+}
+
+func lockToProto(t Macku.LockTarget) makcupb.LockTarget {
+	return makcupb.LockTarget(t + 1)
+}
+
+// toStatus converts a library error into a gRPC status error.
+func toStatus(err error) error {
+	code := codes.Unknown
+	switch {
+	case errors.Is(err, Macku.ErrConnection):
+		code = codes.Unavailable
+	case errors.Is(err, Macku.ErrTimeout):
+		code = codes.DeadlineExceeded
+	case errors.Is(err, Macku.ErrCommand):
+		code = codes.InvalidArgument
+	case errors.Is(err, Macku.ErrResponse):
+		code = codes.Internal
+	}
+	return status.Error(code, err.Error())
+}
+
+// fromStatus converts a gRPC status error back into a library error.
+func fromStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	st := status.Convert(err)
+	switch st.Code() {
+	case codes.Unavailable, codes.Canceled:
+		return Macku.NewConnectionError(st.Message())
+	case codes.DeadlineExceeded:
+		return Macku.NewTimeoutError(st.Message())
+	case codes.InvalidArgument:
+		return Macku.NewCommandError(st.Message())
+	case codes.Internal:
+		return Macku.NewResponseError(st.Message())
+	default:
+		return err
+	}
+}
@@ -0,0 +1,377 @@
+// Package httpapi exposes a MakcuController over a small REST API with a
+// WebSocket event stream, for tooling that does not speak Go or gRPC.
+//
+//	POST /move     {"dx":10,"dy":0,"segments":0}
+//	POST /click    {"button":"left","count":1}
+//	POST /press    {"button":"left"}
+//	POST /release  {"button":"left"}
+//	POST /scroll   {"delta":-3}
+//	POST /lock     {"target":"x"}
+//	POST /unlock   {"target":"x"}
+//	GET  /locks    -> {"LEFT":false,...,"X":true,"Y":false}
+//	GET  /buttons  -> {"states":{"left":true,...},"mask":1}
+//	GET  /device   -> {"port":"COM3",...,"firmware":"km.MAKCU"}
+//	GET  /events   WebSocket: {"type":"button","button":"left","pressed":true,"mask":1,"time":"..."}
+//	GET  /metrics  Prometheus text format, when the transport keeps Macku.Metrics
+//
+// POST bodies must be application/json. Errors are returned as
+// {"error":"...","code":"command"} with a status derived from the library
+// error class.
+package httpapi
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	Macku "github.com/Auchrio/Makcu-go-lib/v3"
+	"github.com/Auchrio/Makcu-go-lib/v3/internal/events"
+	"golang.org/x/net/websocket"
+)
+
+// DefaultAddr is the listen address used when none is given: loopback only.
+const DefaultAddr = "127.0.0.1:7711"
+
+// eventBuffer is the number of events queued per WebSocket before dropping.
+const eventBuffer = 64
+
+// Options configure a Server.
+type Options struct {
+	// Token enables bearer-token auth when non-empty. Requests must send
+	// "Authorization: Bearer <token>"; WebSocket clients that cannot set
+	// headers may pass ?access_token=<token> instead.
+	Token string
+}
+
+// Server is an http.Handler serving one controller.
+type Server struct {
+	c    *Macku.MakcuController
+	opts Options
+	hub  *events.Hub
+	mux  *http.ServeMux
+	done chan struct{}
+	once sync.Once
+}
+
+// NewServer creates a Server for an already-connected controller.
+func NewServer(c *Macku.MakcuController, opts Options) *Server {
+	s := &Server{
+		c:    c,
+		opts: opts,
+		hub:  events.Attach(c),
+		mux:  http.NewServeMux(),
+		done: make(chan struct{}),
+	}
+	s.mux.HandleFunc("POST /move", s.handleMove)
+	s.mux.HandleFunc("POST /click", s.handleButton)
+	s.mux.HandleFunc("POST /press", s.handleButton)
+	s.mux.HandleFunc("POST /release", s.handleButton)
+	s.mux.HandleFunc("POST /scroll", s.handleScroll)
+	s.mux.HandleFunc("POST /lock", s.handleLock)
+	s.mux.HandleFunc("POST /unlock", s.handleLock)
+	s.mux.HandleFunc("GET /locks", s.handleLocks)
+	s.mux.HandleFunc("GET /buttons", s.handleButtons)
+	s.mux.HandleFunc("GET /device", s.handleDevice)
+	s.mux.Handle("GET /events", websocket.Server{Handshake: s.checkOrigin, Handler: s.handleEvents})
+	if src, ok := c.Transport.(Macku.MetricsSource); ok {
+		s.mux.Handle("GET /metrics", Macku.MetricsHandler(src))
+	}
+	return s
+}
+
+// Close ends open WebSocket streams and releases the controller's button callback.
+func (s *Server) Close() {
+	s.once.Do(func() {
+		close(s.done)
+		s.hub.Detach()
+	})
+}
+
+// ListenAndServe serves on addr, or DefaultAddr when addr is empty, until
+// the listener fails.
+func (s *Server) ListenAndServe(addr string) error {
+	if addr == "" {
+		addr = DefaultAddr
+	}
+	srv := &http.Server{Addr: addr, Handler: s, ReadHeaderTimeout: 10 * time.Second}
+	return srv.ListenAndServe()
+}
+
+// ServeHTTP authenticates the request and dispatches it.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.opts.Token != "" && !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="makcu"`)
+		writeError(w, http.StatusUnauthorized, "unauthorized", "missing or invalid bearer token")
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	got := ""
+	if h := r.Header.Get("Authorization"); strings.HasPrefix(h, "Bearer ") {
+		got = strings.TrimPrefix(h, "Bearer ")
+	} else if r.URL.Path == "/events" {
+		got = r.URL.Query().Get("access_token")
+	}
+	return subtle.ConstantTimeCompare([]byte(got), []byte(s.opts.Token)) == 1
+}
+
+// checkOrigin rejects cross-site WebSocket handshakes so a web page cannot
+// read events from a local server. Clients that send no Origin are allowed.
+func (s *Server) checkOrigin(cfg *websocket.Config, r *http.Request) error {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host != r.Host {
+		return fmt.Errorf("cross-origin WebSocket from %q rejected", origin)
+	}
+	cfg.Origin = u
+	return nil
+}
+
+// --- handlers ---
+
+type moveRequest struct {
+	DX       int `json:"dx"`
+	DY       int `json:"dy"`
+	Segments int `json:"segments"`
+}
+
+type buttonRequest struct {
+	Button string `json:"button"`
+	Count  int    `json:"count"`
+}
+
+type scrollRequest struct {
+	Delta int `json:"delta"`
+}
+
+type lockRequest struct {
+	Target string `json:"target"`
+}
+
+func (s *Server) handleMove(w http.ResponseWriter, r *http.Request) {
+	var req moveRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	if req.Segments > 0 {
+		respond(w, nil, s.c.MoveSmooth(req.DX, req.DY, req.Segments))
+		return
+	}
+	respond(w, nil, s.c.Move(req.DX, req.DY))
+}
+
+func (s *Server) handleButton(w http.ResponseWriter, r *http.Request) {
+	var req buttonRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	b, err := Macku.ParseMouseButton(req.Button)
+	if err != nil {
+		respond(w, nil, err)
+		return
+	}
+	switch r.URL.Path {
+	case "/press":
+		err = s.c.Press(b)
+	case "/release":
+		err = s.c.Release(b)
+	default:
+		count := min(max(req.Count, 1), Macku.MaxClickCount)
+		for i := 0; i < count && err == nil; i++ {
+			if err = r.Context().Err(); err == nil {
+				err = s.c.Click(b)
+			}
+		}
+	}
+	respond(w, nil, err)
+}
+
+func (s *Server) handleScroll(w http.ResponseWriter, r *http.Request) {
+	var req scrollRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	respond(w, nil, s.c.Scroll(req.Delta))
+}
+
+func (s *Server) handleLock(w http.ResponseWriter, r *http.Request) {
+	var req lockRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	t, err := Macku.ParseLockTarget(req.Target)
+	if err != nil {
+		respond(w, nil, err)
+		return
+	}
+	if r.URL.Path == "/lock" {
+		respond(w, nil, s.c.Lock(t))
+	} else {
+		respond(w, nil, s.c.Unlock(t))
+	}
+}
+
+func (s *Server) handleLocks(w http.ResponseWriter, r *http.Request) {
+	states, err := s.c.GetAllLockStates()
+	respond(w, states, err)
+}
+
+func (s *Server) handleButtons(w http.ResponseWriter, r *http.Request) {
+	states, err := s.c.GetButtonStates()
+	if err != nil {
+		respond(w, nil, err)
+		return
+	}
+	mask, err := s.c.GetButtonMask()
+	respond(w, struct {
+		States map[string]bool `json:"states"`
+		Mask   int             `json:"mask"`
+	}{states, mask}, err)
+}
+
+func (s *Server) handleDevice(w http.ResponseWriter, r *http.Request) {
+	info, err := s.c.GetDeviceInfo()
+	if err != nil {
+		respond(w, nil, err)
+		return
+	}
+	version, err := s.c.GetFirmwareVersion()
+	respond(w, struct {
+		Macku.DeviceInfo
+		Firmware string `json:"firmware"`
+	}{info, version}, err)
+}
+
+// Event is one message on the /events WebSocket.
+type Event struct {
+	Type        string    `json:"type"` // "button" or "connection"
+	Button      string    `json:"button,omitempty"`
+	Pressed     bool      `json:"pressed,omitempty"`
+	Intercepted bool      `json:"intercepted,omitempty"` // kept from the host by a lock and catch
+	Connected   bool      `json:"connected,omitempty"`
+	Mask        int       `json:"mask"`
+	Time        time.Time `json:"time"`
+}
+
+// handleEvents streams button and connection events until the client goes
+// away or the server is closed. The current connection state is sent first.
+func (s *Server) handleEvents(ws *websocket.Conn) {
+	defer ws.Close()
+	sub := s.hub.Subscribe(eventBuffer)
+	defer sub.Close()
+
+	// Detect the client closing its side; incoming messages are ignored.
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		var discard []byte
+		for websocket.Message.Receive(ws, &discard) == nil {
+		}
+	}()
+
+	first := Event{Type: "connection", Connected: s.c.IsConnected(), Mask: s.hub.Mask(), Time: time.Now()}
+	if websocket.JSON.Send(ws, first) != nil {
+		return
+	}
+	for {
+		select {
+		case <-gone:
+			return
+		case <-s.done:
+			return
+		case ev, ok := <-sub.C():
+			if !ok {
+				return
+			}
+			msg := Event{Type: "button", Button: ev.Button.String(), Pressed: ev.Pressed, Intercepted: ev.Intercepted,
+				Mask: ev.Mask, Time: ev.Time}
+			if ev.Kind == events.Connection {
+				msg = Event{Type: "connection", Connected: ev.Connected, Mask: ev.Mask, Time: ev.Time}
+			}
+			if websocket.JSON.Send(ws, msg) != nil {
+				return
+			}
+		}
+	}
+}
+
+// --- encoding ---
+
+// decode reads a JSON request body, writing a 400/415 on failure.
+func decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if ct := r.Header.Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+		writeError(w, http.StatusUnsupportedMediaType, "invalid_request", "Content-Type must be application/json")
+		return false
+	}
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", "invalid JSON body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// respond writes v (or {"ok":true} when v is nil) or the error err.
+func respond(w http.ResponseWriter, v interface{}, err error) {
+	if err != nil {
+		status, code := errorStatus(err)
+		writeError(w, status, code, err.Error())
+		return
+	}
+	if v == nil {
+		v = map[string]bool{"ok": true}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// errorStatus maps a library error onto an HTTP status and error code.
+func errorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, Macku.ErrCommand):
+		return http.StatusBadRequest, "command"
+	case errors.Is(err, Macku.ErrConnection):
+		return http.StatusServiceUnavailable, "connection"
+	case errors.Is(err, Macku.ErrTimeout):
+		return http.StatusGatewayTimeout, "timeout"
+	case errors.Is(err, Macku.ErrResponse):
+		return http.StatusBadGateway, "response"
+	default:
+		return http.StatusInternalServerError, "internal"
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, code, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(struct {
+		Error string `json:"error"`
+		Code  string `json:"code"`
+	}{msg, code})
+}
+
+// IsLoopback reports whether addr (host:port) binds only to a loopback interface.
+func IsLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,155 @@
+package Macku
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ButtonEvent is a change of a physical button, as reported by the device.
+type ButtonEvent struct {
+	Button  MouseButton
+	Pressed bool
+	// Intercepted is set while the button is locked and caught: the host
+	// does not see the change, only the library does. It is taken from the
+	// library's lock and catch cache (see Mouse.Intercepting), not queried
+	// from the device, so it misses state changed behind the library's back.
+	Intercepted bool
+}
+
+// buttonHandlers holds the callbacks button reports are dispatched to.
+type buttonHandlers struct {
+	mu           sync.Mutex
+	button       func(MouseButton, bool)
+	event        func(ButtonEvent)
+	interceptors map[MouseButton]func(bool)
+}
+
+// SetButtonEventCallback sets a callback invoked with each button change,
+// tagged with whether it was intercepted. It is independent of
+// SetButtonCallback; pass nil to remove it, which also works while
+// disconnected.
+func (c *MakcuController) SetButtonEventCallback(cb func(ButtonEvent)) error {
+	if cb != nil {
+		if err := c.checkConnection(); err != nil {
+			return err
+		}
+	}
+	c.handlers.mu.Lock()
+	c.handlers.event = cb
+	c.handlers.mu.Unlock()
+	c.installButtonHandler()
+	return nil
+}
+
+// installButtonHandler subscribes to the transport's button reports while
+// any callback or interceptor is registered.
+func (c *MakcuController) installButtonHandler() {
+	c.handlers.mu.Lock()
+	active := c.handlers.button != nil || c.handlers.event != nil || len(c.handlers.interceptors) > 0
+	c.handlers.mu.Unlock()
+	if active {
+		c.Transport.SetButtonCallback(c.dispatchButton)
+	} else {
+		c.Transport.SetButtonCallback(nil)
+	}
+}
+
+// dispatchButton delivers one button change to the registered callbacks,
+// and to the button's interceptor if the change was intercepted.
+func (c *MakcuController) dispatchButton(button MouseButton, pressed bool) {
+	c.handlers.mu.Lock()
+	buttonCB, eventCB, intercept := c.handlers.button, c.handlers.event, c.handlers.interceptors[button]
+	c.handlers.mu.Unlock()
+
+	ev := ButtonEvent{Button: button, Pressed: pressed, Intercepted: c.Mouse.Intercepting(button)}
+	if buttonCB != nil {
+		buttonCB(button, pressed)
+	}
+	if eventCB != nil {
+		eventCB(ev)
+	}
+	if ev.Intercepted && intercept != nil {
+		intercept(pressed)
+	}
+}
+
+// Intercept reroutes button to fn: the button is locked, so the host no
+// longer sees it, and caught, so fn is called with each physical press and
+// release instead. Events need button monitoring (Config.SendInit or
+// EnableButtonMonitoring). The interception is applied again after the
+// device reconnects; a failure to do so goes to OnReconnectError.
+//
+// stop ends the interception and puts the lock and catch state back as
+// they were before.
+func (c *MakcuController) Intercept(button MouseButton, fn func(pressed bool)) (stop func() error, err error) {
+	if err := c.checkConnection(); err != nil {
+		return nil, err
+	}
+	name, _, err := c.Mouse.catchTarget(button)
+	if err != nil {
+		return nil, err
+	}
+	wasLocked, err := c.Mouse.lockState(name)
+	if err != nil {
+		return nil, err
+	}
+	wasCaught := c.Mouse.IsCaught(button)
+
+	c.handlers.mu.Lock()
+	if _, busy := c.handlers.interceptors[button]; busy {
+		c.handlers.mu.Unlock()
+		return nil, NewCommandError(fmt.Sprintf("button already intercepted: %v", button))
+	}
+	if c.handlers.interceptors == nil {
+		c.handlers.interceptors = make(map[MouseButton]func(bool))
+	}
+	c.handlers.interceptors[button] = fn
+	c.handlers.mu.Unlock()
+	c.installButtonHandler()
+
+	restore := func() error {
+		c.handlers.mu.Lock()
+		delete(c.handlers.interceptors, button)
+		c.handlers.mu.Unlock()
+		c.installButtonHandler()
+		return errors.Join(c.Mouse.SetCatch(button, wasCaught), c.Mouse.setLock(name, wasLocked))
+	}
+	if err := c.applyIntercept(button, name); err != nil {
+		return nil, errors.Join(err, restore())
+	}
+
+	var mu sync.Mutex // held while the interception is applied again
+	stopped := false
+	unhook := c.OnReconnect(func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		if stopped {
+			return nil
+		}
+		if err := c.applyIntercept(button, name); err != nil {
+			return fmt.Errorf("re-apply %v interception: %w", button, err)
+		}
+		return nil
+	})
+	var once sync.Once
+	var stopErr error
+	return func() error {
+		once.Do(func() {
+			mu.Lock()
+			stopped = true
+			mu.Unlock()
+			unhook()
+			stopErr = restore()
+		})
+		return stopErr
+	}, nil
+}
+
+// applyIntercept locks and catches button, lock target name.
+func (c *MakcuController) applyIntercept(button MouseButton, name string) error {
+	if err := c.Mouse.setLock(name, true); err != nil {
+		return err
+	}
+	return c.Mouse.SetCatch(button, true)
+}
@@ -0,0 +1,622 @@
+// Package cli implements the makcu command-line tool.
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	Macku "github.com/Auchrio/Makcu-go-lib/v3"
+	"github.com/Auchrio/Makcu-go-lib/v3/daemon"
+	"github.com/Auchrio/Makcu-go-lib/v3/relay"
+)
+
+// Exit codes returned by Run.
+const (
+	ExitOK         = 0
+	ExitError      = 1 // Unclassified failure
+	ExitUsage      = 2 // Bad flags or arguments
+	ExitConnection = 3 // ErrConnection
+	ExitTimeout    = 4 // ErrTimeout
+	ExitCommand    = 5 // ErrCommand
+	ExitResponse   = 6 // ErrResponse
+)
+
+// ExitCode maps an error onto the exit code for its Macku error class.
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, errUsage):
+		return ExitUsage
+	case errors.Is(err, Macku.ErrConnection):
+		return ExitConnection
+	case errors.Is(err, Macku.ErrTimeout):
+		return ExitTimeout
+	case errors.Is(err, Macku.ErrCommand):
+		return ExitCommand
+	case errors.Is(err, Macku.ErrResponse):
+		return ExitResponse
+	default:
+		return ExitError
+	}
+}
+
+var errUsage = errors.New("usage error")
+
+func usageError(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", errUsage, fmt.Sprintf(format, args...))
+}
+
+// App holds the I/O streams and device hooks used by the CLI. Tests replace
+// Connect and ListPorts to run without hardware.
+type App struct {
+	Stdout    io.Writer
+	Stderr    io.Writer
+	Stdin     io.Reader
+	Connect   func(cfg Macku.Config) (*Macku.MakcuController, error)
+	ListPorts func() ([]Macku.PortInfo, error)
+
+	json   bool
+	cfg    Macku.Config
+	socket string               // makcud socket; empty opens the device directly
+	remote string               // relay address; empty opens the device directly
+	tap    *Macku.CaptureWriter // set by -capture
+	mu     sync.Mutex           // serialises output from event callbacks
+}
+
+// command is one makcu subcommand.
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(a *App, ctx context.Context, args []string) error
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"list", "list", "enumerate candidate serial ports", (*App).cmdList},
+		{"info", "info", "show device info and firmware version", (*App).cmdInfo},
+		{"move", "move <dx> <dy> [segments]", "relative move (smooth when segments > 0)", (*App).cmdMove},
+		{"click", "click [-count n] [button]", "click a button (default left)", (*App).cmdClick},
+		{"scroll", "scroll <delta>", "scroll the wheel", (*App).cmdScroll},
+		{"lock", "lock <target>", "lock a button or axis", (*App).cmdLock},
+		{"unlock", "unlock <target>", "unlock a button or axis", (*App).cmdUnlock},
+		{"locks", "locks", "show the lock state of every target", (*App).cmdLocks},
+		{"monitor", "monitor [-duration d]", "stream button events", (*App).cmdMonitor},
+		{"raw", "raw [-response] [-timeout d] <command>", "send a raw km.* command", (*App).cmdRaw},
+		{"console", "console", "interactive km.* console", (*App).cmdConsole},
+		{"relay", "relay [-listen addr]", "share the device's byte stream over TCP", (*App).cmdRelay},
+		{"decode", "decode [-text] <capture>", "annotate a -capture file (or convert it to text)", (*App).cmdDecode},
+	}
+}
+
+// Run parses args (without the program name), executes the subcommand and
+// returns the process exit code.
+func (a *App) Run(ctx context.Context, args []string) int {
+	err := a.run(ctx, args)
+	if err != nil {
+		a.printError(err)
+	}
+	return ExitCode(err)
+}
+
+func (a *App) run(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("makcu", flag.ContinueOnError)
+	fs.SetOutput(a.Stderr)
+	port := fs.String("port", "", "serial port to use (skips auto-detection)")
+	debug := fs.Bool("debug", false, "enable debug logging")
+	fs.BoolVar(&a.json, "json", false, "write JSON output")
+	fs.StringVar(&a.socket, "daemon", "", "talk to a makcud daemon on this socket instead of the device")
+	fs.StringVar(&a.remote, "remote", "", "talk to a device behind a makcu relay at host:port")
+	capture := fs.String("capture", "", "record wire traffic to this file (text format if it ends in .txt)")
+	fs.Usage = a.usage(fs)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return usageError("%v", err)
+	}
+
+	a.cfg = Macku.DefaultConfig()
+	a.cfg.Debug = *debug
+	if *port != "" {
+		a.cfg.FallbackCOMPort = *port
+		a.cfg.OverridePort = true
+	}
+	if *capture != "" {
+		f, err := os.Create(*capture)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		format := Macku.CaptureBinary
+		if strings.HasSuffix(*capture, ".txt") {
+			format = Macku.CaptureText
+		}
+		a.tap = Macku.NewCaptureWriter(f, format)
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return usageError("no command given")
+	}
+	name := fs.Arg(0)
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd.run(a, ctx, fs.Args()[1:])
+		}
+	}
+	return usageError("unknown command %q", name)
+}
+
+func (a *App) usage(fs *flag.FlagSet) func() {
+	return func() {
+		fmt.Fprintf(a.Stderr, "Usage: makcu [flags] <command> [args]\n\nCommands:\n")
+		for _, cmd := range commands {
+			fmt.Fprintf(a.Stderr, "  %-40s %s\n", cmd.usage, cmd.summary)
+		}
+		fmt.Fprintf(a.Stderr, "\nFlags:\n")
+		fs.PrintDefaults()
+	}
+}
+
+// connect opens the device, using the App's Connect hook if set, the
+// makcud daemon when -daemon is given, or a relay when -remote is given.
+// Direct and relay connections are recorded when -capture is given.
+func (a *App) connect() (*Macku.MakcuController, error) {
+	if a.Connect != nil {
+		return a.Connect(a.cfg)
+	}
+	var c *Macku.MakcuController
+	switch {
+	case a.socket != "":
+		if a.tap != nil {
+			return nil, usageError("-capture needs direct access to the device, not -daemon")
+		}
+		c = Macku.NewControllerWithTransport(daemon.NewClient(a.socket))
+	case a.remote != "":
+		t := Macku.NewNetTransport(a.remote, a.cfg.Debug, a.cfg.SendInit, a.cfg.AutoReconnect)
+		t.SetTap(a.tap)
+		c = Macku.NewControllerWithTransport(t)
+	default:
+		c = Macku.NewController(a.cfg)
+		if st := c.SerialTransport(); st != nil {
+			st.SetTap(a.tap)
+		}
+	}
+	if err := c.Connect(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// withController connects, runs fn, and disconnects.
+func (a *App) withController(fn func(c *Macku.MakcuController) error) error {
+	c, err := a.connect()
+	if err != nil {
+		return err
+	}
+	defer c.Disconnect()
+	return fn(c)
+}
+
+// output writes v as JSON in -json mode, or text otherwise.
+func (a *App) output(v interface{}, text string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.json {
+		json.NewEncoder(a.Stdout).Encode(v)
+		return
+	}
+	if text != "" {
+		fmt.Fprintln(a.Stdout, text)
+	}
+}
+
+func (a *App) printError(err error) {
+	if a.json {
+		json.NewEncoder(a.Stderr).Encode(map[string]interface{}{
+			"error":     err.Error(),
+			"exit_code": ExitCode(err),
+		})
+		return
+	}
+	fmt.Fprintf(a.Stderr, "makcu: %v\n", err)
+}
+
+func (a *App) ok() {
+	a.output(map[string]bool{"ok": true}, "")
+}
+
+// --- argument helpers ---
+
+func intArg(name, s string) (int, error) {
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, usageError("%s must be an integer, got %q", name, s)
+	}
+	return v, nil
+}
+
+func wantArgs(args []string, minN, maxN int, usage string) error {
+	if len(args) < minN || len(args) > maxN {
+		return usageError("usage: makcu %s", usage)
+	}
+	return nil
+}
+
+func subFlags(a *App, name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(a.Stderr)
+	return fs
+}
+
+func parseSub(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return usageError("%v", err)
+	}
+	return nil
+}
+
+// --- commands ---
+
+func (a *App) cmdList(_ context.Context, args []string) error {
+	if err := wantArgs(args, 0, 0, "list"); err != nil {
+		return err
+	}
+	list := a.ListPorts
+	if list == nil {
+		list = Macku.ListPorts
+	}
+	ports, err := list()
+	if err != nil {
+		return Macku.NewConnectionError(err.Error())
+	}
+	if a.json {
+		a.output(ports, "")
+		return nil
+	}
+	for _, p := range ports {
+		marker := " "
+		if p.IsMakcu {
+			marker = "*"
+		}
+		line := fmt.Sprintf("%s %s", marker, p.Name)
+		if p.IsUSB {
+			line += fmt.Sprintf("  %s:%s", p.VID, p.PID)
+		}
+		if p.Product != "" {
+			line += "  " + p.Product
+		}
+		a.output(nil, line)
+	}
+	return nil
+}
+
+func (a *App) cmdInfo(_ context.Context, args []string) error {
+	if err := wantArgs(args, 0, 0, "info"); err != nil {
+		return err
+	}
+	return a.withController(func(c *Macku.MakcuController) error {
+		info, err := c.GetDeviceInfo()
+		if err != nil {
+			return err
+		}
+		version, err := c.GetFirmwareVersion()
+		if err != nil {
+			return err
+		}
+		a.output(struct {
+			Macku.DeviceInfo
+			Firmware string `json:"firmware"`
+		}{info, version}, fmt.Sprintf("Port:        %s\nDescription: %s\nVID:PID:     %s:%s\nConnected:   %v\nFirmware:    %s",
+			info.Port, info.Description, info.VID, info.PID, info.IsConnected, version))
+		return nil
+	})
+}
+
+func (a *App) cmdMove(_ context.Context, args []string) error {
+	const usage = "move <dx> <dy> [segments]"
+	if err := wantArgs(args, 2, 3, usage); err != nil {
+		return err
+	}
+	dx, err := intArg("dx", args[0])
+	if err != nil {
+		return err
+	}
+	dy, err := intArg("dy", args[1])
+	if err != nil {
+		return err
+	}
+	segments := 0
+	if len(args) == 3 {
+		if segments, err = intArg("segments", args[2]); err != nil {
+			return err
+		}
+	}
+	return a.withController(func(c *Macku.MakcuController) error {
+		if segments > 0 {
+			err = c.MoveSmooth(dx, dy, segments)
+		} else {
+			err = c.Move(dx, dy)
+		}
+		if err == nil {
+			a.ok()
+		}
+		return err
+	})
+}
+
+func (a *App) cmdClick(ctx context.Context, args []string) error {
+	fs := subFlags(a, "click")
+	count := fs.Int("count", 1, "number of clicks")
+	if err := parseSub(fs, args); err != nil {
+		return err
+	}
+	if err := wantArgs(fs.Args(), 0, 1, "click [-count n] [button]"); err != nil {
+		return err
+	}
+	if *count < 0 || *count > Macku.MaxClickCount {
+		return usageError("-count must be 0..%d, got %d", Macku.MaxClickCount, *count)
+	}
+	button := Macku.MouseButtonLeft
+	if fs.NArg() == 1 {
+		b, err := Macku.ParseMouseButton(fs.Arg(0))
+		if err != nil {
+			return usageError("%v", err)
+		}
+		button = b
+	}
+	return a.withController(func(c *Macku.MakcuController) error {
+		for i := 0; i < *count; i++ {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := c.Click(button); err != nil {
+				return err
+			}
+		}
+		a.ok()
+		return nil
+	})
+}
+
+func (a *App) cmdScroll(_ context.Context, args []string) error {
+	if err := wantArgs(args, 1, 1, "scroll <delta>"); err != nil {
+		return err
+	}
+	delta, err := intArg("delta", args[0])
+	if err != nil {
+		return err
+	}
+	return a.withController(func(c *Macku.MakcuController) error {
+		if err := c.Scroll(delta); err != nil {
+			return err
+		}
+		a.ok()
+		return nil
+	})
+}
+
+func (a *App) cmdLock(_ context.Context, args []string) error {
+	return a.setLock(args, true)
+}
+
+func (a *App) cmdUnlock(_ context.Context, args []string) error {
+	return a.setLock(args, false)
+}
+
+func (a *App) setLock(args []string, lock bool) error {
+	verb := map[bool]string{true: "lock", false: "unlock"}[lock]
+	if err := wantArgs(args, 1, 1, verb+" <target>"); err != nil {
+		return err
+	}
+	target, err := Macku.ParseLockTarget(args[0])
+	if err != nil {
+		return usageError("%v", err)
+	}
+	return a.withController(func(c *Macku.MakcuController) error {
+		if lock {
+			err = c.Lock(target)
+		} else {
+			err = c.Unlock(target)
+		}
+		if err == nil {
+			a.ok()
+		}
+		return err
+	})
+}
+
+func (a *App) cmdLocks(_ context.Context, args []string) error {
+	if err := wantArgs(args, 0, 0, "locks"); err != nil {
+		return err
+	}
+	return a.withController(func(c *Macku.MakcuController) error {
+		states, err := c.GetAllLockStates()
+		if err != nil {
+			return err
+		}
+		names := make([]string, 0, len(states))
+		for name := range states {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		lines := make([]string, len(names))
+		for i, name := range names {
+			lines[i] = fmt.Sprintf("%-7s %v", strings.ToLower(name), states[name])
+		}
+		a.output(states, strings.Join(lines, "\n"))
+		return nil
+	})
+}
+
+// buttonEvent is the JSON form of a monitored button change.
+type buttonEvent struct {
+	Time    time.Time `json:"time"`
+	Button  string    `json:"button"`
+	Pressed bool      `json:"pressed"`
+}
+
+func (a *App) cmdMonitor(ctx context.Context, args []string) error {
+	fs := subFlags(a, "monitor")
+	duration := fs.Duration("duration", 0, "stop after this long (0 = until interrupted)")
+	if err := parseSub(fs, args); err != nil {
+		return err
+	}
+	if err := wantArgs(fs.Args(), 0, 0, "monitor [-duration d]"); err != nil {
+		return err
+	}
+	if *duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *duration)
+		defer cancel()
+	}
+	return a.withController(func(c *Macku.MakcuController) error {
+		err := c.SetButtonCallback(func(b Macku.MouseButton, pressed bool) {
+			ev := buttonEvent{Time: time.Now(), Button: b.String(), Pressed: pressed}
+			state := map[bool]string{true: "pressed", false: "released"}[pressed]
+			a.output(ev, fmt.Sprintf("%s %-6s %s", ev.Time.Format("15:04:05.000"), ev.Button, state))
+		})
+		if err != nil {
+			return err
+		}
+		defer c.SetButtonCallback(nil)
+		if err := c.EnableButtonMonitoring(true); err != nil {
+			return err
+		}
+		<-ctx.Done()
+		return nil
+	})
+}
+
+func (a *App) cmdRelay(ctx context.Context, args []string) error {
+	fs := subFlags(a, "relay")
+	listen := fs.String("listen", fmt.Sprintf(":%d", Macku.DefaultRelayPort), "TCP address to listen on")
+	if err := parseSub(fs, args); err != nil {
+		return err
+	}
+	if err := wantArgs(fs.Args(), 0, 0, "relay [-listen addr]"); err != nil {
+		return err
+	}
+	port := a.cfg.FallbackCOMPort
+	if !a.cfg.OverridePort {
+		var err error
+		port, err = Macku.NewSerialTransport(port, a.cfg.Debug, false, false, false).FindCOMPort()
+		if err != nil {
+			return err
+		}
+		if port == "" {
+			return Macku.NewConnectionError("Makcu device not found")
+		}
+	}
+
+	l, err := net.Listen("tcp", *listen)
+	if err != nil {
+		return Macku.NewConnectionError(err.Error())
+	}
+	srv := relay.NewServer(func() (Macku.Port, error) { return Macku.OpenSerialPort(port) })
+	srv.Logf = func(format string, args ...interface{}) {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		fmt.Fprintf(a.Stderr, format+"\n", args...)
+	}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	a.output(map[string]string{"port": port, "listen": l.Addr().String()},
+		fmt.Sprintf("Relaying %s on %s", port, l.Addr()))
+	return srv.Serve(l)
+}
+
+// decodedEvent is the JSON form of a Macku.DecodedEvent.
+type decodedEvent struct {
+	OffsetNs int64  `json:"offset_ns"`
+	Dir      string `json:"dir"`
+	Kind     string `json:"kind"`
+	Text     string `json:"text,omitempty"`
+	Tag      *int   `json:"tag,omitempty"`
+	Command  string `json:"command,omitempty"`
+	Mask     *int   `json:"mask,omitempty"`
+}
+
+func (a *App) cmdDecode(_ context.Context, args []string) error {
+	fs := subFlags(a, "decode")
+	text := fs.Bool("text", false, "print the capture in text format instead of annotating it")
+	if err := parseSub(fs, args); err != nil {
+		return err
+	}
+	if err := wantArgs(fs.Args(), 1, 1, "decode [-text] <capture>"); err != nil {
+		return err
+	}
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	capture, err := Macku.ReadCapture(f)
+	if err != nil {
+		return err
+	}
+	if *text {
+		return capture.WriteTo(a.Stdout, Macku.CaptureText)
+	}
+	for _, ev := range capture.Decode() {
+		out := decodedEvent{
+			OffsetNs: int64(ev.Offset),
+			Dir:      map[Macku.CaptureDir]string{Macku.CaptureWrite: "write", Macku.CaptureRead: "read"}[ev.Dir],
+			Kind:     ev.Kind.String(),
+			Text:     ev.Text,
+			Command:  ev.Command,
+		}
+		if ev.Tag >= 0 {
+			tag := ev.Tag
+			out.Tag = &tag
+		}
+		if ev.Kind == Macku.DecodedButtons {
+			mask := ev.Mask
+			out.Mask = &mask
+		}
+		a.output(out, ev.String())
+	}
+	return nil
+}
+
+func (a *App) cmdRaw(_ context.Context, args []string) error {
+	fs := subFlags(a, "raw")
+	expect := fs.Bool("response", false, "wait for and print the device response")
+	timeout := fs.Duration("timeout", Macku.DefaultTimeout, "response timeout")
+	if err := parseSub(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return usageError("usage: makcu raw [-response] [-timeout d] <command>")
+	}
+	cmd := strings.Join(fs.Args(), " ")
+	return a.withController(func(c *Macku.MakcuController) error {
+		resp, err := c.Transport.SendCommand(cmd, *expect, *timeout)
+		if err != nil {
+			return err
+		}
+		if *expect {
+			a.output(map[string]string{"command": cmd, "response": resp}, resp)
+		} else {
+			a.ok()
+		}
+		return nil
+	})
+}
@@ -0,0 +1,326 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	Macku "github.com/Auchrio/Makcu-go-lib/v3"
+	"golang.org/x/term"
+)
+
+const consolePrompt = "makcu> "
+
+// kmCommands lists the known km.* commands and the argument completions
+// offered after the opening parenthesis.
+var kmCommands = map[string][]string{
+	"km.move(":      nil,
+	"km.wheel(":     nil,
+	"km.left(":      {"0)", "1)"},
+	"km.right(":     {"0)", "1)"},
+	"km.middle(":    {"0)", "1)"},
+	"km.ms1(":       {"0)", "1)"},
+	"km.ms2(":       {"0)", "1)"},
+	"km.lock_ml(":   {")", "0)", "1)"},
+	"km.lock_mr(":   {")", "0)", "1)"},
+	"km.lock_mm(":   {")", "0)", "1)"},
+	"km.lock_ms1(":  {")", "0)", "1)"},
+	"km.lock_ms2(":  {")", "0)", "1)"},
+	"km.lock_mx(":   {")", "0)", "1)"},
+	"km.lock_my(":   {")", "0)", "1)"},
+	"km.catch_ml(":  {")", "0)", "1)"},
+	"km.catch_mr(":  {")", "0)", "1)"},
+	"km.catch_mm(":  {")", "0)", "1)"},
+	"km.catch_ms1(": {")", "0)", "1)"},
+	"km.catch_ms2(": {")", "0)", "1)"},
+	"km.buttons(":   {"0)", "1)"},
+	"km.serial(":    {"0)", "'"},
+	"km.version(":   {")"},
+}
+
+// metaCommands lists console meta-commands and their argument completions.
+var metaCommands = map[string][]string{
+	":help":    nil,
+	":quit":    nil,
+	":exit":    nil,
+	":history": nil,
+	":trace":   {"on", "off"},
+	":events":  {"on", "off"},
+	":timeout": {"50ms", "100ms", "500ms", "1s"},
+}
+
+// Complete is the console's tab-completion callback (the signature of
+// term.Terminal.AutoCompleteCallback). It completes km.* command names,
+// their arguments and meta-commands to the longest unambiguous prefix.
+func Complete(line string, pos int, key rune) (string, int, bool) {
+	if key != '\t' {
+		return "", 0, false
+	}
+	prefix, rest := line[:pos], line[pos:]
+
+	var candidates []string
+	if strings.HasPrefix(prefix, ":") {
+		candidates = completeFrom(metaCommands, prefix, " ")
+	} else {
+		candidates = completeFrom(kmCommands, prefix, "")
+	}
+	if len(candidates) == 0 {
+		return "", 0, false
+	}
+
+	completed := commonPrefix(candidates)
+	if len(completed) <= len(prefix) {
+		return "", 0, false
+	}
+	return completed + rest, len(completed), true
+}
+
+// completeFrom returns the full-line candidates for prefix: command names
+// when the prefix is still inside a name, otherwise that command's arguments.
+// Names that take arguments complete through sep so the next Tab offers them.
+func completeFrom(table map[string][]string, prefix, sep string) []string {
+	var out []string
+	for name, args := range table {
+		head := name + sep
+		switch {
+		case strings.HasPrefix(head, prefix) && len(prefix) < len(head):
+			if len(args) > 0 {
+				out = append(out, head)
+			} else {
+				out = append(out, name)
+			}
+		case strings.HasPrefix(prefix, head):
+			for _, arg := range args {
+				if strings.HasPrefix(head+arg, prefix) {
+					out = append(out, head+arg)
+				}
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func commonPrefix(words []string) string {
+	p := words[0]
+	for _, w := range words[1:] {
+		for !strings.HasPrefix(w, p) {
+			p = p[:len(p)-1]
+		}
+	}
+	return p
+}
+
+// console is an interactive km.* session over one controller.
+type console struct {
+	app     *App
+	c       *Macku.MakcuController
+	out     io.Writer
+	read    func() (string, error)
+	timeout time.Duration
+	trace   bool
+	history []string
+}
+
+func (a *App) cmdConsole(ctx context.Context, args []string) error {
+	if err := wantArgs(args, 0, 0, "console"); err != nil {
+		return err
+	}
+	return a.withController(func(c *Macku.MakcuController) error {
+		con := &console{app: a, c: c, timeout: Macku.DefaultTimeout}
+		restore, err := con.setupIO()
+		if err != nil {
+			return err
+		}
+		defer restore()
+		return con.run(ctx)
+	})
+}
+
+// setupIO uses a line-editing terminal when stdin is a TTY and plain line
+// reads otherwise (pipes, tests).
+func (con *console) setupIO() (func(), error) {
+	if f, ok := con.app.Stdin.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		state, err := term.MakeRaw(int(f.Fd()))
+		if err != nil {
+			return nil, err
+		}
+		t := term.NewTerminal(struct {
+			io.Reader
+			io.Writer
+		}{f, con.app.Stdout}, consolePrompt)
+		t.AutoCompleteCallback = Complete
+		con.out = t
+		con.read = t.ReadLine
+		return func() { term.Restore(int(f.Fd()), state) }, nil
+	}
+
+	scanner := bufio.NewScanner(con.app.Stdin)
+	con.out = con.app.Stdout
+	con.read = func() (string, error) {
+		if scanner.Scan() {
+			return scanner.Text(), nil
+		}
+		if err := scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return func() {}, nil
+}
+
+// printf writes one line of output. The terminal redraws the prompt around
+// it, so button events arriving mid-edit do not corrupt the input line.
+func (con *console) printf(format string, args ...interface{}) {
+	con.app.mu.Lock()
+	defer con.app.mu.Unlock()
+	fmt.Fprintf(con.out, format+"\n", args...)
+}
+
+// Write prints transport debug output like printf, so :trace does not
+// corrupt the line being edited.
+func (con *console) Write(p []byte) (int, error) {
+	con.app.mu.Lock()
+	defer con.app.mu.Unlock()
+	return con.out.Write(p)
+}
+
+func (con *console) run(ctx context.Context) error {
+	if err := con.setEvents(true); err != nil {
+		return err
+	}
+	defer con.c.SetButtonCallback(nil)
+	if l, ok := con.c.Transport.(interface{ SetLogOutput(io.Writer) }); ok {
+		l.SetLogOutput(con)
+		defer l.SetLogOutput(nil)
+	}
+
+	con.printf("Connected. Type :help for commands, Tab to complete, Ctrl+D to exit.")
+	for ctx.Err() == nil {
+		line, err := con.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		con.history = append(con.history, line)
+		if quit := con.exec(line); quit {
+			return nil
+		}
+	}
+	return nil
+}
+
+// exec runs one input line and reports whether the console should exit.
+func (con *console) exec(line string) bool {
+	if strings.HasPrefix(line, ":") {
+		return con.meta(strings.Fields(line))
+	}
+
+	// Queries are written with empty parentheses, e.g. km.version() or km.lock_mx().
+	expect := strings.HasSuffix(line, "()")
+	if con.trace {
+		con.printf("-> %s", line)
+	}
+	start := time.Now()
+	resp, err := con.c.Transport.SendCommand(line, expect, con.timeout)
+	switch {
+	case err != nil:
+		con.printf("error: %v", err)
+	case expect && con.trace:
+		con.printf("<- %s (%v)", resp, time.Since(start).Round(time.Microsecond))
+	case expect:
+		con.printf("%s", resp)
+	}
+	return false
+}
+
+func (con *console) meta(fields []string) bool {
+	arg := ""
+	if len(fields) > 1 {
+		arg = fields[1]
+	}
+	switch fields[0] {
+	case ":quit", ":exit":
+		return true
+	case ":help":
+		con.printf("km.<cmd>(...)      send a command; commands ending in () wait for a response")
+		con.printf(":trace on|off      show sent commands and response latency")
+		con.printf(":timeout [d]       show or set the response timeout (e.g. 250ms)")
+		con.printf(":events on|off     show button events as they arrive")
+		con.printf(":history           list commands entered this session")
+		con.printf(":quit              leave the console")
+	case ":trace":
+		on, ok := parseOnOff(arg)
+		if !ok {
+			con.printf("usage: :trace on|off")
+			break
+		}
+		con.trace = on
+		if d, ok := con.c.Transport.(interface{ SetDebug(bool) }); ok {
+			d.SetDebug(on)
+		}
+		con.printf("trace %s", arg)
+	case ":timeout":
+		if arg != "" {
+			d, err := time.ParseDuration(arg)
+			if err != nil || d <= 0 {
+				con.printf("invalid timeout %q", arg)
+				break
+			}
+			con.timeout = d
+		}
+		con.printf("timeout %v", con.timeout)
+	case ":events":
+		on, ok := parseOnOff(arg)
+		if !ok {
+			con.printf("usage: :events on|off")
+			break
+		}
+		if err := con.setEvents(on); err != nil {
+			con.printf("error: %v", err)
+			break
+		}
+		con.printf("events %s", arg)
+	case ":history":
+		for i, h := range con.history[:len(con.history)-1] {
+			con.printf("%4d  %s", i+1, h)
+		}
+	default:
+		con.printf("unknown meta-command %s (try :help)", fields[0])
+	}
+	return false
+}
+
+func (con *console) setEvents(on bool) error {
+	if !on {
+		con.c.SetButtonCallback(nil)
+		return nil
+	}
+	if err := con.c.SetButtonCallback(func(b Macku.MouseButton, pressed bool) {
+		con.printf("[event] %s %s", b, map[bool]string{true: "pressed", false: "released"}[pressed])
+	}); err != nil {
+		return err
+	}
+	return con.c.EnableButtonMonitoring(true)
+}
+
+func parseOnOff(s string) (bool, bool) {
+	switch s {
+	case "on":
+		return true, true
+	case "off":
+		return false, true
+	}
+	return false, false
+}
@@ -0,0 +1,222 @@
+// Package events fans a controller's button and connection callbacks out to
+// any number of subscribers. The network front ends (daemon, grpcapi,
+// httpapi) use it so each client gets its own ordered event stream.
+package events
+
+import (
+	"sync"
+	"time"
+
+	Macku "github.com/Auchrio/Makcu-go-lib/v3"
+)
+
+// Kind distinguishes button events from connection-state changes.
+type Kind int
+
+const (
+	Button Kind = iota
+	Connection
+)
+
+// Event is one button or connection change. Mask is the button bitmask
+// after the change. Intercepted marks a button change kept from the host
+// by a lock and catch.
+type Event struct {
+	Kind        Kind
+	Button      Macku.MouseButton
+	Pressed     bool
+	Intercepted bool
+	Connected   bool
+	Mask        int
+	Time        time.Time
+}
+
+// Hub owns a controller's button event callback and broadcasts to subscribers.
+type Hub struct {
+	c *Macku.MakcuController
+
+	mu        sync.Mutex
+	subs      map[*Subscription]struct{}
+	mask      int
+	refs      int
+	listening bool // the button event callback is registered
+	detached  bool
+	unwatch   func() // removes the connection callback
+}
+
+var (
+	hubsMu sync.Mutex
+	hubs   = make(map[*Macku.MakcuController]*Hub)
+)
+
+// Attach returns the hub for c, taking over c's button event callback the first
+// time. Several servers may share one controller; each Attach must be paired
+// with a Detach. A controller that is not connected yet refuses the callback;
+// the hub registers it again when the controller connects.
+func Attach(c *Macku.MakcuController) *Hub {
+	hubsMu.Lock()
+	defer hubsMu.Unlock()
+	if h, ok := hubs[c]; ok {
+		h.mu.Lock()
+		h.refs++
+		h.mu.Unlock()
+		return h
+	}
+	h := &Hub{
+		c:    c,
+		subs: make(map[*Subscription]struct{}),
+		mask: c.Transport.GetButtonMask(),
+		refs: 1,
+	}
+	h.listening = c.SetButtonEventCallback(h.onButton) == nil
+	h.unwatch = c.OnConnectionChange(h.onConnection)
+	hubs[c] = h
+	return h
+}
+
+// Detach drops one reference. The last Detach removes the event and
+// connection callbacks and closes every remaining subscription.
+func (h *Hub) Detach() {
+	hubsMu.Lock()
+	defer hubsMu.Unlock()
+	h.mu.Lock()
+	if h.detached {
+		h.mu.Unlock()
+		return
+	}
+	if h.refs--; h.refs > 0 {
+		h.mu.Unlock()
+		return
+	}
+	h.detached = true
+	delete(hubs, h.c)
+	subs := h.subs
+	h.subs = make(map[*Subscription]struct{})
+	h.mu.Unlock()
+
+	h.c.SetButtonEventCallback(nil)
+	h.unwatch()
+	for s := range subs {
+		s.close()
+	}
+}
+
+// Mask returns the button bitmask as tracked from events.
+func (h *Hub) Mask() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.mask
+}
+
+// Subscribe returns a new subscription buffering up to buffer events. A
+// subscriber that falls further behind loses events (counted by Dropped)
+// rather than stalling the device's read loop.
+func (h *Hub) Subscribe(buffer int) *Subscription {
+	s := &Subscription{hub: h, ch: make(chan Event, buffer)}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.detached {
+		close(s.ch)
+		s.closed = true
+		return s
+	}
+	h.subs[s] = struct{}{}
+	return s
+}
+
+func (h *Hub) onButton(be Macku.ButtonEvent) {
+	h.mu.Lock()
+	if be.Pressed {
+		h.mask |= 1 << uint(be.Button)
+	} else {
+		h.mask &^= 1 << uint(be.Button)
+	}
+	ev := Event{Kind: Button, Button: be.Button, Pressed: be.Pressed, Intercepted: be.Intercepted, Mask: h.mask, Time: time.Now()}
+	h.mu.Unlock()
+	h.broadcast(ev)
+}
+
+func (h *Hub) onConnection(connected bool) {
+	if connected {
+		h.listen()
+	}
+	h.broadcast(Event{Kind: Connection, Connected: connected, Mask: h.Mask(), Time: time.Now()})
+}
+
+func (h *Hub) broadcast(ev Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for s := range h.subs {
+		s.send(ev)
+	}
+}
+
+// listen registers the button event callback if Attach could not. hubsMu
+// keeps it from racing a Detach that removes the callback.
+func (h *Hub) listen() {
+	hubsMu.Lock()
+	defer hubsMu.Unlock()
+	h.mu.Lock()
+	skip := h.listening || h.detached
+	h.mu.Unlock()
+	if skip {
+		return
+	}
+	if h.c.SetButtonEventCallback(h.onButton) == nil {
+		h.mu.Lock()
+		h.listening = true
+		h.mu.Unlock()
+	}
+}
+
+// Subscription is one subscriber's event stream.
+type Subscription struct {
+	hub *Hub
+	ch  chan Event
+
+	mu      sync.Mutex
+	closed  bool
+	dropped int
+}
+
+// C returns the event channel; it is closed when the subscription ends.
+func (s *Subscription) C() <-chan Event {
+	return s.ch
+}
+
+// Dropped returns how many events were discarded because the buffer was full.
+func (s *Subscription) Dropped() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+// Close ends the subscription.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	delete(s.hub.subs, s)
+	s.hub.mu.Unlock()
+	s.close()
+}
+
+func (s *Subscription) send(ev Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.ch <- ev:
+	default:
+		s.dropped++
+	}
+}
+
+func (s *Subscription) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.ch)
+	}
+}
@@ -0,0 +1,188 @@
+package Macku
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// LockStatus is what the library knows about one lock target.
+type LockStatus struct {
+	Locked    bool
+	Known     bool      // set or read since the device was last connected
+	Confirmed bool      // read back from the device, not just commanded
+	Updated   time.Time // when last set or read; zero if never
+}
+
+// Stale reports whether the status has to be read from the device again:
+// it is unknown, or older than maxAge when maxAge is positive.
+func (s LockStatus) Stale(maxAge time.Duration) bool {
+	return !s.Known || maxAge > 0 && time.Since(s.Updated) > maxAge
+}
+
+// LockQueryError reports the lock targets whose state could not be read.
+// It unwraps to each underlying error, so errors.Is(err, ErrTimeout) holds
+// if any query timed out.
+type LockQueryError struct {
+	Failed map[string]error // by target name, e.g. "MOUSE4"
+}
+
+func (e *LockQueryError) Error() string {
+	var parts []string
+	for _, name := range lockOrder {
+		if err, ok := e.Failed[name]; ok {
+			parts = append(parts, name+": "+err.Error())
+		}
+	}
+	return "lock state unknown for " + strings.Join(parts, "; ")
+}
+
+func (e *LockQueryError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failed))
+	for _, name := range lockOrder {
+		if err, ok := e.Failed[name]; ok {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// lockStates tracks the device's lock and catch state, per target name.
+// States belong to one connection of the transport and are dropped when it
+// changes.
+type lockStates struct {
+	mu     sync.Mutex
+	status map[string]LockStatus
+	caught map[string]bool // buttons with catch enabled
+	conns  uint64
+	maxAge time.Duration
+}
+
+// connectionCounter is implemented by transports that count (re)connects.
+type connectionCounter interface {
+	Connections() uint64
+}
+
+// syncLocks drops lock and catch states from an earlier connection.
+// Callers hold m.locks.mu.
+func (m *Mouse) syncLocks() {
+	conns := uint64(0)
+	if cc, ok := m.transport.(connectionCounter); ok {
+		conns = cc.Connections()
+	}
+	if conns != m.locks.conns {
+		m.locks.status = nil
+		m.locks.caught = nil
+		m.locks.conns = conns
+	}
+	if m.locks.status == nil {
+		m.locks.status = make(map[string]LockStatus, len(lockTargets))
+	}
+	if m.locks.caught == nil {
+		m.locks.caught = make(map[string]bool)
+	}
+}
+
+// setLockStatus records the state of target name.
+func (m *Mouse) setLockStatus(name string, locked, confirmed bool) {
+	m.locks.mu.Lock()
+	defer m.locks.mu.Unlock()
+	m.syncLocks()
+	m.locks.status[name] = LockStatus{Locked: locked, Known: true, Confirmed: confirmed, Updated: time.Now()}
+}
+
+// cachedLock returns the state of target name if it is known and fresh.
+func (m *Mouse) cachedLock(name string) (bool, bool) {
+	m.locks.mu.Lock()
+	defer m.locks.mu.Unlock()
+	m.syncLocks()
+	st := m.locks.status[name]
+	return st.Locked, !st.Stale(m.locks.maxAge)
+}
+
+// queryLock reads the state of target name from the device.
+func (m *Mouse) queryLock(name string) (bool, error) {
+	info := lockTargets[name]
+	resp, err := m.transport.SendCommand(info.queryCmd, true, 50*time.Millisecond)
+	if err != nil {
+		return false, err
+	}
+	locked, err := ParseBoolResponse(info.queryCmd, resp)
+	if err != nil {
+		return false, err
+	}
+	m.setLockStatus(name, locked, true)
+	return locked, nil
+}
+
+// lockState returns whether target name is locked, from the cache if it
+// is fresh.
+func (m *Mouse) lockState(name string) (bool, error) {
+	if locked, ok := m.cachedLock(name); ok {
+		return locked, nil
+	}
+	return m.queryLock(name)
+}
+
+// readLocks returns the state of every supported target, querying those
+// that are stale, or all of them if force is set.
+func (m *Mouse) readLocks(force bool) (map[string]bool, error) {
+	states := make(map[string]bool, len(lockOrder))
+	var failed map[string]error
+	for _, name := range lockOrder {
+		if locked, ok := m.cachedLock(name); ok && !force {
+			states[name] = locked
+			continue
+		}
+		locked, err := m.queryLock(name)
+		if err != nil {
+			if failed == nil {
+				failed = make(map[string]error)
+			}
+			failed[name] = err
+			continue
+		}
+		states[name] = locked
+	}
+	if failed != nil {
+		return states, &LockQueryError{Failed: failed}
+	}
+	return states, nil
+}
+
+// LockStatuses returns what is known about every supported lock target,
+// without querying the device.
+func (m *Mouse) LockStatuses() map[string]LockStatus {
+	m.locks.mu.Lock()
+	defer m.locks.mu.Unlock()
+	m.syncLocks()
+	out := make(map[string]LockStatus)
+	for _, name := range lockOrder {
+		out[name] = m.locks.status[name]
+	}
+	return out
+}
+
+// RefreshLocks reads the state of every supported lock target from the
+// device, whatever is cached. Targets that could not be read are missing
+// from the result and listed in a *LockQueryError.
+func (m *Mouse) RefreshLocks() (map[string]bool, error) {
+	return m.readLocks(true)
+}
+
+// SetLockMaxAge makes lock states older than d count as stale, so they are
+// read from the device again. Zero, the default, keeps them until the
+// device reconnects.
+func (m *Mouse) SetLockMaxAge(d time.Duration) {
+	m.locks.mu.Lock()
+	defer m.locks.mu.Unlock()
+	m.locks.maxAge = d
+}
+
+// InvalidateCache marks every lock state as unknown, so the next read
+// queries the device.
+func (m *Mouse) InvalidateCache() {
+	m.locks.mu.Lock()
+	defer m.locks.mu.Unlock()
+	m.locks.status = nil
+}
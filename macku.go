@@ -4,4 +4,4 @@
 package Macku
 
 // Version is the current version of the Macku library.
-const Version = "2.3.0"
+const Version = "3.0.0"
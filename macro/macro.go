@@ -0,0 +1,429 @@
+// Package macro implements a versioned JSON macro format for Makcu devices
+// and a Runner that executes macros against a MakcuController.
+//
+// A macro file looks like:
+//
+//	{
+//	  "version": 1,
+//	  "macros": {
+//	    "double": {"steps": [{"op": "click", "button": "left"}, {"op": "click", "button": "left"}]}
+//	  },
+//	  "steps": [
+//	    {"op": "move", "x": 100, "y": 0},
+//	    {"op": "wait", "duration": "150ms"},
+//	    {"op": "loop", "count": 3, "steps": [{"op": "call", "macro": "double"}]}
+//	  ]
+//	}
+package macro
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	Macku "github.com/Auchrio/Makcu-go-lib/v3"
+)
+
+// Version is the macro format version understood by this package.
+const Version = 1
+
+// Step operations.
+const (
+	OpMove          = "move"
+	OpSmoothMove    = "smooth_move"
+	OpClick         = "click"
+	OpPress         = "press"
+	OpRelease       = "release"
+	OpScroll        = "scroll"
+	OpWait          = "wait"
+	OpWaitForButton = "wait_for_button"
+	OpLock          = "lock"
+	OpUnlock        = "unlock"
+	OpLoop          = "loop"
+	OpCall          = "call"
+)
+
+// Duration is a time.Duration that unmarshals from a Go duration string
+// ("150ms") or a number of milliseconds.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	if s, err := strconv.Unquote(string(data)); err == nil {
+		v, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		*d = Duration(v)
+		return nil
+	}
+	ms, err := strconv.ParseFloat(string(data), 64)
+	if err != nil {
+		return fmt.Errorf("invalid duration %s", data)
+	}
+	*d = Duration(ms * float64(time.Millisecond))
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Step is one macro instruction. Op selects which of the other fields apply.
+type Step struct {
+	Op       string   `json:"op"`
+	X        int      `json:"x,omitempty"`        // move, smooth_move
+	Y        int      `json:"y,omitempty"`        // move, smooth_move
+	Segments int      `json:"segments,omitempty"` // smooth_move
+	Button   string   `json:"button,omitempty"`   // click, press, release, wait_for_button
+	Pressed  *bool    `json:"pressed,omitempty"`  // wait_for_button (default true)
+	Target   string   `json:"target,omitempty"`   // lock, unlock
+	Delta    int      `json:"delta,omitempty"`    // scroll
+	Duration Duration `json:"duration,omitempty"` // wait
+	Timeout  Duration `json:"timeout,omitempty"`  // wait_for_button (0 = no timeout)
+	Count    int      `json:"count,omitempty"`    // loop
+	Steps    []Step   `json:"steps,omitempty"`    // loop
+	Macro    string   `json:"macro,omitempty"`    // call
+
+	Line int `json:"-"` // Source line, set by Parse
+}
+
+// Sub is a named sub-macro that steps can invoke with OpCall.
+type Sub struct {
+	Steps []Step `json:"steps"`
+}
+
+// Macro is a parsed macro file.
+type Macro struct {
+	Version int            `json:"version"`
+	Name    string         `json:"name,omitempty"`
+	Macros  map[string]Sub `json:"macros,omitempty"`
+	Steps   []Step         `json:"steps"`
+}
+
+// Error is a macro syntax or validation error at a source line.
+type Error struct {
+	Line int
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+	}
+	return e.Msg
+}
+
+// Errors collects every validation error found in a macro.
+type Errors []*Error
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// ParseFile reads and parses a macro file.
+func ParseFile(path string) (*Macro, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse decodes and validates a macro. Errors carry the source line of the
+// offending step; validation failures are returned together as Errors.
+func Parse(data []byte) (*Macro, error) {
+	var m Macro
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&m); err != nil {
+		return nil, decodeError(data, err)
+	}
+
+	lines, err := lineIndex(data)
+	if err != nil {
+		return nil, decodeError(data, err)
+	}
+	m.setLines(lines)
+
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// decodeError converts a json decoding error into an *Error with a line number.
+func decodeError(data []byte, err error) error {
+	var syntax *json.SyntaxError
+	var typ *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntax):
+		return &Error{Line: lineAt(data, syntax.Offset), Msg: syntax.Error()}
+	case errors.As(err, &typ):
+		return &Error{Line: lineAt(data, typ.Offset), Msg: fmt.Sprintf("%s: expected %s, got %s", typ.Field, typ.Type, typ.Value)}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// DisallowUnknownFields errors carry neither an offset nor a path.
+		return &Error{Line: unknownFieldLine(data, reflect.TypeFor[Macro]()), Msg: strings.TrimPrefix(err.Error(), "json: ")}
+	default:
+		return &Error{Msg: err.Error()}
+	}
+}
+
+// unknownFieldLine returns the line of the first object key in data that
+// has no field in the struct decoded from it, or 0 if there is none. The
+// decoder stops at that key, so it is the one an unknown-field error means.
+func unknownFieldLine(data []byte, t reflect.Type) int {
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	// walk reads one value of type t (nil if not checked) and returns the
+	// line of the first unknown key in it.
+	var walk func(t reflect.Type) (int, error)
+	walk = func(t reflect.Type) (int, error) {
+		tok, err := dec.Token()
+		if err != nil {
+			return 0, err
+		}
+		delim, ok := tok.(json.Delim)
+		if !ok {
+			return 0, nil
+		}
+		for t != nil && t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		for dec.More() {
+			var elem reflect.Type
+			if delim == '{' {
+				key, err := dec.Token()
+				if err != nil {
+					return 0, err
+				}
+				switch {
+				case t == nil:
+				case t.Kind() == reflect.Struct:
+					f, ok := jsonField(t, key.(string))
+					if !ok {
+						return lineAt(data, dec.InputOffset()), nil
+					}
+					elem = f.Type
+				case t.Kind() == reflect.Map:
+					elem = t.Elem()
+				}
+			} else if t != nil && t.Kind() == reflect.Slice {
+				elem = t.Elem()
+			}
+			if line, err := walk(elem); line > 0 || err != nil {
+				return line, err
+			}
+		}
+		_, err = dec.Token()
+		return 0, err
+	}
+
+	line, _ := walk(t)
+	return line
+}
+
+// jsonField returns the field of struct t that key decodes into, matching
+// names case-insensitively like encoding/json.
+func jsonField(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if strings.EqualFold(name, key) {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func lineAt(data []byte, offset int64) int {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	return strings.Count(string(data[:offset]), "\n") + 1
+}
+
+// lineIndex walks the JSON token stream and maps every value's path
+// (e.g. ".steps[2].steps[0]") to the line it starts on.
+func lineIndex(data []byte) (map[string]int, error) {
+	lines := make(map[string]int)
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	var walk func(path string) error
+	walk = func(path string) error {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		lines[path] = lineAt(data, dec.InputOffset())
+		delim, ok := tok.(json.Delim)
+		if !ok {
+			return nil
+		}
+		for i := 0; dec.More(); i++ {
+			if delim == '{' {
+				key, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				if err := walk(fmt.Sprintf("%s.%v", path, key)); err != nil {
+					return err
+				}
+			} else if err := walk(fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		_, err = dec.Token()
+		return err
+	}
+
+	return lines, walk("")
+}
+
+func (m *Macro) setLines(lines map[string]int) {
+	var set func(steps []Step, path string)
+	set = func(steps []Step, path string) {
+		for i := range steps {
+			p := fmt.Sprintf("%s[%d]", path, i)
+			steps[i].Line = lines[p]
+			set(steps[i].Steps, p+".steps")
+		}
+	}
+	set(m.Steps, ".steps")
+	for name, sub := range m.Macros {
+		set(sub.Steps, ".macros."+name+".steps")
+	}
+}
+
+// Validate checks the macro for unknown operations, missing or out-of-range
+// arguments, undefined or recursive sub-macro calls. All problems are
+// reported together as Errors.
+func (m *Macro) Validate() error {
+	var errs Errors
+	add := func(line int, format string, args ...interface{}) {
+		errs = append(errs, &Error{Line: line, Msg: fmt.Sprintf(format, args...)})
+	}
+
+	if m.Version != Version {
+		add(0, "unsupported macro version %d (want %d)", m.Version, Version)
+	}
+
+	var check func(steps []Step)
+	check = func(steps []Step) {
+		for _, s := range steps {
+			switch s.Op {
+			case OpMove:
+			case OpSmoothMove:
+				if s.Segments < 1 {
+					add(s.Line, "smooth_move: segments must be >= 1")
+				}
+			case OpClick, OpPress, OpRelease, OpWaitForButton:
+				if _, err := Macku.ParseMouseButton(s.Button); err != nil {
+					add(s.Line, "%s: %v", s.Op, err)
+				}
+				if s.Timeout < 0 {
+					add(s.Line, "%s: timeout must not be negative", s.Op)
+				}
+			case OpScroll:
+				if s.Delta == 0 {
+					add(s.Line, "scroll: delta must be non-zero")
+				}
+			case OpWait:
+				if s.Duration <= 0 {
+					add(s.Line, "wait: duration must be positive")
+				}
+			case OpLock, OpUnlock:
+				if _, err := Macku.ParseLockTarget(s.Target); err != nil {
+					add(s.Line, "%s: %v", s.Op, err)
+				}
+			case OpLoop:
+				if s.Count < 1 {
+					add(s.Line, "loop: count must be >= 1")
+				}
+				if len(s.Steps) == 0 {
+					add(s.Line, "loop: steps must not be empty")
+				}
+				check(s.Steps)
+			case OpCall:
+				if _, ok := m.Macros[s.Macro]; !ok {
+					add(s.Line, "call: undefined macro %q", s.Macro)
+				}
+			case "":
+				add(s.Line, "missing op")
+			default:
+				add(s.Line, "unknown op %q", s.Op)
+			}
+		}
+	}
+
+	names := make([]string, 0, len(m.Macros))
+	for name := range m.Macros {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	check(m.Steps)
+	for _, name := range names {
+		check(m.Macros[name].Steps)
+	}
+	for _, name := range names {
+		if line, ok := m.findCycle(name, map[string]bool{}); ok {
+			add(line, "call: macro %q calls itself recursively", name)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// findCycle reports whether calling the named macro can reach itself again,
+// returning the line of the call that closes the cycle.
+func (m *Macro) findCycle(name string, visiting map[string]bool) (int, bool) {
+	if visiting[name] {
+		return 0, true
+	}
+	visiting[name] = true
+	defer delete(visiting, name)
+
+	var search func(steps []Step) (int, bool)
+	search = func(steps []Step) (int, bool) {
+		for _, s := range steps {
+			switch s.Op {
+			case OpCall:
+				if _, ok := m.Macros[s.Macro]; !ok {
+					continue
+				}
+				if line, ok := m.findCycle(s.Macro, visiting); ok {
+					if line == 0 {
+						line = s.Line
+					}
+					return line, true
+				}
+			case OpLoop:
+				if line, ok := search(s.Steps); ok {
+					return line, true
+				}
+			}
+		}
+		return 0, false
+	}
+	return search(m.Macros[name].Steps)
+}
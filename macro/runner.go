@@ -0,0 +1,195 @@
+package macro
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	Macku "github.com/Auchrio/Makcu-go-lib/v3"
+)
+
+// DefaultPollInterval is how often wait_for_button samples the button state.
+const DefaultPollInterval = 5 * time.Millisecond
+
+// Runner executes macros against a MakcuController.
+type Runner struct {
+	Controller   *Macku.MakcuController
+	PollInterval time.Duration // Button poll interval for wait_for_button
+
+	macro *Macro
+	held  map[Macku.MouseButton]bool
+	locks map[Macku.LockTarget]bool
+}
+
+// NewRunner creates a Runner for the given controller.
+func NewRunner(c *Macku.MakcuController) *Runner {
+	return &Runner{Controller: c, PollInterval: DefaultPollInterval}
+}
+
+// Run validates and executes m until it completes, fails, or ctx is
+// cancelled. Whatever the outcome — including a panic — every button the
+// macro pressed and every target it locked is released before Run returns.
+func (r *Runner) Run(ctx context.Context, m *Macro) (err error) {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+
+	r.macro = m
+	r.held = make(map[Macku.MouseButton]bool)
+	r.locks = make(map[Macku.LockTarget]bool)
+	defer func() {
+		if cleanupErr := r.cleanup(); err == nil {
+			err = cleanupErr
+		}
+	}()
+
+	return r.runSteps(ctx, m.Steps)
+}
+
+// cleanup releases held buttons and unlocks targets locked by the macro.
+func (r *Runner) cleanup() error {
+	var first error
+	for button := range r.held {
+		if err := r.Controller.Release(button); err != nil && first == nil {
+			first = err
+		}
+	}
+	for target := range r.locks {
+		if err := r.Controller.Unlock(target); err != nil && first == nil {
+			first = err
+		}
+	}
+	r.held, r.locks = nil, nil
+	return first
+}
+
+func (r *Runner) runSteps(ctx context.Context, steps []Step) error {
+	for _, s := range steps {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := r.runStep(ctx, s); err != nil {
+			var se *stepError
+			if errors.As(err, &se) || ctx.Err() != nil {
+				return err
+			}
+			return &stepError{step: s, err: err}
+		}
+	}
+	return nil
+}
+
+func (r *Runner) runStep(ctx context.Context, s Step) error {
+	c := r.Controller
+	switch s.Op {
+	case OpMove:
+		return c.Move(s.X, s.Y)
+	case OpSmoothMove:
+		return c.MoveSmooth(s.X, s.Y, s.Segments)
+	case OpClick:
+		button, _ := Macku.ParseMouseButton(s.Button)
+		return c.Click(button)
+	case OpPress:
+		button, _ := Macku.ParseMouseButton(s.Button)
+		if err := c.Press(button); err != nil {
+			return err
+		}
+		r.held[button] = true
+	case OpRelease:
+		button, _ := Macku.ParseMouseButton(s.Button)
+		if err := c.Release(button); err != nil {
+			return err
+		}
+		delete(r.held, button)
+	case OpScroll:
+		return c.Scroll(s.Delta)
+	case OpWait:
+		return sleep(ctx, time.Duration(s.Duration))
+	case OpWaitForButton:
+		return r.waitForButton(ctx, s)
+	case OpLock:
+		target, _ := Macku.ParseLockTarget(s.Target)
+		if err := c.Lock(target); err != nil {
+			return err
+		}
+		r.locks[target] = true
+	case OpUnlock:
+		target, _ := Macku.ParseLockTarget(s.Target)
+		if err := c.Unlock(target); err != nil {
+			return err
+		}
+		delete(r.locks, target)
+	case OpLoop:
+		for i := 0; i < s.Count; i++ {
+			if err := r.runSteps(ctx, s.Steps); err != nil {
+				return err
+			}
+		}
+	case OpCall:
+		return r.runSteps(ctx, r.macro.Macros[s.Macro].Steps)
+	}
+	return nil
+}
+
+// waitForButton polls the button state until it matches, the step timeout
+// expires (ErrTimeout), or ctx is cancelled.
+func (r *Runner) waitForButton(ctx context.Context, s Step) error {
+	button, _ := Macku.ParseMouseButton(s.Button)
+	want := s.Pressed == nil || *s.Pressed
+
+	interval := r.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	var deadline <-chan time.Time
+	if s.Timeout > 0 {
+		timer := time.NewTimer(time.Duration(s.Timeout))
+		defer timer.Stop()
+		deadline = timer.C
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		pressed, err := r.Controller.IsPressed(button)
+		if err != nil {
+			return err
+		}
+		if pressed == want {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline:
+			return Macku.NewTimeoutError(fmt.Sprintf("timed out waiting for %s (pressed=%v)", button, want))
+		case <-ticker.C:
+		}
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// stepError annotates a runtime failure with the source line of its step.
+type stepError struct {
+	step Step
+	err  error
+}
+
+func (e *stepError) Error() string {
+	return fmt.Sprintf("line %d: %s: %v", e.step.Line, e.step.Op, e.err)
+}
+
+func (e *stepError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,245 @@
+package Macku
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LatencyBuckets are the upper bounds, in seconds, of the response latency
+// histograms.
+var LatencyBuckets = []float64{0.0005, 0.001, 0.002, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1}
+
+// Histogram is a snapshot of a latency histogram.
+type Histogram struct {
+	Counts []uint64 // per bucket of LatencyBuckets, not cumulative; one extra for +Inf
+	Count  uint64
+	Sum    float64 // seconds
+}
+
+// Metrics is a point-in-time snapshot of transport activity.
+type Metrics struct {
+	Connected         bool
+	CommandsSent      map[string]uint64    // by command type ("move", "version", ...)
+	BytesWritten      uint64               // bytes written to the device
+	BytesRead         uint64               // bytes read from the device
+	ResponseLatency   map[string]Histogram // by query command type
+	Timeouts          uint64               // queries that timed out in SendCommand
+	StaleCommands     uint64               // pending commands dropped by the listener's cleanup
+	ReconnectAttempts uint64
+	Reconnects        uint64            // successful reconnects
+	ButtonEvents      map[string]uint64 // press and release transitions by button name
+}
+
+// MetricsSource is implemented by transports that keep Metrics.
+type MetricsSource interface {
+	Metrics() Metrics
+}
+
+// commandType returns the metric label for a command: the name of a km.*
+// call ("km.move(1,2)" -> "move"), or "other".
+func commandType(command string) string {
+	if i := strings.Index(command, "#"); i >= 0 {
+		command = command[:i]
+	}
+	rest, ok := strings.CutPrefix(command, "km.")
+	if !ok {
+		return "other"
+	}
+	name, _, ok := strings.Cut(rest, "(")
+	if !ok || name == "" || len(name) > 32 {
+		return "other"
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_' || r == '.') {
+			return "other"
+		}
+	}
+	return name
+}
+
+// --- collection ---
+
+// transportMetrics accumulates Metrics for a SerialTransport.
+type transportMetrics struct {
+	mu                sync.Mutex
+	commands          map[string]uint64
+	bytesOut          uint64
+	bytesIn           uint64
+	latency           map[string]*Histogram
+	timeouts          uint64
+	stale             uint64
+	reconnectAttempts uint64
+	reconnects        uint64
+	buttons           map[string]uint64
+}
+
+func newTransportMetrics() *transportMetrics {
+	return &transportMetrics{
+		commands: make(map[string]uint64),
+		latency:  make(map[string]*Histogram),
+		buttons:  make(map[string]uint64),
+	}
+}
+
+func (m *transportMetrics) add(counter *uint64, n uint64) {
+	m.mu.Lock()
+	*counter += n
+	m.mu.Unlock()
+}
+
+func (m *transportMetrics) commandSent(command string) {
+	m.mu.Lock()
+	m.commands[commandType(command)]++
+	m.mu.Unlock()
+}
+
+func (m *transportMetrics) observeLatency(command string, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name := commandType(command)
+	h := m.latency[name]
+	if h == nil {
+		h = &Histogram{Counts: make([]uint64, len(LatencyBuckets)+1)}
+		m.latency[name] = h
+	}
+	secs := d.Seconds()
+	i := sort.SearchFloat64s(LatencyBuckets, secs)
+	h.Counts[i]++
+	h.Count++
+	h.Sum += secs
+}
+
+func (m *transportMetrics) buttonEvent(name string) {
+	m.mu.Lock()
+	m.buttons[name]++
+	m.mu.Unlock()
+}
+
+func (m *transportMetrics) snapshot(connected bool) Metrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := Metrics{
+		Connected:         connected,
+		CommandsSent:      make(map[string]uint64, len(m.commands)),
+		BytesWritten:      m.bytesOut,
+		BytesRead:         m.bytesIn,
+		ResponseLatency:   make(map[string]Histogram, len(m.latency)),
+		Timeouts:          m.timeouts,
+		StaleCommands:     m.stale,
+		ReconnectAttempts: m.reconnectAttempts,
+		Reconnects:        m.reconnects,
+		ButtonEvents:      make(map[string]uint64, len(m.buttons)),
+	}
+	for k, v := range m.commands {
+		out.CommandsSent[k] = v
+	}
+	for k, h := range m.latency {
+		out.ResponseLatency[k] = Histogram{Counts: append([]uint64(nil), h.Counts...), Count: h.Count, Sum: h.Sum}
+	}
+	for k, v := range m.buttons {
+		out.ButtonEvents[k] = v
+	}
+	return out
+}
+
+// --- Prometheus exposition ---
+
+// WritePrometheus writes m in the Prometheus text exposition format.
+func WritePrometheus(w io.Writer, m Metrics) error {
+	pw := &promWriter{w: w}
+	pw.gauge("makcu_connected", "Whether the device link is up.", boolFloat(m.Connected))
+	pw.labelledCounter("makcu_commands_sent_total", "Commands written to the device, by command.", "command", m.CommandsSent)
+	pw.counter("makcu_bytes_written_total", "Bytes written to the device.", m.BytesWritten)
+	pw.counter("makcu_bytes_read_total", "Bytes read from the device.", m.BytesRead)
+
+	pw.header("makcu_response_latency_seconds", "histogram", "Time from sending a query to its response, by command.")
+	names := make([]string, 0, len(m.ResponseLatency))
+	for name := range m.ResponseLatency {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		h := m.ResponseLatency[name]
+		var cum uint64
+		for i, upper := range LatencyBuckets {
+			cum += h.Counts[i]
+			pw.printf("makcu_response_latency_seconds_bucket{command=%q,le=%q} %d\n", name, formatFloat(upper), cum)
+		}
+		pw.printf("makcu_response_latency_seconds_bucket{command=%q,le=\"+Inf\"} %d\n", name, h.Count)
+		pw.printf("makcu_response_latency_seconds_sum{command=%q} %s\n", name, formatFloat(h.Sum))
+		pw.printf("makcu_response_latency_seconds_count{command=%q} %d\n", name, h.Count)
+	}
+
+	pw.counter("makcu_command_timeouts_total", "Queries that timed out waiting for a response.", m.Timeouts)
+	pw.counter("makcu_stale_commands_total", "Pending commands dropped as stale by the listener.", m.StaleCommands)
+	pw.counter("makcu_reconnect_attempts_total", "Reconnect attempts after a link failure.", m.ReconnectAttempts)
+	pw.counter("makcu_reconnects_total", "Successful reconnects.", m.Reconnects)
+	pw.labelledCounter("makcu_button_events_total", "Button press and release transitions reported by the device.", "button", m.ButtonEvents)
+	return pw.err
+}
+
+// MetricsHandler serves src's metrics in the Prometheus text format.
+func MetricsHandler(src MetricsSource) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		WritePrometheus(w, src.Metrics())
+	})
+}
+
+type promWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (p *promWriter) printf(format string, args ...interface{}) {
+	if p.err == nil {
+		_, p.err = fmt.Fprintf(p.w, format, args...)
+	}
+}
+
+func (p *promWriter) header(name, typ, help string) {
+	p.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+func (p *promWriter) counter(name, help string, v uint64) {
+	p.header(name, "counter", help)
+	p.printf("%s %d\n", name, v)
+}
+
+func (p *promWriter) gauge(name, help string, v float64) {
+	p.header(name, "gauge", help)
+	p.printf("%s %s\n", name, formatFloat(v))
+}
+
+func (p *promWriter) labelledCounter(name, help, label string, values map[string]uint64) {
+	p.header(name, "counter", help)
+	for _, k := range sortedKeys(values) {
+		p.printf("%s{%s=%q} %d\n", name, label, k, values[k])
+	}
+}
+
+func sortedKeys(m map[string]uint64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func boolFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -3,60 +3,161 @@ package Macku
 import (
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/Auchrio/Makcu-go-lib/v3/protocol"
 	"go.bug.st/serial/enumerator"
 )
 
-// Pre-built command strings for press/release (avoids fmt.Sprintf per call).
-var (
-	pressCommands   = [5]string{"km.left(1)", "km.right(1)", "km.middle(1)", "km.ms1(1)", "km.ms2(1)"}
-	releaseCommands = [5]string{"km.left(0)", "km.right(0)", "km.middle(0)", "km.ms1(0)", "km.ms2(0)"}
-)
+// Pre-built command strings for press/release (avoids formatting per call).
+var pressCommands, releaseCommands [StandardButtons]string
+
+func init() {
+	for b := range StandardButtons {
+		pressCommands[b] = mustCommand(protocol.Button(b, true))
+		releaseCommands[b] = mustCommand(protocol.Button(b, false))
+	}
+}
+
+// mustCommand returns the text of a command built from constant arguments.
+func mustCommand(c protocol.Command, err error) string {
+	if err != nil {
+		panic(err)
+	}
+	return c.String()
+}
+
+// commandError reports an argument the protocol rejects as ErrCommand.
+func commandError(err error) error {
+	return NewCommandError(err.Error())
+}
 
-// lockInfo holds the serial commands and cache bit for one lock target.
+// lockInfo holds the serial commands for one lock target. Axes have no
+// catch commands.
 type lockInfo struct {
-	lockCmd   string
-	unlockCmd string
-	queryCmd  string
-	bit       int
+	lockCmd    string
+	unlockCmd  string
+	queryCmd   string
+	catchCmd   string
+	uncatchCmd string
+	catchQuery string
+	button     MouseButton // the button locked, or -1 for an axis
+}
+
+// newLockInfo builds the commands for protocol lock target name.
+func newLockInfo(name string, button MouseButton) lockInfo {
+	info := lockInfo{
+		lockCmd:   mustCommand(protocol.Lock(name, true)),
+		unlockCmd: mustCommand(protocol.Lock(name, false)),
+		queryCmd:  mustCommand(protocol.LockQuery(name)),
+		button:    button,
+	}
+	if button >= 0 {
+		info.catchCmd = mustCommand(protocol.Catch(name, true))
+		info.uncatchCmd = mustCommand(protocol.Catch(name, false))
+		info.catchQuery = mustCommand(protocol.CatchQuery(name))
+	}
+	return info
 }
 
 var lockTargets = map[string]lockInfo{
-	"LEFT":   {lockCmd: "km.lock_ml(1)", unlockCmd: "km.lock_ml(0)", queryCmd: "km.lock_ml()", bit: 0},
-	"RIGHT":  {lockCmd: "km.lock_mr(1)", unlockCmd: "km.lock_mr(0)", queryCmd: "km.lock_mr()", bit: 1},
-	"MIDDLE": {lockCmd: "km.lock_mm(1)", unlockCmd: "km.lock_mm(0)", queryCmd: "km.lock_mm()", bit: 2},
-	"MOUSE4": {lockCmd: "km.lock_ms1(1)", unlockCmd: "km.lock_ms1(0)", queryCmd: "km.lock_ms1()", bit: 3},
-	"MOUSE5": {lockCmd: "km.lock_ms2(1)", unlockCmd: "km.lock_ms2(0)", queryCmd: "km.lock_ms2()", bit: 4},
-	"X":      {lockCmd: "km.lock_mx(1)", unlockCmd: "km.lock_mx(0)", queryCmd: "km.lock_mx()", bit: 5},
-	"Y":      {lockCmd: "km.lock_my(1)", unlockCmd: "km.lock_my(0)", queryCmd: "km.lock_my()", bit: 6},
+	"LEFT":   newLockInfo("ml", MouseButtonLeft),
+	"RIGHT":  newLockInfo("mr", MouseButtonRight),
+	"MIDDLE": newLockInfo("mm", MouseButtonMiddle),
+	"MOUSE4": newLockInfo("ms1", MouseButton4),
+	"MOUSE5": newLockInfo("ms2", MouseButton5),
+	"X":      newLockInfo("mx", -1),
+	"Y":      newLockInfo("my", -1),
 }
 
+// lockOrder lists the lock targets in the order GetAllLockStates queries them.
+var lockOrder = []string{"LEFT", "RIGHT", "MIDDLE", "MOUSE4", "MOUSE5", "X", "Y"}
+
 // DeviceInfo holds information about the connected Makcu device.
 type DeviceInfo struct {
-	Port        string
-	Description string
-	VID         string
-	PID         string
-	IsConnected bool
+	Port        string `json:"port"`
+	Description string `json:"description"`
+	VID         string `json:"vid"`
+	PID         string `json:"pid"`
+	IsConnected bool   `json:"connected"`
 }
 
-// Mouse provides mid-level mouse operations over the SerialTransport.
+// Mouse provides mid-level mouse operations over a Transport.
 type Mouse struct {
-	transport       *SerialTransport
-	lockStatesCache int
-	cacheValid      bool
+	transport Transport
+	cursor    CursorProvider
+	accel     AccelerationModel
+	locks     lockStates
+
+	capsMu sync.RWMutex // guards caps
+	caps   Capabilities
 }
 
 // NewMouse creates a new Mouse bound to the given transport.
-func NewMouse(transport *SerialTransport) *Mouse {
-	return &Mouse{transport: transport}
+func NewMouse(transport Transport) *Mouse {
+	return &Mouse{transport: transport, cursor: defaultCursorProvider()}
+}
+
+// checkButton returns ErrUnsupported for a button without commands: only
+// left through mouse5 have one, mouse6 to mouse8 are only reported.
+func checkButton(button MouseButton) error {
+	if button < 0 || button >= StandardButtons {
+		return NewUnsupportedError(fmt.Sprintf("unsupported button: %v", button))
+	}
+	return nil
+}
+
+// Capabilities returns what the firmware supports, as found by
+// DetectCapabilities. Before detection Resolved is false and no command is
+// refused.
+func (m *Mouse) Capabilities() Capabilities {
+	m.capsMu.RLock()
+	defer m.capsMu.RUnlock()
+	return m.caps
+}
+
+// DetectCapabilities queries the firmware version and looks it up in the
+// capability table. Capabilities are only Resolved, and commands only
+// gated, if the reply has a version number.
+func (m *Mouse) DetectCapabilities() (Capabilities, error) {
+	resp, err := m.GetFirmwareVersion()
+	if err != nil {
+		return m.Capabilities(), err
+	}
+	caps := capabilitiesFor(ParseFirmwareVersion(resp))
+	if f, ok := m.transport.(interface{ ButtonFraming() bool }); ok && f.ButtonFraming() {
+		caps.FramedButtons = true
+	}
+	m.capsMu.Lock()
+	defer m.capsMu.Unlock()
+	m.caps = caps
+	return caps, nil
+}
+
+// require returns ErrUnsupported if detected capabilities lack command.
+func (m *Mouse) require(command string) error {
+	caps := m.Capabilities()
+	if caps.Resolved && !caps.Supports(command) {
+		return NewUnsupportedError(fmt.Sprintf("%s not supported by firmware %v", command, caps.Firmware))
+	}
+	return nil
+}
+
+// checkSegments returns ErrUnsupported if the firmware cannot split a move
+// into that many segments.
+func (m *Mouse) checkSegments(segments int) error {
+	caps := m.Capabilities()
+	if caps.Resolved && segments > caps.MaxMoveSegments {
+		return NewUnsupportedError(fmt.Sprintf("%d move segments exceed the limit %d of firmware %v", segments, caps.MaxMoveSegments, caps.Firmware))
+	}
+	return nil
 }
 
 // Press sends a button-press command.
 func (m *Mouse) Press(button MouseButton) error {
-	if button < 0 || int(button) >= len(pressCommands) {
-		return NewCommandError(fmt.Sprintf("unsupported button: %v", button))
+	if err := checkButton(button); err != nil {
+		return err
 	}
 	_, err := m.transport.SendCommand(pressCommands[button], false, 0)
 	return err
@@ -64,8 +165,8 @@ func (m *Mouse) Press(button MouseButton) error {
 
 // Release sends a button-release command.
 func (m *Mouse) Release(button MouseButton) error {
-	if button < 0 || int(button) >= len(releaseCommands) {
-		return NewCommandError(fmt.Sprintf("unsupported button: %v", button))
+	if err := checkButton(button); err != nil {
+		return err
 	}
 	_, err := m.transport.SendCommand(releaseCommands[button], false, 0)
 	return err
@@ -81,26 +182,47 @@ func (m *Mouse) Click(button MouseButton) error {
 
 // Move sends a relative mouse movement.
 func (m *Mouse) Move(x, y int) error {
-	_, err := m.transport.SendCommand(fmt.Sprintf("km.move(%d,%d)", x, y), false, 0)
+	cmd, err := protocol.Move(x, y)
+	if err != nil {
+		return commandError(err)
+	}
+	_, err = m.transport.SendCommand(cmd.String(), false, 0)
 	return err
 }
 
 // MoveSmooth sends a segmented smooth relative movement.
 func (m *Mouse) MoveSmooth(x, y, segments int) error {
-	_, err := m.transport.SendCommand(fmt.Sprintf("km.move(%d,%d,%d)", x, y, segments), false, 0)
+	if err := m.checkSegments(segments); err != nil {
+		return err
+	}
+	cmd, err := protocol.MoveSmooth(x, y, segments)
+	if err != nil {
+		return commandError(err)
+	}
+	_, err = m.transport.SendCommand(cmd.String(), false, 0)
 	return err
 }
 
 // MoveBezier sends a bezier-curve relative movement with a control point.
 func (m *Mouse) MoveBezier(x, y, segments, ctrlX, ctrlY int) error {
-	_, err := m.transport.SendCommand(
-		fmt.Sprintf("km.move(%d,%d,%d,%d,%d)", x, y, segments, ctrlX, ctrlY), false, 0)
+	if err := m.checkSegments(segments); err != nil {
+		return err
+	}
+	cmd, err := protocol.MoveBezier(x, y, segments, ctrlX, ctrlY)
+	if err != nil {
+		return commandError(err)
+	}
+	_, err = m.transport.SendCommand(cmd.String(), false, 0)
 	return err
 }
 
 // Scroll sends a scroll wheel command (positive = up, negative = down).
 func (m *Mouse) Scroll(delta int) error {
-	_, err := m.transport.SendCommand(fmt.Sprintf("km.wheel(%d)", delta), false, 0)
+	cmd, err := protocol.Wheel(delta)
+	if err != nil {
+		return commandError(err)
+	}
+	_, err = m.transport.SendCommand(cmd.String(), false, 0)
 	return err
 }
 
@@ -111,7 +233,6 @@ func (m *Mouse) setLock(name string, lock bool) error {
 	if !ok {
 		return NewCommandError(fmt.Sprintf("unknown lock target: %s", name))
 	}
-
 	cmd := info.unlockCmd
 	if lock {
 		cmd = info.lockCmd
@@ -121,13 +242,7 @@ func (m *Mouse) setLock(name string, lock bool) error {
 	if err != nil {
 		return err
 	}
-
-	if lock {
-		m.lockStatesCache |= 1 << info.bit
-	} else {
-		m.lockStatesCache &= ^(1 << info.bit)
-	}
-	m.cacheValid = true
+	m.setLockStatus(name, lock, false)
 	return nil
 }
 
@@ -152,88 +267,126 @@ func (m *Mouse) LockX(lock bool) error { return m.setLock("X", lock) }
 // LockY locks/unlocks the Y axis.
 func (m *Mouse) LockY(lock bool) error { return m.setLock("Y", lock) }
 
-// IsLocked checks whether the given button is currently locked.
+// IsLocked checks whether the given button is currently locked, querying
+// the device unless its state is known and fresh.
 func (m *Mouse) IsLocked(button MouseButton) (bool, error) {
-	name := strings.ToUpper(button.String())
-	if name == "MOUSE4" || name == "MOUSE5" {
-		// already correct
+	if err := checkButton(button); err != nil {
+		return false, err
 	}
+	return m.lockState(strings.ToUpper(button.String()))
+}
+
+// GetAllLockStates returns the lock state of every supported button and
+// axis, querying the device for those not known and fresh. Targets that
+// could not be read are missing from the result and listed in a
+// *LockQueryError.
+func (m *Mouse) GetAllLockStates() (map[string]bool, error) {
+	return m.readLocks(false)
+}
+
+// --- catch methods ---
 
+// catchTarget returns the lock target name and commands for catching
+// button.
+func (m *Mouse) catchTarget(button MouseButton) (string, lockInfo, error) {
+	if err := checkButton(button); err != nil {
+		return "", lockInfo{}, err
+	}
+	name := strings.ToUpper(button.String())
 	info, ok := lockTargets[name]
-	if !ok {
-		return false, NewCommandError(fmt.Sprintf("unsupported lock target: %v", button))
+	if !ok || info.catchCmd == "" {
+		return "", lockInfo{}, NewCommandError(fmt.Sprintf("invalid catch target: %v", button))
 	}
-
-	if m.cacheValid {
-		return m.lockStatesCache&(1<<info.bit) != 0, nil
+	if err := m.require(info.catchCmd); err != nil {
+		return "", lockInfo{}, err
 	}
+	return name, info, nil
+}
 
-	resp, err := m.transport.SendCommand(info.queryCmd, true, 50*time.Millisecond)
+// SetCatch enables or disables catch for button. While the button is also
+// locked, the device counts its physical presses and its button reports
+// are marked as intercepted.
+func (m *Mouse) SetCatch(button MouseButton, enable bool) error {
+	name, info, err := m.catchTarget(button)
 	if err != nil {
-		return false, err
+		return err
 	}
-
-	locked := strings.TrimSpace(resp) == "1"
-	if locked {
-		m.lockStatesCache |= 1 << info.bit
-	} else {
-		m.lockStatesCache &= ^(1 << info.bit)
+	cmd := info.uncatchCmd
+	if enable {
+		cmd = info.catchCmd
+	}
+	if _, err := m.transport.SendCommand(cmd, false, 0); err != nil {
+		return err
 	}
-	return locked, nil
+	m.locks.mu.Lock()
+	defer m.locks.mu.Unlock()
+	m.syncLocks()
+	m.locks.caught[name] = enable
+	return nil
 }
 
-// GetAllLockStates returns the lock state of every button and axis.
-func (m *Mouse) GetAllLockStates() (map[string]bool, error) {
-	if m.cacheValid {
-		return map[string]bool{
-			"LEFT":   m.lockStatesCache&(1<<0) != 0,
-			"RIGHT":  m.lockStatesCache&(1<<1) != 0,
-			"MIDDLE": m.lockStatesCache&(1<<2) != 0,
-			"MOUSE4": m.lockStatesCache&(1<<3) != 0,
-			"MOUSE5": m.lockStatesCache&(1<<4) != 0,
-			"X":      m.lockStatesCache&(1<<5) != 0,
-			"Y":      m.lockStatesCache&(1<<6) != 0,
-		}, nil
-	}
-
-	states := make(map[string]bool, 7)
-	targets := []string{"LEFT", "RIGHT", "MIDDLE", "MOUSE4", "MOUSE5", "X", "Y"}
-
-	for _, name := range targets {
-		info := lockTargets[name]
-		resp, err := m.transport.SendCommand(info.queryCmd, true, 50*time.Millisecond)
-		if err != nil {
-			states[name] = false
-			continue
-		}
-		locked := strings.TrimSpace(resp) == "1"
-		states[name] = locked
-		if locked {
-			m.lockStatesCache |= 1 << info.bit
-		} else {
-			m.lockStatesCache &= ^(1 << info.bit)
-		}
-	}
+// IsCaught reports whether catch was enabled for button on the current
+// connection.
+func (m *Mouse) IsCaught(button MouseButton) bool {
+	m.locks.mu.Lock()
+	defer m.locks.mu.Unlock()
+	m.syncLocks()
+	return m.locks.caught[strings.ToUpper(button.String())]
+}
 
-	m.cacheValid = true
-	return states, nil
+// Intercepting reports whether button is both locked and caught, so its
+// physical presses are kept from the host. It only consults the cache: the
+// lock state must be known (set or read back with IsLocked) and catch
+// enabled through SetCatch on the current connection, as the device has no
+// query for the catch state. It never sends a command.
+func (m *Mouse) Intercepting(button MouseButton) bool {
+	name := strings.ToUpper(button.String())
+	m.locks.mu.Lock()
+	defer m.locks.mu.Unlock()
+	m.syncLocks()
+	st := m.locks.status[name]
+	return m.locks.caught[name] && st.Known && st.Locked
+}
+
+// CatchCount queries how many presses of button the device caught since
+// the last query.
+func (m *Mouse) CatchCount(button MouseButton) (int, error) {
+	_, info, err := m.catchTarget(button)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := m.transport.SendCommand(info.catchQuery, true, 50*time.Millisecond)
+	if err != nil {
+		return 0, err
+	}
+	return ParseIntResponse(info.catchQuery, resp)
 }
 
 // SpoofSerial sets a custom serial number on the device.
 func (m *Mouse) SpoofSerial(serial string) error {
-	_, err := m.transport.SendCommand(fmt.Sprintf("km.serial('%s')", serial), false, 0)
+	if err := m.require("serial"); err != nil {
+		return err
+	}
+	cmd, err := protocol.Serial(serial)
+	if err != nil {
+		return commandError(err)
+	}
+	_, err = m.transport.SendCommand(cmd.String(), false, 0)
 	return err
 }
 
 // ResetSerial resets the device serial number to factory default.
 func (m *Mouse) ResetSerial() error {
-	_, err := m.transport.SendCommand("km.serial(0)", false, 0)
+	if err := m.require("serial"); err != nil {
+		return err
+	}
+	_, err := m.transport.SendCommand(protocol.ResetSerial().String(), false, 0)
 	return err
 }
 
 // GetDeviceInfo returns information about the connected device and its COM port.
 func (m *Mouse) GetDeviceInfo() DeviceInfo {
-	port := m.transport.Port
+	port := m.transport.PortName()
 	connected := m.transport.IsConnected()
 
 	if !connected || port == "" {
@@ -275,14 +428,13 @@ func (m *Mouse) GetDeviceInfo() DeviceInfo {
 
 // GetFirmwareVersion queries the device for its firmware version string.
 func (m *Mouse) GetFirmwareVersion() (string, error) {
-	resp, err := m.transport.SendCommand("km.version()", true, 100*time.Millisecond)
+	query := protocol.Version().String()
+	resp, err := m.transport.SendCommand(query, true, 100*time.Millisecond)
 	if err != nil {
 		return "", err
 	}
-	return resp, nil
-}
-
-// InvalidateCache marks the lock-state cache as stale.
-func (m *Mouse) InvalidateCache() {
-	m.cacheValid = false
+	if _, err := ParseVersionResponse(query, resp); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(resp), nil
 }
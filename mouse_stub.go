@@ -4,8 +4,14 @@ package Macku
 
 import "errors"
 
-// MoveAbs is only supported on Windows where GetCursorPos and
-// SystemParametersInfoW are available.
-func (m *Mouse) MoveAbs(target [2]int, speed int, waitMs int) error {
-	return errors.New("MoveAbs is only supported on Windows")
+// defaultCursorProvider returns nil: there is no portable way to read the
+// system cursor, so callers must supply one via SetCursorProvider.
+func defaultCursorProvider() CursorProvider {
+	return nil
+}
+
+// getMouseSpeedMultiplier is only implemented on Windows, where
+// SystemParametersInfoW exposes the pointer-speed setting.
+func getMouseSpeedMultiplier() (float64, error) {
+	return 0, errors.New("pointer speed is only available on Windows")
 }
@@ -5,7 +5,6 @@ package Macku
 import (
 	"fmt"
 	"syscall"
-	"time"
 	"unsafe"
 )
 
@@ -44,38 +43,14 @@ func getMouseSpeedMultiplier() (float64, error) {
 	return float64(speed) / 10.0, nil
 }
 
-// MoveAbs moves the mouse cursor to an absolute screen position by issuing
-// incremental relative moves, compensating for the Windows pointer-speed setting.
-// Speed is clamped to 1–14. This function is only available on Windows.
-func (m *Mouse) MoveAbs(target [2]int, speed int, waitMs int) error {
-	multiplier, err := getMouseSpeedMultiplier()
-	if err != nil {
-		return err
-	}
-
-	endX, endY := target[0], target[1]
-	speed = clamp(speed, 1, 14)
-
-	for {
-		cx, cy, err := getCursorPos()
-		if err != nil {
-			return err
-		}
-
-		dx, dy := endX-cx, endY-cy
-		if absInt(dx) <= 1 && absInt(dy) <= 1 {
-			break
-		}
+// systemCursor reads the Windows cursor position via GetCursorPos.
+type systemCursor struct{}
 
-		moveX := clamp(int(float64(dx)/multiplier), -speed, speed)
-		moveY := clamp(int(float64(dy)/multiplier), -speed, speed)
-
-		_, err = m.transport.SendCommand(fmt.Sprintf("km.move(%d,%d)", moveX, moveY), false, 0)
-		if err != nil {
-			return err
-		}
-		time.Sleep(time.Duration(waitMs) * time.Millisecond)
-	}
+func (systemCursor) CursorPos() (int, int, error) {
+	return getCursorPos()
+}
 
-	return nil
+// defaultCursorProvider returns the Windows system cursor.
+func defaultCursorProvider() CursorProvider {
+	return systemCursor{}
 }
@@ -0,0 +1,232 @@
+package Macku
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// CursorProvider reports the current on-screen cursor position. MoveAbs uses
+// it to close the loop between relative device moves and the actual cursor.
+type CursorProvider interface {
+	CursorPos() (x, y int, err error)
+}
+
+// MoveAbsOptions configures the closed-loop absolute positioning controller.
+type MoveAbsOptions struct {
+	Tolerance     int               // Max per-axis distance (px) considered on target
+	MaxStep       int               // Max device counts per axis per iteration
+	Gain          float64           // Initial device counts per pixel of error (ignored when Model is set; 0 = the Mouse's model, else 1)
+	Model         AccelerationModel // Pointer acceleration model (nil = linear from Gain)
+	MaxIterations int               // Give up after this many moves (0 = unlimited)
+	Timeout       time.Duration     // Give up after this long (0 = unlimited)
+	StepDelay     time.Duration     // Pause after each move before re-reading the cursor
+	StallLimit    int               // Consecutive moves without cursor progress before giving up
+}
+
+// DefaultMoveAbsOptions returns MoveAbsOptions with bounded iterations and time.
+func DefaultMoveAbsOptions() MoveAbsOptions {
+	return MoveAbsOptions{
+		Tolerance:     1,
+		MaxStep:       14,
+		MaxIterations: 1000,
+		Timeout:       5 * time.Second,
+		StepDelay:     time.Millisecond,
+		StallLimit:    10,
+	}
+}
+
+// MoveAbsResult describes the outcome of a closed-loop absolute move.
+type MoveAbsResult struct {
+	Iterations int           // Number of relative moves sent
+	ErrorX     int           // Final X distance to target (px)
+	ErrorY     int           // Final Y distance to target (px)
+	Elapsed    time.Duration // Total time spent
+	Converged  bool          // True if the cursor ended within tolerance
+}
+
+const (
+	minMoveAbsCorrection = 0.01
+	maxMoveAbsCorrection = 100.0
+	overshootDamping     = 0.5
+	stallBoost           = 1.5
+	correctionBlend      = 0.5
+)
+
+// axisController holds the adaptive state for one axis. The acceleration model
+// converts pixel error into counts; correction scales the requested
+// displacement to absorb whatever the model gets wrong.
+type axisController struct {
+	name       string
+	model      AccelerationModel
+	correction float64
+	sent       int // counts sent on the previous iteration
+	lastErr    int // error observed before the previous move
+	stalls     int
+}
+
+// step returns the device counts to send for the given error, clamped to maxStep.
+// Any error outside tolerance yields at least one count so the loop always
+// makes progress even when the model predicts a sub-count move.
+func (a *axisController) step(errPx, tolerance, maxStep int) int {
+	if absInt(errPx) <= tolerance {
+		return 0
+	}
+	counts := int(math.Round(a.model.Counts(float64(errPx) * a.correction)))
+	if counts == 0 {
+		if errPx > 0 {
+			counts = 1
+		} else {
+			counts = -1
+		}
+	}
+	return clamp(counts, -maxStep, maxStep)
+}
+
+// observe adapts the correction from the cursor response to the previous move
+// and reports whether the axis has stalled for stallLimit iterations.
+func (a *axisController) observe(errPx, tolerance, stallLimit int) bool {
+	if a.sent == 0 {
+		a.stalls = 0
+		return false
+	}
+
+	moved := a.lastErr - errPx
+	switch {
+	case errPx != 0 && absInt(errPx) > tolerance && (errPx > 0) != (a.lastErr > 0):
+		// Overshoot: the error changed sign, so the step is too large.
+		a.correction *= overshootDamping
+		a.stalls = 0
+	case moved == 0 || (moved > 0) != (a.sent > 0):
+		// No progress (sub-pixel step or locked axis): push harder.
+		a.correction *= stallBoost
+		a.stalls++
+	default:
+		predicted := a.model.Displacement(float64(a.sent))
+		measured := predicted / float64(moved)
+		a.correction += (measured - a.correction) * correctionBlend
+		a.stalls = 0
+	}
+
+	a.correction = math.Max(minMoveAbsCorrection, math.Min(maxMoveAbsCorrection, a.correction))
+	return stallLimit > 0 && a.stalls >= stallLimit
+}
+
+// MoveToPosition drives the cursor reported by cursor towards target by
+// issuing relative moves through move. Each iteration converts the remaining
+// error into counts through opts.Model and a correction factor that adapts to
+// the observed cursor response, halving on overshoot. The loop stops when both
+// axes are within opts.Tolerance, or returns a timeout error once
+// MaxIterations, Timeout or StallLimit is hit.
+func MoveToPosition(cursor CursorProvider, move func(dx, dy int) error, target [2]int, opts MoveAbsOptions) (MoveAbsResult, error) {
+	if opts.MaxStep <= 0 {
+		opts.MaxStep = 1
+	}
+	model := opts.Model
+	if model == nil {
+		gain := opts.Gain
+		if gain <= 0 {
+			gain = 1.0
+		}
+		model = LinearAcceleration{Multiplier: 1 / gain}
+	}
+
+	start := time.Now()
+	ax := &axisController{name: "X", model: model, correction: 1}
+	ay := &axisController{name: "Y", model: model, correction: 1}
+	var result MoveAbsResult
+
+	for {
+		cx, cy, err := cursor.CursorPos()
+		if err != nil {
+			result.Elapsed = time.Since(start)
+			return result, err
+		}
+
+		ex, ey := target[0]-cx, target[1]-cy
+		result.ErrorX, result.ErrorY = ex, ey
+		result.Elapsed = time.Since(start)
+
+		if absInt(ex) <= opts.Tolerance && absInt(ey) <= opts.Tolerance {
+			result.Converged = true
+			return result, nil
+		}
+
+		if opts.MaxIterations > 0 && result.Iterations >= opts.MaxIterations {
+			return result, NewTimeoutError(fmt.Sprintf(
+				"MoveAbs did not converge after %d iterations (error %d,%d)", result.Iterations, ex, ey))
+		}
+		if opts.Timeout > 0 && result.Elapsed >= opts.Timeout {
+			return result, NewTimeoutError(fmt.Sprintf(
+				"MoveAbs did not converge within %v (error %d,%d)", opts.Timeout, ex, ey))
+		}
+
+		for _, a := range []struct {
+			ctl *axisController
+			err int
+		}{{ax, ex}, {ay, ey}} {
+			if a.ctl.observe(a.err, opts.Tolerance, opts.StallLimit) {
+				return result, NewTimeoutError(fmt.Sprintf(
+					"MoveAbs stalled: %s axis did not respond after %d moves (error %d,%d)",
+					a.ctl.name, a.ctl.stalls, ex, ey))
+			}
+		}
+
+		mx := ax.step(ex, opts.Tolerance, opts.MaxStep)
+		my := ay.step(ey, opts.Tolerance, opts.MaxStep)
+		if err := move(mx, my); err != nil {
+			return result, err
+		}
+		result.Iterations++
+
+		ax.sent, ax.lastErr = mx, ex
+		ay.sent, ay.lastErr = my, ey
+
+		if opts.StepDelay > 0 {
+			time.Sleep(opts.StepDelay)
+		}
+	}
+}
+
+// SetCursorProvider sets the cursor source used by MoveAbs. On Windows the
+// system cursor is used by default; other platforms must supply one.
+func (m *Mouse) SetCursorProvider(cursor CursorProvider) {
+	m.cursor = cursor
+}
+
+// MoveAbs moves the mouse cursor to an absolute screen position by issuing
+// incremental relative moves, compensating for pointer acceleration.
+// Speed (max counts per step) is clamped to 1–14.
+func (m *Mouse) MoveAbs(target [2]int, speed int, waitMs int) error {
+	opts := DefaultMoveAbsOptions()
+	opts.MaxStep = clamp(speed, 1, 14)
+	opts.StepDelay = time.Duration(waitMs) * time.Millisecond
+	_, err := m.MoveAbsWithOptions(target, opts)
+	return err
+}
+
+// MoveAbsWithOptions runs the closed-loop absolute positioning controller
+// against the mouse's CursorProvider and returns the result. If neither
+// opts.Model nor opts.Gain is set, the model set with SetAccelerationModel
+// (or the platform default) is used.
+func (m *Mouse) MoveAbsWithOptions(target [2]int, opts MoveAbsOptions) (MoveAbsResult, error) {
+	if m.cursor == nil {
+		return MoveAbsResult{}, NewCommandError("MoveAbs requires a CursorProvider on this platform")
+	}
+	if opts.Model == nil && opts.Gain <= 0 {
+		opts.Model = m.accelerationModel()
+	}
+	return MoveToPosition(m.cursor, m.Move, target, opts)
+}
+
+// accelerationModel returns the configured model, falling back to a linear
+// model from the OS pointer-speed setting where available.
+func (m *Mouse) accelerationModel() AccelerationModel {
+	if m.accel != nil {
+		return m.accel
+	}
+	if multiplier, err := getMouseSpeedMultiplier(); err == nil && multiplier > 0 {
+		return LinearAcceleration{Multiplier: multiplier}
+	}
+	return nil
+}
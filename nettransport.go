@@ -0,0 +1,35 @@
+package Macku
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+// DefaultRelayPort is the TCP port a relay listens on by default.
+const DefaultRelayPort = 7712
+
+// netDialTimeout bounds the TCP connect to a relay.
+const netDialTimeout = 3 * time.Second
+
+// NetTransport speaks the raw Makcu byte stream over TCP to a relay serving
+// a device on another host. It is a SerialTransport underneath, so the
+// response parser, command correlation and button reports behave exactly as
+// on a local port.
+type NetTransport struct {
+	*SerialTransport
+	Addr string // relay address, host:port
+}
+
+// NewNetTransport creates (but does not connect) a transport to the relay at addr.
+func NewNetTransport(addr string, debug, sendInit, autoReconnect bool) *NetTransport {
+	t := &NetTransport{Addr: addr}
+	t.SerialTransport = NewStreamTransport("tcp://"+addr, func() (Port, error) {
+		conn, err := net.DialTimeout("tcp", addr, netDialTimeout)
+		if err != nil {
+			return nil, NewConnectionError(fmt.Sprintf("failed to reach relay %s: %v", addr, err))
+		}
+		return NewConnPort(conn), nil
+	}, debug, sendInit, autoReconnect)
+	return t
+}
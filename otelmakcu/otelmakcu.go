@@ -0,0 +1,64 @@
+// Package otelmakcu adapts an OpenTelemetry tracer to Macku.Tracer. It is a
+// separate module so the core library does not depend on OpenTelemetry.
+//
+//	c.SetTracer(otelmakcu.New(otel.Tracer("makcu")))
+package otelmakcu
+
+import (
+	"context"
+	"fmt"
+
+	Macku "github.com/Auchrio/Makcu-go-lib/v3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracer is a Macku.Tracer backed by an OpenTelemetry tracer.
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+// New returns a Macku.Tracer that starts its spans on t.
+func New(t trace.Tracer) *Tracer {
+	return &Tracer{tracer: t}
+}
+
+// Start begins an OpenTelemetry span named name under any span in ctx.
+func (t *Tracer) Start(ctx context.Context, name string) (context.Context, Macku.Span) {
+	ctx, span := t.tracer.Start(ctx, name)
+	return ctx, otelSpan{span}
+}
+
+type otelSpan struct {
+	span trace.Span
+}
+
+func (s otelSpan) SetAttribute(key string, value interface{}) {
+	s.span.SetAttributes(attributeOf(key, value))
+}
+
+func (s otelSpan) End(err error) {
+	if err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+	s.span.End()
+}
+
+func attributeOf(key string, value interface{}) attribute.KeyValue {
+	switch v := value.(type) {
+	case string:
+		return attribute.String(key, v)
+	case int:
+		return attribute.Int(key, v)
+	case int64:
+		return attribute.Int64(key, v)
+	case bool:
+		return attribute.Bool(key, v)
+	case float64:
+		return attribute.Float64(key, v)
+	default:
+		return attribute.String(key, fmt.Sprint(v))
+	}
+}
@@ -0,0 +1,75 @@
+package otelmakcu_test
+
+import (
+	"testing"
+
+	"github.com/Auchrio/Makcu-go-lib/otelmakcu"
+	Macku "github.com/Auchrio/Makcu-go-lib/v3"
+	"github.com/Auchrio/Makcu-go-lib/v3/emulator"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSpansExported(t *testing.T) {
+	exp := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp))
+	defer tp.Shutdown(t.Context())
+
+	dev := emulator.New()
+	c := Macku.NewControllerWithTransport(Macku.NewStreamTransport("emulator", dev.Open, false, true, false))
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Disconnect()
+	c.SetTracer(otelmakcu.New(tp.Tracer("makcu")))
+
+	if err := c.Click(Macku.MouseButtonMiddle); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetAllLockStates(); err != nil {
+		t.Fatal(err)
+	}
+	c.Disconnect()
+	if err := c.Click(Macku.MouseButtonLeft); err == nil {
+		t.Fatal("Click after Disconnect succeeded")
+	}
+
+	spans := exp.GetSpans()
+	byName := map[string][]tracetest.SpanStub{}
+	for _, s := range spans {
+		byName[s.Name] = append(byName[s.Name], s)
+	}
+	clicks, locks, cmds := byName["makcu.Click"], byName["makcu.GetAllLockStates"], byName[Macku.SpanCommand]
+	if len(clicks) != 2 || len(locks) != 1 || len(cmds) != 9 {
+		t.Fatalf("spans: %d Click, %d GetAllLockStates, %d commands", len(clicks), len(locks), len(cmds))
+	}
+	for i, cmd := range cmds {
+		parent := clicks[0]
+		if i >= 2 {
+			parent = locks[0]
+		}
+		if cmd.Parent.SpanID() != parent.SpanContext.SpanID() || cmd.SpanContext.TraceID() != parent.SpanContext.TraceID() {
+			t.Errorf("command span %d is not a child of %s", i, parent.Name)
+		}
+	}
+	if !hasAttr(cmds[2].Attributes, attribute.Int(Macku.AttrCommandID, 1)) ||
+		!hasAttr(cmds[2].Attributes, attribute.String(Macku.AttrCommandText, "km.lock_ml()")) {
+		t.Errorf("first lock query attributes = %v", cmds[2].Attributes)
+	}
+
+	failed := clicks[1]
+	if failed.Status.Code != codes.Error || !hasAttr(failed.Attributes, attribute.String(Macku.AttrErrorClass, "connection")) {
+		t.Errorf("failed Click span: status %v, attributes %v", failed.Status, failed.Attributes)
+	}
+}
+
+func hasAttr(attrs []attribute.KeyValue, want attribute.KeyValue) bool {
+	for _, a := range attrs {
+		if a == want {
+			return true
+		}
+	}
+	return false
+}
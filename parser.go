@@ -0,0 +1,198 @@
+package Macku
+
+// StreamParser splits the device byte stream into text lines and
+// button-mask bytes. The protocol distinguishes printable text lines
+// (terminated by CR+LF, or a bare LF after text) from raw button data
+// (bytes < 32). A bare LF is ambiguous — 0x0A is also right+mouse4 — and is
+// resolved from the surrounding bytes and the last reported mask; CR and TAB
+// outside a line are button data. Only a CR mask directly followed by an LF
+// mask cannot be told from a line terminator.
+//
+// Devices that support framed reports (km.buttons(2)) send each mask as
+// ButtonFramePrefix followed by the mask byte instead. Once the host has
+// asked for them (AcceptFrames), the first frame switches the parser to
+// framed mode, where CR/LF only ever end lines and stray control bytes are
+// dropped, so every mask decodes unambiguously. Before that a prefix byte
+// is line noise and is dropped.
+//
+// StreamParser is the listener's parser with no I/O attached, so captures,
+// tools and tests can run device output through the same rules. It decodes
+// three inputs differently from the original listen() loop: a TAB before
+// any text is a mask (0x09) rather than the start of a line, a CR followed
+// by text is a mask (0x0D) rather than dropped, and a stray
+// ButtonFramePrefix is dropped rather than kept as text.
+type StreamParser struct {
+	line     []byte
+	textMode bool
+	last     int  // previous byte, -1 before the first
+	mask     int  // last button mask reported
+	accept   bool // km.buttons(2) was sent; a prefix starts a frame
+	framed   bool // a frame has been seen; heuristics are off
+	inFrame  bool // the next byte is a framed mask
+
+	onLine   func(line []byte) // raw line without terminator; reused after return
+	onButton func(mask int)
+}
+
+// ButtonFramePrefix starts a framed button report; the next byte is the
+// mask. It never occurs in the device's ASCII text.
+const ButtonFramePrefix = 0xFE
+
+// maxLineLength bounds a text line; longer lines are truncated.
+const maxLineLength = 256
+
+// NewStreamParser returns a parser that calls onLine for each text line and
+// onButton for each button-mask byte. The line passed to onLine is only
+// valid until it returns.
+func NewStreamParser(onLine func(line []byte), onButton func(mask int)) *StreamParser {
+	return &StreamParser{
+		line:     make([]byte, 0, maxLineLength),
+		last:     -1,
+		onLine:   onLine,
+		onButton: onButton,
+	}
+}
+
+// SetMask sets the last reported mask, e.g. to carry button state over
+// from a previous connection.
+func (p *StreamParser) SetMask(mask int) {
+	p.mask = mask
+}
+
+// SetFramed switches between framed and heuristic decoding, e.g. back to
+// heuristic after reconnecting to a device that may not frame its reports.
+// A partial line is discarded.
+func (p *StreamParser) SetFramed(framed bool) {
+	p.framed = framed
+	p.inFrame = false
+	p.textMode = false
+	p.last = -1
+	p.line = p.line[:0]
+}
+
+// AcceptFrames sets whether a ButtonFramePrefix starts a frame, i.e.
+// whether the host has asked the device for framed reports. Without it a
+// prefix byte is dropped and the parser stays heuristic.
+func (p *StreamParser) AcceptFrames(accept bool) {
+	p.accept = accept
+}
+
+// Framed reports whether the parser is decoding framed button reports.
+func (p *StreamParser) Framed() bool {
+	return p.framed
+}
+
+// Write feeds data to the parser. It never fails.
+func (p *StreamParser) Write(data []byte) (int, error) {
+	for _, b := range data {
+		p.Feed(b)
+	}
+	return len(data), nil
+}
+
+// Feed consumes one byte from the device.
+func (p *StreamParser) Feed(v byte) {
+	switch {
+	case p.inFrame:
+		p.inFrame = false
+		p.mask = int(v)
+		p.onButton(p.mask)
+		return
+	case v == ButtonFramePrefix:
+		if p.accept || p.framed {
+			p.framed = true
+			p.inFrame = true
+		}
+		return
+	case p.framed:
+		p.feedFramed(v)
+		return
+	}
+
+	b := int(v)
+
+	switch {
+	// Case 1: CR+LF — complete a text line.
+	case p.last == 0x0D && b == 0x0A:
+		p.flushLine()
+		p.textMode = false
+
+	// Case 2: printable ASCII, or TAB within a line — accumulate text.
+	case b >= 32 || b == 0x09 && (p.textMode || len(p.line) > 0):
+		if p.last == 0x0D && !p.textMode {
+			// A CR outside text is button data (0x0D = left+middle+mouse4).
+			p.button(0x0D)
+		}
+		p.textMode = true
+		if len(p.line) < maxLineLength {
+			p.line = append(p.line, v)
+		}
+
+	// Case 3: CR — may be start of CRLF.
+	case b == 0x0D:
+		if p.textMode || len(p.line) > 0 {
+			p.textMode = true
+		}
+
+	// Case 4: bare LF — disambiguate between text terminator and button data (0x0A = right+mouse4).
+	case b == 0x0A:
+		if p.mask != 0 ||
+			(p.last >= 0 && p.last < 32 && p.last != 0x0D) ||
+			(len(p.line) > 0 && !p.textMode) {
+			p.button(b)
+			break
+		}
+		switch {
+		case p.last == 0x0D:
+			// Completing CRLF
+			p.flushLine()
+		case len(p.line) > 0 && p.textMode:
+			// LF-only line end
+			p.flushLine()
+		case p.textMode:
+		default:
+			p.button(b)
+		}
+		p.textMode = false
+
+	// Case 5: other control bytes (< 32, excluding CR/LF) — button data.
+	// TAB before any text is 0x09 = left+mouse4.
+	default:
+		if p.last == 0x0D {
+			p.button(0x0D)
+		}
+		p.button(b)
+	}
+
+	p.last = b
+}
+
+// feedFramed consumes a byte outside a frame in framed mode. A frame can
+// arrive mid-line without disturbing the line.
+func (p *StreamParser) feedFramed(v byte) {
+	switch {
+	case v == 0x0A:
+		p.flushLine()
+	case v >= 32 || v == 0x09:
+		if len(p.line) < maxLineLength {
+			p.line = append(p.line, v)
+		}
+	}
+	// Other control bytes, CR included, carry nothing in framed mode.
+}
+
+func (p *StreamParser) flushLine() {
+	if len(p.line) > 0 {
+		line := p.line
+		p.line = p.line[:0]
+		p.onLine(line)
+	}
+}
+
+// button reports a mask byte and discards any partial line.
+func (p *StreamParser) button(mask int) {
+	p.onButton(mask)
+	p.mask = mask
+	p.textMode = false
+	p.line = p.line[:0]
+}
@@ -0,0 +1,104 @@
+package Macku
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// Port is the raw byte stream under a SerialTransport: a serial port, a TCP
+// connection to a relay, or an in-memory device emulator. Read must return
+// (0, nil) when the read timeout expires without data.
+type Port interface {
+	Read(p []byte) (int, error)
+	Write(p []byte) (int, error)
+	Close() error
+	SetReadTimeout(t time.Duration) error
+}
+
+// OpenSerialPort opens a serial port at 115200 baud and switches the device
+// to 4 000 000 baud, returning the port ready for the Makcu protocol.
+func OpenSerialPort(name string) (Port, error) {
+	return openSerial(name, func(string, ...interface{}) {})
+}
+
+func openSerial(name string, logf func(string, ...interface{})) (Port, error) {
+	sp, err := serial.Open(name, &serial.Mode{
+		BaudRate: 115200,
+		DataBits: 8,
+		StopBits: serial.OneStopBit,
+		Parity:   serial.NoParity,
+	})
+	if err != nil {
+		return nil, NewConnectionError(fmt.Sprintf("failed to open %s: %v", name, err))
+	}
+	if err := changeBaudTo4M(sp, logf); err != nil {
+		sp.Close()
+		return nil, NewConnectionError(fmt.Sprintf("failed to switch to 4M baud: %v", err))
+	}
+	return sp, nil
+}
+
+// changeBaudTo4M sends the baud-change magic bytes and switches to 4 000 000 baud.
+func changeBaudTo4M(sp serial.Port, logf func(string, ...interface{})) error {
+	logf("Changing baud rate to 4M")
+
+	if _, err := sp.Write(baudChangeCommand); err != nil {
+		return err
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	err := sp.SetMode(&serial.Mode{
+		BaudRate: 4000000,
+		DataBits: 8,
+		StopBits: serial.OneStopBit,
+		Parity:   serial.NoParity,
+	})
+	if err != nil {
+		return err
+	}
+
+	logf("Baud rate changed: 115200 -> 4000000")
+	return nil
+}
+
+// connPort adapts a net.Conn to Port, mapping the read timeout onto read
+// deadlines.
+type connPort struct {
+	conn    net.Conn
+	timeout time.Duration
+}
+
+// NewConnPort wraps a stream connection (TCP, net.Pipe, ...) as a Port.
+func NewConnPort(conn net.Conn) Port {
+	return &connPort{conn: conn}
+}
+
+func (p *connPort) Read(b []byte) (int, error) {
+	if p.timeout > 0 {
+		p.conn.SetReadDeadline(time.Now().Add(p.timeout))
+	}
+	n, err := p.conn.Read(b)
+	var ne net.Error
+	if err != nil && errors.As(err, &ne) && ne.Timeout() {
+		return n, nil
+	}
+	return n, err
+}
+
+func (p *connPort) Write(b []byte) (int, error) {
+	return p.conn.Write(b)
+}
+
+func (p *connPort) Close() error {
+	return p.conn.Close()
+}
+
+func (p *connPort) SetReadTimeout(t time.Duration) error {
+	p.timeout = t
+	return nil
+}
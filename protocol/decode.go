@@ -0,0 +1,98 @@
+package protocol
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Decode parses a command line as Encode writes it, returning the command
+// and its tag, or NoTag. Surrounding whitespace and the line ending are
+// ignored, as are spaces around arguments.
+func Decode(line string) (Command, int, error) {
+	line = strings.TrimSpace(line)
+	if len(line) > MaxLineLength {
+		return Command{}, NoTag, syntaxError(line, "longer than %d bytes", MaxLineLength)
+	}
+	tag := NoTag
+	if i := strings.LastIndexByte(line, '#'); i > strings.LastIndexByte(line, ')') {
+		n, err := strconv.Atoi(line[i+1:])
+		if err != nil || n < 0 {
+			return Command{}, NoTag, syntaxError(line, "bad tag %q", line[i+1:])
+		}
+		tag, line = n, strings.TrimSpace(line[:i])
+	}
+
+	rest, ok := strings.CutPrefix(line, "km.")
+	if !ok {
+		return Command{}, NoTag, syntaxError(line, "missing km. prefix")
+	}
+	open := strings.IndexByte(rest, '(')
+	if open < 1 || !strings.HasSuffix(rest, ")") {
+		return Command{}, NoTag, syntaxError(line, "expected km.name(...)")
+	}
+	c := Command{Name: rest[:open]}
+	for _, r := range c.Name {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return Command{}, NoTag, syntaxError(line, "bad command name %q", c.Name)
+		}
+	}
+
+	args := rest[open+1 : len(rest)-1]
+	if strings.TrimSpace(args) == "" {
+		return c, tag, nil
+	}
+	for {
+		a, n, err := decodeArg(args)
+		if err != nil {
+			return Command{}, NoTag, syntaxError(line, "%s", err)
+		}
+		c.Args = append(c.Args, a)
+		args = strings.TrimLeft(args[n:], " ")
+		if args == "" {
+			return c, tag, nil
+		}
+		if args[0] != ',' {
+			return Command{}, NoTag, syntaxError(line, "expected , before %q", args)
+		}
+		args = args[1:]
+	}
+}
+
+// decodeArg parses the argument at the start of s, returning it and the
+// number of bytes consumed.
+func decodeArg(s string) (Arg, int, error) {
+	start := len(s) - len(strings.TrimLeft(s, " "))
+	s = s[start:]
+	if s == "" {
+		return Arg{}, 0, fmt.Errorf("missing argument")
+	}
+	if q := s[0]; q == '\'' || q == '"' {
+		var b strings.Builder
+		for i := 1; i < len(s); i++ {
+			switch c := s[i]; {
+			case c == q:
+				return Str(b.String()), start + i + 1, nil
+			case c == '\\' && i+1 < len(s):
+				i++
+				b.WriteByte(s[i])
+			default:
+				b.WriteByte(c)
+			}
+		}
+		return Arg{}, 0, fmt.Errorf("unterminated string")
+	}
+	end := strings.IndexAny(s, ", ")
+	if end < 0 {
+		end = len(s)
+	}
+	n, err := strconv.Atoi(s[:end])
+	if err != nil {
+		return Arg{}, 0, fmt.Errorf("bad argument %q", s[:end])
+	}
+	return Int(n), start + end, nil
+}
+
+func syntaxError(line, format string, args ...any) error {
+	return fmt.Errorf("%w: %q: %s", ErrSyntax, line, fmt.Sprintf(format, args...))
+}
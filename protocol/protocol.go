@@ -0,0 +1,304 @@
+// Package protocol defines the MAKCU km.* command syntax shared by the
+// client and the emulator: typed constructors that validate their
+// arguments, an encoder to the bytes written to the device, and a decoder
+// for the same syntax.
+//
+//	cmd, err := protocol.Move(10, -4) // km.move(10,-4)
+//	port.Write(protocol.Encode(cmd, protocol.NoTag))
+//
+// A command line is "km.name(arg,...)", optionally followed by "#tag" to
+// match a reply to its query, and ended by CR LF. Arguments are decimal
+// integers or single-quoted strings in which \ and ' are escaped with a
+// backslash.
+package protocol
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalid is returned for a command argument out of range.
+	ErrInvalid = errors.New("protocol: invalid argument")
+	// ErrSyntax is returned for a line that is not a well-formed command.
+	ErrSyntax = errors.New("protocol: syntax error")
+)
+
+// Argument limits.
+const (
+	MinMove         = math.MinInt16 // per-axis movement and bezier control points
+	MaxMove         = math.MaxInt16
+	MaxSegments     = math.MaxUint16 // segments of a smooth or bezier move
+	MinWheel        = math.MinInt8
+	MaxWheel        = math.MaxInt8
+	MaxSerialLength = 32
+	MaxLineLength   = 256 // longest command line the firmware reads
+)
+
+// NoTag marks a command line without a "#tag".
+const NoTag = -1
+
+// ButtonNames are the km.* button commands in mask-bit order. Mask bits
+// 5-7 have no documented command.
+var ButtonNames = [5]string{"left", "right", "middle", "ms1", "ms2"}
+
+// LockNames are the km.lock_* targets: the buttons, then the axes.
+var LockNames = [7]string{"ml", "mr", "mm", "ms1", "ms2", "mx", "my"}
+
+// Arg is one command argument: an integer, or a string if Quoted.
+type Arg struct {
+	Int    int
+	Str    string
+	Quoted bool
+}
+
+// Int returns an integer argument.
+func Int(n int) Arg { return Arg{Int: n} }
+
+// Str returns a string argument.
+func Str(s string) Arg { return Arg{Str: s, Quoted: true} }
+
+func (a Arg) String() string {
+	if !a.Quoted {
+		return strconv.Itoa(a.Int)
+	}
+	var b strings.Builder
+	b.WriteByte('\'')
+	for i := 0; i < len(a.Str); i++ {
+		if c := a.Str[i]; c == '\\' || c == '\'' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(a.Str[i])
+	}
+	b.WriteByte('\'')
+	return b.String()
+}
+
+// Command is one km.* call. A query is a command without arguments.
+type Command struct {
+	Name string // without "km.", e.g. "move"
+	Args []Arg
+}
+
+// String returns the command as sent, without tag or line ending, e.g.
+// "km.move(10,-4)".
+func (c Command) String() string {
+	var b strings.Builder
+	b.WriteString("km.")
+	b.WriteString(c.Name)
+	b.WriteByte('(')
+	for i, a := range c.Args {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(a.String())
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+// Ints returns the integer arguments, skipping strings.
+func (c Command) Ints() []int {
+	var n []int
+	for _, a := range c.Args {
+		if !a.Quoted {
+			n = append(n, a.Int)
+		}
+	}
+	return n
+}
+
+// Encode returns the line that sends c, with "#tag" unless tag is NoTag.
+func Encode(c Command, tag int) []byte {
+	return EncodeLine(c.String(), tag)
+}
+
+// EncodeLine returns the line that sends a command already in text form.
+func EncodeLine(command string, tag int) []byte {
+	b := make([]byte, 0, len(command)+8)
+	b = append(b, command...)
+	if tag != NoTag {
+		b = append(b, '#')
+		b = strconv.AppendInt(b, int64(tag), 10)
+	}
+	return append(b, '\r', '\n')
+}
+
+func call(name string, args ...int) Command {
+	c := Command{Name: name, Args: make([]Arg, len(args))}
+	for i, n := range args {
+		c.Args[i] = Int(n)
+	}
+	return c
+}
+
+func checkRange(what string, n, lo, hi int) error {
+	if n < lo || n > hi {
+		return fmt.Errorf("%w: %s %d out of range %d..%d", ErrInvalid, what, n, lo, hi)
+	}
+	return nil
+}
+
+func flag(on bool) int {
+	if on {
+		return 1
+	}
+	return 0
+}
+
+// Move returns km.move(x,y), a relative movement.
+func Move(x, y int) (Command, error) {
+	if err := errors.Join(checkRange("x", x, MinMove, MaxMove), checkRange("y", y, MinMove, MaxMove)); err != nil {
+		return Command{}, err
+	}
+	return call("move", x, y), nil
+}
+
+// MoveSmooth returns km.move(x,y,segments), a movement split into segments.
+func MoveSmooth(x, y, segments int) (Command, error) {
+	if _, err := Move(x, y); err != nil {
+		return Command{}, err
+	}
+	if err := checkRange("segments", segments, 1, MaxSegments); err != nil {
+		return Command{}, err
+	}
+	return call("move", x, y, segments), nil
+}
+
+// MoveBezier returns km.move(x,y,segments,cx,cy), a movement along a
+// curve through the control point (cx, cy).
+func MoveBezier(x, y, segments, cx, cy int) (Command, error) {
+	if _, err := MoveSmooth(x, y, segments); err != nil {
+		return Command{}, err
+	}
+	if err := errors.Join(checkRange("control x", cx, MinMove, MaxMove), checkRange("control y", cy, MinMove, MaxMove)); err != nil {
+		return Command{}, err
+	}
+	return call("move", x, y, segments, cx, cy), nil
+}
+
+// Wheel returns km.wheel(delta); positive scrolls up.
+func Wheel(delta int) (Command, error) {
+	if err := checkRange("wheel delta", delta, MinWheel, MaxWheel); err != nil {
+		return Command{}, err
+	}
+	return call("wheel", delta), nil
+}
+
+func buttonName(button int) (string, error) {
+	if err := checkRange("button", button, 0, len(ButtonNames)-1); err != nil {
+		return "", err
+	}
+	return ButtonNames[button], nil
+}
+
+// Button returns the command pressing or releasing button, numbered in
+// mask-bit order (0 is left, 4 is km.ms2).
+func Button(button int, down bool) (Command, error) {
+	name, err := buttonName(button)
+	if err != nil {
+		return Command{}, err
+	}
+	return call(name, flag(down)), nil
+}
+
+// ButtonQuery returns the query for whether button is held.
+func ButtonQuery(button int) (Command, error) {
+	name, err := buttonName(button)
+	if err != nil {
+		return Command{}, err
+	}
+	return call(name), nil
+}
+
+func lockName(target string) (string, error) {
+	for _, n := range LockNames {
+		if n == target {
+			return "lock_" + n, nil
+		}
+	}
+	return "", fmt.Errorf("%w: lock target %q", ErrInvalid, target)
+}
+
+// Lock returns the command locking or unlocking target, one of LockNames.
+func Lock(target string, lock bool) (Command, error) {
+	name, err := lockName(target)
+	if err != nil {
+		return Command{}, err
+	}
+	return call(name, flag(lock)), nil
+}
+
+// LockQuery returns the query for whether target is locked.
+func LockQuery(target string) (Command, error) {
+	name, err := lockName(target)
+	if err != nil {
+		return Command{}, err
+	}
+	return call(name), nil
+}
+
+func catchName(target string) (string, error) {
+	for _, n := range LockNames[:len(ButtonNames)] {
+		if n == target {
+			return "catch_" + n, nil
+		}
+	}
+	return "", fmt.Errorf("%w: catch target %q", ErrInvalid, target)
+}
+
+// Catch returns the command enabling or disabling catch for a button lock
+// target: while the button is locked, its physical presses are counted.
+func Catch(target string, enable bool) (Command, error) {
+	name, err := catchName(target)
+	if err != nil {
+		return Command{}, err
+	}
+	return call(name, flag(enable)), nil
+}
+
+// CatchQuery returns the query for how many presses were caught on target
+// since the last query.
+func CatchQuery(target string) (Command, error) {
+	name, err := catchName(target)
+	if err != nil {
+		return Command{}, err
+	}
+	return call(name), nil
+}
+
+// Buttons returns km.buttons(mode): 0 stops button reports, 1 sends raw
+// masks and 2 framed reports.
+func Buttons(mode int) (Command, error) {
+	if err := checkRange("buttons mode", mode, 0, 2); err != nil {
+		return Command{}, err
+	}
+	return call("buttons", mode), nil
+}
+
+// Version returns km.version().
+func Version() Command {
+	return call("version")
+}
+
+// Serial returns the command spoofing the device serial. It must be 1 to
+// MaxSerialLength printable ASCII characters.
+func Serial(serial string) (Command, error) {
+	if serial == "" || len(serial) > MaxSerialLength {
+		return Command{}, fmt.Errorf("%w: serial must be 1..%d characters, got %d", ErrInvalid, MaxSerialLength, len(serial))
+	}
+	for i := 0; i < len(serial); i++ {
+		if c := serial[i]; c < 0x20 || c > 0x7E {
+			return Command{}, fmt.Errorf("%w: serial has non-printable byte 0x%02X", ErrInvalid, c)
+		}
+	}
+	return Command{Name: "serial", Args: []Arg{Str(serial)}}, nil
+}
+
+// ResetSerial returns km.serial(0), restoring the factory serial.
+func ResetSerial() Command {
+	return call("serial", 0)
+}
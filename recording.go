@@ -0,0 +1,189 @@
+package Macku
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// RecordingVersion is the current Recording JSON format version.
+const RecordingVersion = 1
+
+// RecordedCommand is one command captured by a Recorder.
+type RecordedCommand struct {
+	At             time.Duration `json:"at_ns"` // Offset from the start of the recording
+	Command        string        `json:"command"`
+	ExpectResponse bool          `json:"expect_response,omitempty"`
+}
+
+// Recording is a timed sequence of commands that can be saved as JSON and
+// replayed through any controller.
+type Recording struct {
+	Version  int               `json:"version"`
+	Commands []RecordedCommand `json:"commands"`
+}
+
+// Duration returns the offset of the last recorded command.
+func (r *Recording) Duration() time.Duration {
+	if len(r.Commands) == 0 {
+		return 0
+	}
+	return r.Commands[len(r.Commands)-1].At
+}
+
+// Trim returns a copy containing only the commands between from and to
+// (inclusive), with offsets rebased so the first kept command is at zero.
+// A non-positive to means "until the end".
+func (r *Recording) Trim(from, to time.Duration) *Recording {
+	out := &Recording{Version: r.Version}
+	var base time.Duration
+	for _, cmd := range r.Commands {
+		if cmd.At < from || (to > 0 && cmd.At > to) {
+			continue
+		}
+		if len(out.Commands) == 0 {
+			base = cmd.At
+		}
+		cmd.At -= base
+		out.Commands = append(out.Commands, cmd)
+	}
+	return out
+}
+
+// Save writes the recording as indented JSON.
+func (r *Recording) Save(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// LoadRecording reads a recording written by Save.
+func LoadRecording(rd io.Reader) (*Recording, error) {
+	var rec Recording
+	if err := json.NewDecoder(rd).Decode(&rec); err != nil {
+		return nil, fmt.Errorf("failed to decode recording: %w", err)
+	}
+	if rec.Version != RecordingVersion {
+		return nil, fmt.Errorf("unsupported recording version: %d", rec.Version)
+	}
+	return &rec, nil
+}
+
+// Replay sends every recorded command through the controller's transport,
+// preserving the original spacing divided by speed (2 = twice as fast). A
+// speed of zero or less sends commands back-to-back. Replay stops at the
+// first error or when ctx is cancelled.
+func (r *Recording) Replay(ctx context.Context, c *MakcuController, speed float64) error {
+	if err := c.checkConnection(); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	for i, cmd := range r.Commands {
+		if speed > 0 {
+			due := time.Duration(float64(cmd.At) / speed)
+			if wait := due - time.Since(start); wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return ctx.Err()
+				case <-timer.C:
+				}
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if _, err := c.Transport.SendCommand(cmd.Command, cmd.ExpectResponse, 0); err != nil {
+			return fmt.Errorf("replay failed at command %d (%s): %w", i, cmd.Command, err)
+		}
+	}
+	return nil
+}
+
+// Recorder is a Transport that forwards to another Transport and captures
+// every command sent through it with its offset from the start of recording.
+type Recorder struct {
+	Transport
+
+	mu       sync.Mutex
+	start    time.Time
+	commands []RecordedCommand
+}
+
+// NewRecorder wraps inner and starts recording immediately.
+func NewRecorder(inner Transport) *Recorder {
+	return &Recorder{Transport: inner, start: time.Now()}
+}
+
+// SendCommand records the command and forwards it to the wrapped transport.
+// Commands the transport failed to write are not recorded; queries that
+// timed out are, since they reached the device.
+func (r *Recorder) SendCommand(command string, expectResponse bool, timeout time.Duration) (string, error) {
+	at := r.since()
+	resp, err := r.Transport.SendCommand(command, expectResponse, timeout)
+	if err == nil || errors.Is(err, ErrTimeout) {
+		r.record(RecordedCommand{At: at, Command: command, ExpectResponse: expectResponse})
+	}
+	return resp, err
+}
+
+// monitorCommander is implemented by transports that can tell which
+// km.buttons command EnableButtonMonitoring sends.
+type monitorCommander interface {
+	monitorCommand(enable bool) string
+}
+
+// EnableButtonMonitoring forwards to the wrapped transport, which keeps the
+// negotiated report format, and records the km.buttons command it sent.
+// Transports that cannot tell which command that is go unrecorded.
+func (r *Recorder) EnableButtonMonitoring(enable bool) error {
+	at := r.since()
+	mc, known := r.Transport.(monitorCommander)
+	cmd := ""
+	if known {
+		cmd = mc.monitorCommand(enable)
+	}
+	if err := r.Transport.EnableButtonMonitoring(enable); err != nil {
+		return err
+	}
+	if known {
+		r.record(RecordedCommand{At: at, Command: cmd})
+	}
+	return nil
+}
+
+func (r *Recorder) record(cmd RecordedCommand) {
+	r.mu.Lock()
+	r.commands = append(r.commands, cmd)
+	r.mu.Unlock()
+}
+
+// Reset discards captured commands and restarts the clock.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.start = time.Now()
+	r.commands = nil
+}
+
+// Recording returns a snapshot of the commands captured so far.
+func (r *Recorder) Recording() *Recording {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return &Recording{
+		Version:  RecordingVersion,
+		Commands: append([]RecordedCommand(nil), r.commands...),
+	}
+}
+
+func (r *Recorder) since() time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return time.Since(r.start)
+}
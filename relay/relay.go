@@ -0,0 +1,165 @@
+// Package relay exposes a local Makcu device on a TCP listener as a raw
+// byte stream, for Macku.NetTransport on another host. Nothing is parsed on
+// the relay: the remote SerialTransport sees the same bytes it would on a
+// local port.
+//
+// The relay serves one client at a time; a second client is refused until
+// the first disconnects. The device is opened when a client connects and
+// closed when it leaves, so the baud-change handshake runs per session.
+package relay
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	Macku "github.com/Auchrio/Makcu-go-lib/v3"
+)
+
+// readTimeout is how long a device read blocks before the pump re-checks
+// for shutdown.
+const readTimeout = 50 * time.Millisecond
+
+// Server relays bytes between a TCP client and a device port.
+type Server struct {
+	// Open opens the device for a new session, e.g.
+	//	func() (Macku.Port, error) { return Macku.OpenSerialPort("COM3") }
+	Open func() (Macku.Port, error)
+	Logf func(format string, args ...interface{}) // optional diagnostics
+
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	active    net.Conn
+	closed    bool
+	wg        sync.WaitGroup
+}
+
+// NewServer creates a Server that opens the device with open.
+func NewServer(open func() (Macku.Port, error)) *Server {
+	return &Server{Open: open, listeners: make(map[net.Listener]struct{})}
+}
+
+// ListenAndServe listens on addr and serves until Close.
+func (s *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts clients on l until Close, returning nil in that case.
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		l.Close()
+		return nil
+	}
+	s.listeners[l] = struct{}{}
+	s.mu.Unlock()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			delete(s.listeners, l)
+			s.mu.Unlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+		s.mu.Lock()
+		busy := s.active != nil || s.closed
+		if !busy {
+			s.active = conn
+			s.wg.Add(1)
+		}
+		s.mu.Unlock()
+		if busy {
+			s.logf("relay: refusing %s: device in use", conn.RemoteAddr())
+			conn.Close()
+			continue
+		}
+		go s.serve(conn)
+	}
+}
+
+// Close stops the listeners, ends the active session and waits for it to
+// release the device.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	for l := range s.listeners {
+		l.Close()
+	}
+	if s.active != nil {
+		s.active.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return nil
+}
+
+func (s *Server) logf(format string, args ...interface{}) {
+	if s.Logf != nil {
+		s.Logf(format, args...)
+	}
+}
+
+// serve pumps bytes both ways until either side fails.
+func (s *Server) serve(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		conn.Close()
+		s.mu.Lock()
+		s.active = nil
+		s.mu.Unlock()
+	}()
+
+	port, err := s.Open()
+	if err != nil {
+		s.logf("relay: %s: %v", conn.RemoteAddr(), err)
+		return
+	}
+	defer port.Close()
+	port.SetReadTimeout(readTimeout)
+	s.logf("relay: %s connected", conn.RemoteAddr())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		io.Copy(portWriter{port}, conn)
+	}()
+
+	buf := make([]byte, 4096)
+	for {
+		select {
+		case <-done:
+			s.logf("relay: %s disconnected", conn.RemoteAddr())
+			return
+		default:
+		}
+		n, err := port.Read(buf)
+		if n > 0 {
+			if _, werr := conn.Write(buf[:n]); werr != nil {
+				return
+			}
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				s.logf("relay: device read: %v", err)
+			}
+			return
+		}
+	}
+}
+
+// portWriter hides Port's other methods so io.Copy uses plain writes.
+type portWriter struct{ p Macku.Port }
+
+func (w portWriter) Write(b []byte) (int, error) { return w.p.Write(b) }
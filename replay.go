@@ -0,0 +1,275 @@
+package Macku
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ReplayTransport plays a captured device session back into the real
+// SerialTransport parser and checks that the library writes the same bytes
+// it wrote when the capture was taken. Device output is released in
+// capture order, never before the host writes that preceded it, and spaced
+// by the original gaps (scaled by SetSpeed). Use it to reproduce field reports
+// from a capture in go test:
+//
+//	t := Macku.NewReplayTransport(capture)
+//	c := Macku.NewControllerWithTransport(t)
+//	c.Connect()
+//	... drive c as the original program did ...
+//	c.Disconnect()
+//	if err := t.Verify(); err != nil { ... }
+//
+// Command tags are reproduced as long as the replayed program issues the
+// same commands from a fresh transport, as the original did.
+type ReplayTransport struct {
+	*SerialTransport
+	port *replayPort
+}
+
+// NewReplayTransport creates (but does not connect) a transport replaying
+// capture at its original speed. Connect writes km.buttons(1) when the
+// capture starts with it.
+func NewReplayTransport(capture *Capture) *ReplayTransport {
+	p := newReplayPort(capture)
+	t := &ReplayTransport{port: p}
+	opened := false
+	t.SerialTransport = NewStreamTransport("replay", func() (Port, error) {
+		if opened {
+			return nil, NewConnectionError("replay: capture can only be connected once")
+		}
+		opened = true
+		return p, nil
+	}, false, p.startsWithInit(), false)
+	return t
+}
+
+// SetSpeed scales the replay timing: 2 plays twice as fast, 0 or less
+// releases device output as soon as the host writes allow.
+func (t *ReplayTransport) SetSpeed(speed float64) {
+	t.port.mu.Lock()
+	defer t.port.mu.Unlock()
+	t.port.speed = speed
+}
+
+// Verify returns the first divergence from the capture — a write that does
+// not match, or host writes and device output that were never replayed —
+// or nil if the session was reproduced exactly.
+func (t *ReplayTransport) Verify() error {
+	p := t.port
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	if p.wIdx < len(p.records) {
+		return &ReplayError{Record: p.wIdx, Want: p.records[p.wIdx].Data[p.wPos:]}
+	}
+	if p.rIdx < len(p.records) {
+		return &ReplayError{Record: p.rIdx, Want: p.records[p.rIdx].Data[p.rPos:], Unread: true}
+	}
+	return nil
+}
+
+// ReplayError describes where a replayed session diverged from its capture.
+type ReplayError struct {
+	Record int    // index of the capture record
+	Got    []byte // bytes written by the library, from the point of divergence
+	Want   []byte // bytes the capture expected, from the same point
+	Unread bool   // device output that the library never consumed
+}
+
+func (e *ReplayError) Error() string {
+	switch {
+	case e.Unread:
+		return fmt.Sprintf("replay: record %d: device output %s never delivered", e.Record, strconv.Quote(string(e.Want)))
+	case e.Got == nil:
+		return fmt.Sprintf("replay: record %d: expected write %s never happened", e.Record, strconv.Quote(string(e.Want)))
+	case e.Want == nil:
+		return fmt.Sprintf("replay: unexpected write %s after the end of the capture", strconv.Quote(string(e.Got)))
+	default:
+		return fmt.Sprintf("replay: record %d: wrote %s, want %s", e.Record, strconv.Quote(string(e.Got)), strconv.Quote(string(e.Want)))
+	}
+}
+
+// --- port ---
+
+// replayPort serves a capture as a Port. Writes are matched against the
+// capture's write records; reads return its read records once every
+// earlier write has been matched and the original gap has elapsed.
+type replayPort struct {
+	mu      sync.Mutex
+	records []CaptureRecord
+	speed   float64
+	timeout time.Duration
+	wrote   chan struct{} // signalled on write progress
+	closed  bool
+	err     error
+
+	// Next write and read record (len(records) when exhausted) and the
+	// offset into each.
+	wIdx, wPos int
+	rIdx, rPos int
+	at         []time.Time // when each record was replayed; zero if not yet
+}
+
+func newReplayPort(c *Capture) *replayPort {
+	p := &replayPort{
+		records: c.Records,
+		speed:   1,
+		wrote:   make(chan struct{}, 1),
+		at:      make([]time.Time, len(c.Records)),
+	}
+	p.wIdx = p.nextRecord(CaptureWrite, 0)
+	p.rIdx = p.nextRecord(CaptureRead, 0)
+	return p
+}
+
+// startsWithInit reports whether the first host write is the
+// km.buttons(1) sent by SendInit.
+func (p *replayPort) startsWithInit() bool {
+	return p.wIdx < len(p.records) && string(p.records[p.wIdx].Data) == "km.buttons(1)\r"
+}
+
+// nextRecord returns the index of the first non-empty record at or after i
+// with the given direction, or len(records).
+func (p *replayPort) nextRecord(dir CaptureDir, i int) int {
+	for ; i < len(p.records); i++ {
+		if p.records[i].Dir == dir && len(p.records[i].Data) > 0 {
+			return i
+		}
+	}
+	return len(p.records)
+}
+
+func (p *replayPort) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return 0, io.ErrClosedPipe
+	}
+	if p.err != nil {
+		return 0, p.err
+	}
+	for i := 0; i < len(b); {
+		if p.wIdx == len(p.records) {
+			p.err = &ReplayError{Record: len(p.records), Got: append([]byte(nil), b[i:]...)}
+			return i, p.err
+		}
+		want := p.records[p.wIdx].Data[p.wPos:]
+		n := 0
+		for n < len(want) && i+n < len(b) && b[i+n] == want[n] {
+			n++
+		}
+		if n < len(want) && i+n < len(b) {
+			p.err = &ReplayError{Record: p.wIdx, Got: append([]byte(nil), b[i:]...), Want: append([]byte(nil), want...)}
+			return i, p.err
+		}
+		i += n
+		p.wPos += n
+		if p.wPos == len(p.records[p.wIdx].Data) {
+			p.at[p.wIdx] = time.Now()
+			p.wIdx, p.wPos = p.nextRecord(CaptureWrite, p.wIdx+1), 0
+		}
+	}
+	select {
+	case p.wrote <- struct{}{}:
+	default:
+	}
+	return len(b), nil
+}
+
+func (p *replayPort) Read(b []byte) (int, error) {
+	deadline := time.Now().Add(p.readTimeout())
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return 0, io.EOF
+		}
+		n, wait, ok := p.deliver(b)
+		p.mu.Unlock()
+		if ok {
+			return n, nil
+		}
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return 0, nil
+		}
+		if wait <= 0 || wait > remaining {
+			wait = remaining
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-p.wrote:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// deliver copies the next read record into b if it is due. Otherwise it
+// returns how long until it is due (0 when blocked on host writes or the
+// capture is exhausted).
+func (p *replayPort) deliver(b []byte) (n int, wait time.Duration, ok bool) {
+	r := p.rIdx
+	if p.err != nil || r == len(p.records) {
+		return 0, 0, false
+	}
+	if p.rPos == 0 {
+		// Every earlier host write must have happened.
+		if p.wIdx < r {
+			return 0, 0, false
+		}
+		if wait := p.untilDue(r); wait > 0 {
+			return 0, wait, false
+		}
+	}
+	n = copy(b, p.records[r].Data[p.rPos:])
+	p.rPos += n
+	if p.rPos == len(p.records[r].Data) {
+		p.at[r] = time.Now()
+		p.rIdx, p.rPos = p.nextRecord(CaptureRead, r+1), 0
+	}
+	return n, 0, true
+}
+
+// untilDue returns how long record r must still wait to keep its original
+// gap from the latest earlier record already replayed.
+func (p *replayPort) untilDue(r int) time.Duration {
+	if p.speed <= 0 {
+		return 0
+	}
+	for i := r - 1; i >= 0; i-- {
+		if !p.at[i].IsZero() {
+			gap := time.Duration(float64(p.records[r].Offset-p.records[i].Offset) / p.speed)
+			return time.Until(p.at[i].Add(gap))
+		}
+	}
+	return 0
+}
+
+func (p *replayPort) readTimeout() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.timeout <= 0 {
+		return time.Millisecond
+	}
+	return p.timeout
+}
+
+func (p *replayPort) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	return nil
+}
+
+func (p *replayPort) SetReadTimeout(t time.Duration) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.timeout = t
+	return nil
+}
@@ -0,0 +1,118 @@
+package Macku
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ResponseError is returned when a reply to command cannot be used: the
+// device reported an error instead of a value, or the reply did not have
+// the expected form. It matches ErrResponse, and device errors also match
+// ErrDevice.
+type ResponseError struct {
+	Command string // the command that was answered
+	Raw     string // the reply as received
+	Device  bool   // the reply is an error message from the firmware
+	Reason  string
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("%s: %s: %q", e.Command, e.Reason, e.Raw)
+}
+
+func (e *ResponseError) Unwrap() error {
+	if e.Device {
+		return errDeviceResponse
+	}
+	return ErrResponse
+}
+
+// deviceErrorLine matches the error messages the firmware prints in place
+// of a value; echoLine matches a command echoed back.
+var (
+	deviceErrorLine = regexp.MustCompile(`(?i)^(?:error\b|err:|unknown command|invalid\b|traceback\b|\w+error:)`)
+	echoLine        = regexp.MustCompile(`^km\.\w+\([^()]*\)$`)
+)
+
+// IsDeviceError reports whether line is an error message from the firmware
+// rather than a value.
+func IsDeviceError(line string) bool {
+	return deviceErrorLine.MatchString(strings.TrimSpace(line))
+}
+
+// isEcho reports whether line is a command as echoed by the device.
+func isEcho(line string) bool {
+	return echoLine.MatchString(line)
+}
+
+// checkReply rejects device errors and echoes in place of a value.
+func checkReply(command, line string) (string, error) {
+	line = strings.TrimSpace(line)
+	switch {
+	case IsDeviceError(line):
+		return line, &ResponseError{Command: command, Raw: line, Device: true, Reason: "device error"}
+	case isEcho(line):
+		return line, &ResponseError{Command: command, Raw: line, Reason: "echo instead of a value"}
+	case line == "":
+		return line, &ResponseError{Command: command, Raw: line, Reason: "empty reply"}
+	}
+	return line, nil
+}
+
+// ParseBoolResponse parses the reply to a query such as km.lock_ml(),
+// which must be "0" or "1".
+func ParseBoolResponse(command, line string) (bool, error) {
+	line, err := checkReply(command, line)
+	if err != nil {
+		return false, err
+	}
+	switch line {
+	case "0":
+		return false, nil
+	case "1":
+		return true, nil
+	}
+	return false, &ResponseError{Command: command, Raw: line, Reason: "expected 0 or 1"}
+}
+
+// ParseIntResponse parses a decimal integer reply.
+func ParseIntResponse(command, line string) (int, error) {
+	line, err := checkReply(command, line)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(line)
+	if err != nil {
+		return 0, &ResponseError{Command: command, Raw: line, Reason: "expected an integer"}
+	}
+	return n, nil
+}
+
+// ParseVersionResponse parses the reply to km.version(), which must name
+// the MAKCU or carry a version number.
+func ParseVersionResponse(command, line string) (FirmwareVersion, error) {
+	line, err := checkReply(command, line)
+	if err != nil {
+		return FirmwareVersion{}, err
+	}
+	v := ParseFirmwareVersion(line)
+	if !v.Known && !strings.Contains(strings.ToUpper(line), "MAKCU") {
+		return FirmwareVersion{}, &ResponseError{Command: command, Raw: line, Reason: "expected a firmware version"}
+	}
+	return v, nil
+}
+
+// CheckEchoResponse checks that line echoes command, as the device does
+// for commands that set state. Whitespace inside the echo is ignored.
+func CheckEchoResponse(command, line string) error {
+	line = strings.TrimSpace(line)
+	if IsDeviceError(line) {
+		return &ResponseError{Command: command, Raw: line, Device: true, Reason: "device error"}
+	}
+	if strings.Join(strings.Fields(line), "") != strings.Join(strings.Fields(command), "") {
+		return &ResponseError{Command: command, Raw: line, Reason: "expected an echo of the command"}
+	}
+	return nil
+}
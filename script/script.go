@@ -0,0 +1,388 @@
+// Package script embeds a Starlark interpreter for automation logic that
+// needs to branch on device state. Scripts get a small builtin API bound to a
+// MakcuController:
+//
+//	move(dx, dy, segments=0)      click(button="left", count=1)
+//	press(button)                 release(button)
+//	scroll(delta)                 lock(target) / unlock(target)
+//	is_pressed(button) -> bool    is_locked(button) -> bool
+//	wait_for(button, pressed=True, timeout=0) -> bool
+//	sleep(seconds)
+//
+// Scripts are sandboxed: load() is disabled, there is no file or network
+// access, and execution is bounded by a wall-clock timeout and a step limit.
+package script
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	Macku "github.com/Auchrio/Makcu-go-lib/v3"
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+// Options configures script execution limits.
+type Options struct {
+	Timeout      time.Duration     // Max wall-clock time per run (0 = unlimited)
+	MaxSteps     uint64            // Max Starlark computation steps (0 = unlimited)
+	PollInterval time.Duration     // Button poll interval for wait_for
+	Print        func(msg string)  // Receives print() output (nil = discard)
+	Globals      map[string]string // Extra string constants exposed to the script
+}
+
+// DefaultOptions returns Options with a 30 second timeout and a 10M step limit.
+func DefaultOptions() Options {
+	return Options{
+		Timeout:      30 * time.Second,
+		MaxSteps:     10_000_000,
+		PollInterval: 5 * time.Millisecond,
+	}
+}
+
+// ErrLimit is returned when a script exceeds its timeout or step limit.
+var ErrLimit = errors.New("script: execution limit exceeded")
+
+// Engine runs Starlark scripts against a MakcuController.
+type Engine struct {
+	Controller *Macku.MakcuController
+	Options    Options
+}
+
+// New creates an Engine for the given controller.
+func New(c *Macku.MakcuController, opts Options) *Engine {
+	return &Engine{Controller: c, Options: opts}
+}
+
+// run holds the per-execution state shared by the builtins.
+type run struct {
+	ctx   context.Context
+	c     *Macku.MakcuController
+	opts  Options
+	held  map[Macku.MouseButton]bool
+	locks map[Macku.LockTarget]bool
+}
+
+// Run executes src (named filename in errors) until it finishes, fails, is
+// cancelled through ctx, or exceeds a limit. Buttons the script still holds
+// and targets it locked are released before Run returns.
+func (e *Engine) Run(ctx context.Context, filename string, src []byte) (err error) {
+	if e.Options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.Options.Timeout)
+		defer cancel()
+	}
+
+	r := &run{
+		ctx:   ctx,
+		c:     e.Controller,
+		opts:  e.Options,
+		held:  make(map[Macku.MouseButton]bool),
+		locks: make(map[Macku.LockTarget]bool),
+	}
+	defer func() {
+		if cleanupErr := r.cleanup(); err == nil {
+			err = cleanupErr
+		}
+	}()
+
+	thread := &starlark.Thread{
+		Name: filename,
+		Print: func(_ *starlark.Thread, msg string) {
+			if e.Options.Print != nil {
+				e.Options.Print(msg)
+			}
+		},
+		Load: func(*starlark.Thread, string) (starlark.StringDict, error) {
+			return nil, errors.New("load() is not allowed in scripts")
+		},
+	}
+	if e.Options.MaxSteps > 0 {
+		thread.SetMaxExecutionSteps(e.Options.MaxSteps)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			thread.Cancel(ctx.Err().Error())
+		case <-done:
+		}
+	}()
+
+	_, err = starlark.ExecFileOptions(&syntax.FileOptions{
+		While:           true,
+		TopLevelControl: true,
+		GlobalReassign:  true,
+	}, thread, filename, src, r.globals())
+	if err != nil {
+		return r.classify(err, thread)
+	}
+	return nil
+}
+
+// classify maps cancellation and step-limit failures onto ctx errors and
+// ErrLimit so callers can tell them apart from script bugs.
+func (r *run) classify(err error, thread *starlark.Thread) error {
+	if ctxErr := r.ctx.Err(); ctxErr != nil {
+		if errors.Is(ctxErr, context.DeadlineExceeded) && r.opts.Timeout > 0 {
+			return fmt.Errorf("%w: timeout after %v", ErrLimit, r.opts.Timeout)
+		}
+		return ctxErr
+	}
+	if r.opts.MaxSteps > 0 && thread.ExecutionSteps() >= r.opts.MaxSteps {
+		return fmt.Errorf("%w: more than %d steps", ErrLimit, r.opts.MaxSteps)
+	}
+	var evalErr *starlark.EvalError
+	if errors.As(err, &evalErr) {
+		if unwrapped := evalErr.Unwrap(); unwrapped != nil {
+			return fmt.Errorf("%s: %w", evalErr.Backtrace(), unwrapped)
+		}
+	}
+	return err
+}
+
+func (r *run) cleanup() error {
+	var first error
+	for button := range r.held {
+		if err := r.c.Release(button); err != nil && first == nil {
+			first = err
+		}
+	}
+	for target := range r.locks {
+		if err := r.c.Unlock(target); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func (r *run) globals() starlark.StringDict {
+	g := starlark.StringDict{
+		"move":       starlark.NewBuiltin("move", r.move),
+		"click":      starlark.NewBuiltin("click", r.click),
+		"press":      starlark.NewBuiltin("press", r.press),
+		"release":    starlark.NewBuiltin("release", r.release),
+		"scroll":     starlark.NewBuiltin("scroll", r.scroll),
+		"lock":       starlark.NewBuiltin("lock", r.lock),
+		"unlock":     starlark.NewBuiltin("unlock", r.unlock),
+		"is_pressed": starlark.NewBuiltin("is_pressed", r.isPressed),
+		"is_locked":  starlark.NewBuiltin("is_locked", r.isLocked),
+		"wait_for":   starlark.NewBuiltin("wait_for", r.waitFor),
+		"sleep":      starlark.NewBuiltin("sleep", r.sleep),
+	}
+	for name, value := range r.opts.Globals {
+		g[name] = starlark.String(value)
+	}
+	return g
+}
+
+// --- builtins ---
+
+func (r *run) move(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var dx, dy, segments int
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "dx", &dx, "dy", &dy, "segments?", &segments); err != nil {
+		return nil, err
+	}
+	if segments > 0 {
+		return starlark.None, r.c.MoveSmooth(dx, dy, segments)
+	}
+	return starlark.None, r.c.Move(dx, dy)
+}
+
+func (r *run) click(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	name, count := "left", 1
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "button?", &name, "count?", &count); err != nil {
+		return nil, err
+	}
+	// A builtin runs as one Starlark step, so its loop is outside the step
+	// limit.
+	if count < 0 || count > Macku.MaxClickCount {
+		return nil, fmt.Errorf("%s: count must be 0..%d, got %d", b.Name(), Macku.MaxClickCount, count)
+	}
+	button, err := Macku.ParseMouseButton(name)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < count; i++ {
+		if err := r.ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := r.c.Click(button); err != nil {
+			return nil, err
+		}
+	}
+	return starlark.None, nil
+}
+
+func (r *run) press(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	button, err := unpackButton(b, args, kwargs)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.c.Press(button); err != nil {
+		return nil, err
+	}
+	r.held[button] = true
+	return starlark.None, nil
+}
+
+func (r *run) release(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	button, err := unpackButton(b, args, kwargs)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.c.Release(button); err != nil {
+		return nil, err
+	}
+	delete(r.held, button)
+	return starlark.None, nil
+}
+
+func (r *run) scroll(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var delta int
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "delta", &delta); err != nil {
+		return nil, err
+	}
+	return starlark.None, r.c.Scroll(delta)
+}
+
+func (r *run) lock(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	target, err := unpackTarget(b, args, kwargs)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.c.Lock(target); err != nil {
+		return nil, err
+	}
+	r.locks[target] = true
+	return starlark.None, nil
+}
+
+func (r *run) unlock(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	target, err := unpackTarget(b, args, kwargs)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.c.Unlock(target); err != nil {
+		return nil, err
+	}
+	delete(r.locks, target)
+	return starlark.None, nil
+}
+
+func (r *run) isPressed(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	button, err := unpackButton(b, args, kwargs)
+	if err != nil {
+		return nil, err
+	}
+	pressed, err := r.c.IsPressed(button)
+	return starlark.Bool(pressed), err
+}
+
+func (r *run) isLocked(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	button, err := unpackButton(b, args, kwargs)
+	if err != nil {
+		return nil, err
+	}
+	locked, err := r.c.IsLocked(button)
+	return starlark.Bool(locked), err
+}
+
+// waitFor blocks until the button reaches the wanted state and returns True,
+// or returns False once timeout (seconds, 0 = none) elapses.
+func (r *run) waitFor(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var name string
+	pressed := true
+	var timeout starlark.Value = starlark.MakeInt(0)
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "button", &name, "pressed?", &pressed, "timeout?", &timeout); err != nil {
+		return nil, err
+	}
+	button, err := Macku.ParseMouseButton(name)
+	if err != nil {
+		return nil, err
+	}
+	limit, err := seconds(b, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	interval := r.opts.PollInterval
+	if interval <= 0 {
+		interval = DefaultOptions().PollInterval
+	}
+	var deadline <-chan time.Time
+	if limit > 0 {
+		timer := time.NewTimer(limit)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		state, err := r.c.IsPressed(button)
+		if err != nil {
+			return nil, err
+		}
+		if state == pressed {
+			return starlark.True, nil
+		}
+		select {
+		case <-r.ctx.Done():
+			return nil, r.ctx.Err()
+		case <-deadline:
+			return starlark.False, nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *run) sleep(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var v starlark.Value
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "seconds", &v); err != nil {
+		return nil, err
+	}
+	d, err := seconds(b, v)
+	if err != nil {
+		return nil, err
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-r.ctx.Done():
+		return nil, r.ctx.Err()
+	case <-timer.C:
+		return starlark.None, nil
+	}
+}
+
+// --- argument helpers ---
+
+func unpackButton(b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (Macku.MouseButton, error) {
+	var name string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "button", &name); err != nil {
+		return 0, err
+	}
+	return Macku.ParseMouseButton(name)
+}
+
+func unpackTarget(b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (Macku.LockTarget, error) {
+	var name string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "target", &name); err != nil {
+		return 0, err
+	}
+	return Macku.ParseLockTarget(name)
+}
+
+// seconds converts a Starlark int or float number of seconds to a Duration.
+func seconds(b *starlark.Builtin, v starlark.Value) (time.Duration, error) {
+	f, ok := starlark.AsFloat(v)
+	if !ok || f < 0 {
+		return 0, fmt.Errorf("%s: expected non-negative number of seconds, got %s", b.Name(), v)
+	}
+	return time.Duration(f * float64(time.Second)), nil
+}
@@ -0,0 +1,153 @@
+package lib_test
+
+import (
+	"math"
+	"testing"
+
+	Macku "github.com/Auchrio/Makcu-go-lib/v3"
+)
+
+// ---------------------------------------------------------------------------
+// Acceleration model tests
+// ---------------------------------------------------------------------------
+
+func TestAccelerationModelsInvert(t *testing.T) {
+	models := map[string]Macku.AccelerationModel{
+		"flat":   Macku.FlatAcceleration{},
+		"linear": Macku.LinearAcceleration{Multiplier: 1.75},
+		"piecewise": Macku.PiecewiseAcceleration{Points: []Macku.AccelPoint{
+			{Counts: 4, Pixels: 4}, {Counts: 10, Pixels: 18}, {Counts: 20, Pixels: 50},
+		}},
+		"adaptive": Macku.NewAdaptiveAcceleration(0.5),
+	}
+	for name, m := range models {
+		t.Run(name, func(t *testing.T) {
+			for _, counts := range []float64{-40, -7, -1, 0, 0.5, 1, 3, 8, 15, 27, 100} {
+				px := m.Displacement(counts)
+				back := m.Counts(px)
+				if math.Abs(back-counts) > 1e-6 {
+					t.Errorf("Counts(Displacement(%v)) = %v", counts, back)
+				}
+			}
+		})
+	}
+}
+
+func TestAccelerationModelsMonotonic(t *testing.T) {
+	models := []Macku.AccelerationModel{
+		Macku.NewAdaptiveAcceleration(-1),
+		Macku.NewAdaptiveAcceleration(0),
+		Macku.NewAdaptiveAcceleration(1),
+	}
+	for _, m := range models {
+		prev := m.Displacement(0)
+		for c := 0.25; c < 200; c += 0.25 {
+			d := m.Displacement(c)
+			if d < prev {
+				t.Fatalf("%+v: Displacement(%v) = %v < %v", m, c, d, prev)
+			}
+			prev = d
+		}
+	}
+}
+
+func TestPiecewiseAccelerationInterpolation(t *testing.T) {
+	curve := Macku.PiecewiseAcceleration{Points: []Macku.AccelPoint{
+		{Counts: 2, Pixels: 2}, {Counts: 6, Pixels: 14},
+	}}
+	tests := []struct{ counts, want float64 }{
+		{1, 1},   // through the implicit origin
+		{4, 8},   // between points
+		{10, 26}, // extrapolated with the last slope (3 px/count)
+		{-4, -8}, // mirrored
+	}
+	for _, tt := range tests {
+		if got := curve.Displacement(tt.counts); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Displacement(%v) = %v, want %v", tt.counts, got, tt.want)
+		}
+	}
+}
+
+func TestAdaptiveAccelerationThreshold(t *testing.T) {
+	a := Macku.AdaptiveAcceleration{BaseGain: 1, Threshold: 4, Incline: 0.5, MaxFactor: 3}
+	if got := a.Displacement(4); got != 4 {
+		t.Errorf("Displacement(4) = %v, want 4 (below threshold)", got)
+	}
+	if got := a.Displacement(6); got != 12 {
+		t.Errorf("Displacement(6) = %v, want 12", got)
+	}
+	if got := a.Displacement(100); got != 300 {
+		t.Errorf("Displacement(100) = %v, want 300 (capped at MaxFactor)", got)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Calibration tests
+// ---------------------------------------------------------------------------
+
+func TestCalibrateAccelerationLinear(t *testing.T) {
+	cur := newSimCursor(960, 540, linearAccel(2))
+	opts := Macku.DefaultCalibrationOptions()
+	opts.Settle = 0
+	cal, err := Macku.CalibrateAcceleration(cur, cur.Move, opts)
+	if err != nil {
+		t.Fatalf("CalibrateAcceleration: %v", err)
+	}
+	if math.Abs(cal.Linear.Multiplier-2) > 1e-9 {
+		t.Errorf("Linear.Multiplier = %v, want 2", cal.Linear.Multiplier)
+	}
+	if cur.x != 960 || cur.y != 540 {
+		t.Errorf("cursor ended at (%d,%d), want start position", cur.x, cur.y)
+	}
+	if len(cal.Samples) != len(opts.Steps) {
+		t.Errorf("got %d samples, want %d", len(cal.Samples), len(opts.Steps))
+	}
+}
+
+func TestCalibrateAccelerationCurve(t *testing.T) {
+	cur := newSimCursor(960, 540, thresholdAccel)
+	opts := Macku.DefaultCalibrationOptions()
+	opts.Settle = 0
+	opts.Steps = []int{1, 2, 4, 8, 16}
+	cal, err := Macku.CalibrateAcceleration(cur, cur.Move, opts)
+	if err != nil {
+		t.Fatalf("CalibrateAcceleration: %v", err)
+	}
+	for _, c := range opts.Steps {
+		want := float64(thresholdAccel(c))
+		if got := cal.Curve.Displacement(float64(c)); math.Abs(got-want) > 1e-9 {
+			t.Errorf("Curve.Displacement(%d) = %v, want %v", c, got, want)
+		}
+	}
+}
+
+func TestMoveToPositionWithCalibratedModel(t *testing.T) {
+	accel := linearAccel(3)
+	probe := newSimCursor(960, 540, accel)
+	cal, err := Macku.CalibrateAcceleration(probe, probe.Move,
+		Macku.CalibrationOptions{Steps: []int{1, 4, 16}, Repeats: 1})
+	if err != nil {
+		t.Fatalf("CalibrateAcceleration: %v", err)
+	}
+
+	modelled := newSimCursor(0, 0, accel)
+	opts := fastMoveAbsOptions()
+	opts.MaxStep = 127
+	opts.Model = cal.Curve
+	withModel, err := Macku.MoveToPosition(modelled, modelled.Move, [2]int{900, 600}, opts)
+	if err != nil {
+		t.Fatalf("MoveToPosition with model: %v", err)
+	}
+
+	naive := newSimCursor(0, 0, accel)
+	opts.Model = nil
+	without, err := Macku.MoveToPosition(naive, naive.Move, [2]int{900, 600}, opts)
+	if err != nil {
+		t.Fatalf("MoveToPosition without model: %v", err)
+	}
+
+	if withModel.Iterations > without.Iterations {
+		t.Errorf("iterations with model = %d, without = %d; model should not be slower",
+			withModel.Iterations, without.Iterations)
+	}
+}
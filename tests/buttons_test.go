@@ -0,0 +1,72 @@
+package lib_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	Macku "github.com/Auchrio/Makcu-go-lib/v3"
+	"github.com/Auchrio/Makcu-go-lib/v3/emulator"
+)
+
+// ---------------------------------------------------------------------------
+// Extra buttons
+// ---------------------------------------------------------------------------
+
+func TestExtraButtonsUnsupported(t *testing.T) {
+	c, ft := newFakeController()
+	for b := Macku.MouseButton6; b <= Macku.MouseButton8; b++ {
+		if err := c.Press(b); !errors.Is(err, Macku.ErrUnsupported) || !errors.Is(err, Macku.ErrCommand) {
+			t.Errorf("Press(%v) = %v, want unsupported command error", b, err)
+		}
+		if err := c.Release(b); !errors.Is(err, Macku.ErrUnsupported) {
+			t.Errorf("Release(%v) = %v, want ErrUnsupported", b, err)
+		}
+		if _, err := c.IsLocked(b); !errors.Is(err, Macku.ErrUnsupported) {
+			t.Errorf("IsLocked(%v) = %v, want ErrUnsupported", b, err)
+		}
+		if err := c.Mouse.SetCatch(b, true); !errors.Is(err, Macku.ErrUnsupported) {
+			t.Errorf("SetCatch(%v) = %v, want ErrUnsupported", b, err)
+		}
+		if _, err := Macku.ParseLockTarget(b.String()); err == nil {
+			t.Errorf("ParseLockTarget(%q) found a lock target", b)
+		}
+	}
+	if cmds := ft.commands(); len(cmds) != 0 {
+		t.Errorf("unsupported buttons sent %q", cmds)
+	}
+
+	ft.answerLockQueries()
+	states, err := c.GetAllLockStates()
+	if err != nil || len(states) != 7 {
+		t.Errorf("GetAllLockStates = %v, %v", states, err)
+	}
+}
+
+func TestExtraButtonsReported(t *testing.T) {
+	dev := emulator.New()
+	c, _ := framingController(t, dev)
+
+	// Bits 5-7 only fit in framed reports.
+	events := make(chan string, 8)
+	c.SetButtonCallback(func(b Macku.MouseButton, pressed bool) {
+		events <- b.String() + map[bool]string{true: "+", false: "-"}[pressed]
+	})
+	dev.SetButtons(0xE0)
+	var got []string
+	for len(got) < 3 {
+		select {
+		case e := <-events:
+			got = append(got, e)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("events = %q", got)
+		}
+	}
+	if !slices.Equal(got, []string{"mouse6+", "mouse7+", "mouse8+"}) {
+		t.Errorf("events = %q", got)
+	}
+	if states, _ := c.GetButtonStates(); !states["mouse8"] || states["mouse5"] {
+		t.Errorf("GetButtonStates = %v", states)
+	}
+}
@@ -0,0 +1,190 @@
+package lib_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	Macku "github.com/Auchrio/Makcu-go-lib/v3"
+	"github.com/Auchrio/Makcu-go-lib/v3/emulator"
+	"github.com/Auchrio/Makcu-go-lib/v3/internal/cli"
+)
+
+// ---------------------------------------------------------------------------
+// Capture files
+// ---------------------------------------------------------------------------
+
+func sampleCapture() *Macku.Capture {
+	return &Macku.Capture{
+		Start: time.Date(2026, 1, 2, 3, 4, 5, 6, time.UTC),
+		Records: []Macku.CaptureRecord{
+			{Offset: 0, Dir: Macku.CaptureWrite, Data: []byte("km.buttons(1)\r")},
+			{Offset: 1500 * time.Microsecond, Dir: Macku.CaptureWrite, Data: []byte("km.version()#1\r\n")},
+			{Offset: 1900 * time.Microsecond, Dir: Macku.CaptureRead, Data: []byte("km.version()\r\n>>> km.MAKCU\r\n")},
+			{Offset: 3*time.Second + 7, Dir: Macku.CaptureRead, Data: []byte{0x02, 0x0A, 0x00}},
+		},
+	}
+}
+
+func TestCaptureRoundTrip(t *testing.T) {
+	want := sampleCapture()
+	for _, format := range []Macku.CaptureFormat{Macku.CaptureBinary, Macku.CaptureText} {
+		var buf bytes.Buffer
+		if err := want.WriteTo(&buf, format); err != nil {
+			t.Fatal(err)
+		}
+		got, err := Macku.ReadCapture(&buf)
+		if err != nil {
+			t.Fatalf("format %d: %v", format, err)
+		}
+		if !got.Start.Equal(want.Start) || !reflect.DeepEqual(got.Records, want.Records) {
+			t.Errorf("format %d: round trip = %+v, want %+v", format, got, want)
+		}
+	}
+}
+
+func TestCaptureTextFormat(t *testing.T) {
+	var buf bytes.Buffer
+	if err := sampleCapture().WriteTo(&buf, Macku.CaptureText); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := []string{
+		"# makcu capture v1 start=2026-01-02T03:04:05.000000006Z",
+		`+0.000000000 > "km.buttons(1)\r"`,
+		`+0.001500000 > "km.version()#1\r\n"`,
+		`+0.001900000 < "km.version()\r\n>>> km.MAKCU\r\n"`,
+		`+3.000000007 < "\x02\n\x00"`,
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("text capture =\n%s\nwant\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestReadCaptureRejectsMalformedInput(t *testing.T) {
+	var bin bytes.Buffer
+	sampleCapture().WriteTo(&bin, Macku.CaptureBinary)
+
+	for name, input := range map[string]string{
+		"truncated binary": bin.String()[:bin.Len()-2],
+		"bad direction":    `+0.1 ? "x"`,
+		"bad offset":       `+x > "x"`,
+		"unquoted data":    `+0.1 > x`,
+	} {
+		if _, err := Macku.ReadCapture(strings.NewReader(input)); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Decoder
+// ---------------------------------------------------------------------------
+
+func TestDecodeAppliesListenerRules(t *testing.T) {
+	c := sampleCapture()
+	c.Records = append(c.Records, Macku.CaptureRecord{Offset: 4 * time.Second, Dir: Macku.CaptureRead, Data: []byte("hello\n")})
+
+	var got []string
+	for _, ev := range c.Decode() {
+		got = append(got, ev.String())
+	}
+	want := []string{
+		"+0.000000000 > command  km.buttons(1)",
+		"+0.001500000 > command  km.version() #1",
+		`+0.001900000 < echo     "km.version()" (km.version() #1)`,
+		`+0.001900000 < response "km.MAKCU" (km.version() #1)`,
+		"+3.000000007 < buttons  0x02 [right]",
+		"+3.000000007 < buttons  0x0A [right mouse4]", // LF after a non-zero mask is button data
+		"+3.000000007 < buttons  0x00 []",
+		`+4.000000000 < text     "hello"`, // LF-terminated line, nothing pending
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestTapRecordsSession(t *testing.T) {
+	dev := emulator.New()
+	var buf bytes.Buffer
+	cw := Macku.NewCaptureWriter(&buf, Macku.CaptureBinary)
+	st := Macku.NewStreamTransport("emulator", dev.Open, false, true, false)
+	st.SetTap(cw)
+	c := Macku.NewControllerWithTransport(st)
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	pressed := make(chan struct{}, 1)
+	c.SetButtonCallback(func(Macku.MouseButton, bool) { pressed <- struct{}{} })
+	c.Move(3, 4)
+	flush(t, c)
+	dev.SetButtons(1 << uint(Macku.MouseButtonMiddle))
+	select {
+	case <-pressed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("no button event")
+	}
+	c.Disconnect()
+	if err := cw.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	capture, err := Macku.ReadCapture(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kinds []string
+	versionTag := -1
+	for _, ev := range capture.Decode() {
+		kinds = append(kinds, ev.Kind.String()+":"+ev.Text)
+		switch {
+		case ev.Kind == Macku.DecodedCommand && ev.Text == "km.version()":
+			versionTag = ev.Tag
+		case ev.Kind == Macku.DecodedResponse && (ev.Command != "km.version()" || ev.Tag != versionTag):
+			t.Errorf("response %+v not matched to km.version() #%d", ev, versionTag)
+		}
+	}
+	want := []string{"command:km.buttons(1)", "command:km.move(3,4)", "command:km.version()", "response:km.MAKCU", "buttons:"}
+	if !reflect.DeepEqual(kinds, want) {
+		t.Errorf("decoded kinds = %v, want %v", kinds, want)
+	}
+}
+
+func TestCLIDecode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.cap")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sampleCapture().WriteTo(f, Macku.CaptureBinary)
+	f.Close()
+
+	h := newCLIHarness()
+	if code := h.run("decode", path); code != cli.ExitOK {
+		t.Fatalf("decode: exit %d, stderr %q", code, h.stderr.String())
+	}
+	if out := h.stdout.String(); !strings.Contains(out, `response "km.MAKCU" (km.version() #1)`) {
+		t.Errorf("decode output:\n%s", out)
+	}
+
+	h = newCLIHarness()
+	if code := h.run("-json", "decode", path); code != cli.ExitOK {
+		t.Fatalf("decode -json: exit %d", code)
+	}
+	if out := h.stdout.String(); !strings.Contains(out, `"kind":"buttons","mask":2`) {
+		t.Errorf("decode -json output:\n%s", out)
+	}
+
+	h = newCLIHarness()
+	if code := h.run("decode", "-text", path); code != cli.ExitOK {
+		t.Fatalf("decode -text: exit %d", code)
+	}
+	if out := h.stdout.String(); !strings.HasPrefix(out, "# makcu capture v1") {
+		t.Errorf("decode -text output:\n%s", out)
+	}
+}
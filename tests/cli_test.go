@@ -0,0 +1,328 @@
+package lib_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	Macku "github.com/Auchrio/Makcu-go-lib/v3"
+	"github.com/Auchrio/Makcu-go-lib/v3/emulator"
+	"github.com/Auchrio/Makcu-go-lib/v3/internal/cli"
+)
+
+// ---------------------------------------------------------------------------
+// makcu CLI tests
+// ---------------------------------------------------------------------------
+
+type cliHarness struct {
+	app    *cli.App
+	ft     *fakeTransport
+	stdout bytes.Buffer
+	stderr bytes.Buffer
+}
+
+func newCLIHarness() *cliHarness {
+	h := &cliHarness{ft: newFakeTransport()}
+	h.app = &cli.App{
+		Stdout: &h.stdout,
+		Stderr: &h.stderr,
+		Connect: func(Macku.Config) (*Macku.MakcuController, error) {
+			c := Macku.NewControllerWithTransport(h.ft)
+			return c, c.Connect()
+		},
+		ListPorts: func() ([]Macku.PortInfo, error) {
+			return []Macku.PortInfo{
+				{Name: "/dev/ttyS0"},
+				{Name: "/dev/ttyACM0", VID: "1A86", PID: "55D3", IsUSB: true, IsMakcu: true},
+			}, nil
+		},
+	}
+	return h
+}
+
+func (h *cliHarness) run(args ...string) int {
+	return h.app.Run(context.Background(), args)
+}
+
+func TestCLICommandsSendExpectedCommands(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"move", "10", "-20"}, "km.move(10,-20)"},
+		{[]string{"move", "10", "-20", "5"}, "km.move(10,-20,5)"},
+		{[]string{"click"}, "km.left(1) km.left(0)"},
+		{[]string{"click", "-count", "2", "right"}, "km.right(1) km.right(0) km.right(1) km.right(0)"},
+		{[]string{"scroll", "-3"}, "km.wheel(-3)"},
+		{[]string{"lock", "x"}, "km.lock_mx(1)"},
+		{[]string{"unlock", "mouse4"}, "km.lock_ms1(0)"},
+		{[]string{"raw", "km.echo(1)"}, "km.echo(1)"},
+	}
+	for _, tt := range tests {
+		h := newCLIHarness()
+		if code := h.run(tt.args...); code != cli.ExitOK {
+			t.Errorf("%v: exit %d, stderr %q", tt.args, code, h.stderr.String())
+			continue
+		}
+		if got := h.ft.joined(); got != tt.want {
+			t.Errorf("%v: sent %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestCLIInfoJSON(t *testing.T) {
+	h := newCLIHarness()
+	h.ft.responses["km.version()"] = "km.MAKCU v3.2"
+	if code := h.run("-json", "info"); code != cli.ExitOK {
+		t.Fatalf("exit %d, stderr %q", code, h.stderr.String())
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal(h.stdout.Bytes(), &out); err != nil {
+		t.Fatalf("invalid JSON %q: %v", h.stdout.String(), err)
+	}
+	if out["firmware"] != "km.MAKCU v3.2" || out["port"] != "FAKE" {
+		t.Errorf("info = %v", out)
+	}
+}
+
+func TestCLIListMarksMakcuPorts(t *testing.T) {
+	h := newCLIHarness()
+	if code := h.run("list"); code != cli.ExitOK {
+		t.Fatalf("exit %d", code)
+	}
+	lines := strings.Split(strings.TrimSpace(h.stdout.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[1], "* /dev/ttyACM0") {
+		t.Errorf("list output = %q", h.stdout.String())
+	}
+}
+
+func TestCLILocksJSON(t *testing.T) {
+	h := newCLIHarness()
+	for _, q := range []string{"ml", "mr", "mm", "ms1", "ms2", "mx", "my"} {
+		h.ft.responses["km.lock_"+q+"()"] = "0"
+	}
+	h.ft.responses["km.lock_my()"] = "1"
+	if code := h.run("-json", "locks"); code != cli.ExitOK {
+		t.Fatalf("exit %d, stderr %q", code, h.stderr.String())
+	}
+	var states map[string]bool
+	if err := json.Unmarshal(h.stdout.Bytes(), &states); err != nil {
+		t.Fatalf("invalid JSON %q: %v", h.stdout.String(), err)
+	}
+	if !states["Y"] || states["X"] || len(states) != 7 {
+		t.Errorf("locks = %v", states)
+	}
+}
+
+func TestCLIRawResponse(t *testing.T) {
+	h := newCLIHarness()
+	h.ft.responses["km.version()"] = "km.MAKCU"
+	if code := h.run("raw", "-response", "km.version()"); code != cli.ExitOK {
+		t.Fatalf("exit %d", code)
+	}
+	if got := strings.TrimSpace(h.stdout.String()); got != "km.MAKCU" {
+		t.Errorf("raw output = %q", got)
+	}
+}
+
+func TestCLIMonitorStreamsEvents(t *testing.T) {
+	h := newCLIHarness()
+	pressed := make(chan struct{})
+	go func() {
+		defer close(pressed)
+		time.Sleep(20 * time.Millisecond)
+		h.ft.setMask(1)
+		h.ft.setMask(0)
+	}()
+	if code := h.run("-json", "monitor", "-duration", "60ms"); code != cli.ExitOK {
+		t.Fatalf("exit %d, stderr %q", code, h.stderr.String())
+	}
+	<-pressed
+	dec := json.NewDecoder(&h.stdout)
+	var events []map[string]interface{}
+	for dec.More() {
+		var ev map[string]interface{}
+		if err := dec.Decode(&ev); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		events = append(events, ev)
+	}
+	if len(events) != 2 || events[0]["pressed"] != true || events[1]["button"] != "left" {
+		t.Errorf("events = %v", events)
+	}
+	if got := h.ft.joined(); got != "km.buttons(1)" {
+		t.Errorf("sent %q, want monitoring enabled", got)
+	}
+}
+
+func TestCLIExitCodes(t *testing.T) {
+	h := newCLIHarness()
+	if code := h.run("move", "x", "1"); code != cli.ExitUsage {
+		t.Errorf("bad argument exit = %d, want %d", code, cli.ExitUsage)
+	}
+	if code := h.run("frobnicate"); code != cli.ExitUsage {
+		t.Errorf("unknown command exit = %d, want %d", code, cli.ExitUsage)
+	}
+	if code := h.run("raw", "-response", "km.nothing()"); code != cli.ExitTimeout {
+		t.Errorf("timeout exit = %d, want %d", code, cli.ExitTimeout)
+	}
+
+	h = newCLIHarness()
+	h.app.Connect = func(Macku.Config) (*Macku.MakcuController, error) {
+		return nil, Macku.NewConnectionError("Makcu device not found")
+	}
+	if code := h.run("-json", "info"); code != cli.ExitConnection {
+		t.Errorf("connection failure exit = %d, want %d", code, cli.ExitConnection)
+	}
+	if !strings.Contains(h.stderr.String(), `"exit_code":3`) {
+		t.Errorf("JSON error output = %q", h.stderr.String())
+	}
+
+	if got := cli.ExitCode(Macku.NewCommandError("bad")); got != cli.ExitCommand {
+		t.Errorf("ExitCode(command error) = %d, want %d", got, cli.ExitCommand)
+	}
+	if got := cli.ExitCode(Macku.NewResponseError("bad")); got != cli.ExitResponse {
+		t.Errorf("ExitCode(response error) = %d, want %d", got, cli.ExitResponse)
+	}
+}
+
+func TestCLIClickCountBounded(t *testing.T) {
+	h := newCLIHarness()
+	over := fmt.Sprint(Macku.MaxClickCount + 1)
+	if code := h.run("click", "-count", over); code != cli.ExitUsage {
+		t.Errorf("click -count %s exit = %d, want %d", over, code, cli.ExitUsage)
+	}
+	if cmds := h.ft.commands(); len(cmds) != 0 {
+		t.Errorf("oversized click sent %q", cmds)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if code := h.app.Run(ctx, []string{"click", "-count", "5"}); code == cli.ExitOK {
+		t.Error("click with a cancelled context succeeded")
+	}
+	if cmds := h.ft.commands(); len(cmds) != 0 {
+		t.Errorf("cancelled click sent %q", cmds)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// makcu console tests
+// ---------------------------------------------------------------------------
+
+func TestConsoleSession(t *testing.T) {
+	h := newCLIHarness()
+	h.ft.responses["km.version()"] = "km.MAKCU"
+	h.app.Stdin = strings.NewReader(strings.Join([]string{
+		"km.move(5,5)",
+		"km.version()",
+		":trace on",
+		"km.version()",
+		":timeout 250ms",
+		"km.lock_mx()",
+		":history",
+		":bogus",
+		":quit",
+		"km.move(9,9)",
+	}, "\n"))
+
+	if code := h.run("console"); code != cli.ExitOK {
+		t.Fatalf("exit %d, stderr %q", code, h.stderr.String())
+	}
+	out := h.stdout.String()
+	for _, want := range []string{
+		"km.MAKCU\n",
+		"trace on\n",
+		"-> km.version()\n",
+		"<- km.MAKCU (",
+		"timeout 250ms\n",
+		"error: command timed out: km.lock_mx()\n",
+		"   1  km.move(5,5)\n",
+		"unknown meta-command :bogus",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("console output missing %q:\n%s", want, out)
+		}
+	}
+	if got := h.ft.joined(); got != "km.buttons(1) km.move(5,5) km.version() km.version() km.lock_mx()" {
+		t.Errorf("sent %q", got)
+	}
+}
+
+func TestConsoleTraceUsesConsoleOutput(t *testing.T) {
+	h := newCLIHarness()
+	dev := emulator.New()
+	h.app.Connect = func(Macku.Config) (*Macku.MakcuController, error) {
+		c := Macku.NewControllerWithTransport(Macku.NewStreamTransport("emulator", dev.Open, false, true, false))
+		return c, c.Connect()
+	}
+	h.app.Stdin = strings.NewReader(":trace on\nkm.version()\n:quit\n")
+
+	if code := h.run("console"); code != cli.ExitOK {
+		t.Fatalf("exit %d, stderr %q", code, h.stderr.String())
+	}
+	if out := h.stdout.String(); !strings.Contains(out, "[INFO] ") {
+		t.Errorf("transport debug output not on the console:\n%s", out)
+	}
+}
+
+func TestConsoleShowsButtonEvents(t *testing.T) {
+	h := newCLIHarness()
+	r, w := io.Pipe()
+	h.app.Stdin = r
+	done := make(chan int)
+	go func() { done <- h.run("console") }()
+
+	time.Sleep(20 * time.Millisecond)
+	h.ft.setMask(1 << 2)
+	fmt.Fprintln(w, ":events off")
+	time.Sleep(20 * time.Millisecond)
+	h.ft.setMask(0)
+	w.Close()
+
+	if code := <-done; code != cli.ExitOK {
+		t.Fatalf("exit %d", code)
+	}
+	out := h.stdout.String()
+	if !strings.Contains(out, "[event] middle pressed") {
+		t.Errorf("missing press event:\n%s", out)
+	}
+	if strings.Contains(out, "[event] middle released") {
+		t.Errorf("event shown after :events off:\n%s", out)
+	}
+}
+
+func TestConsoleComplete(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    string
+		wantPos int
+		ok      bool
+	}{
+		{"km.ve", "km.version(", 11, true},
+		{"km.version(", "km.version()", 12, true},
+		{"km.lock_m", "km.lock_m", 0, false},     // ambiguous, no longer common prefix
+		{"km.lock_mx(", "km.lock_mx(", 0, false}, // ")", "0)", "1)" share nothing
+		{"km.mi", "km.middle(", 10, true},
+		{"km.middle(1", "km.middle(1)", 12, true},
+		{":tr", ":trace ", 7, true},
+		{":qu", ":quit", 5, true},
+		{":trace o", ":trace o", 0, false},
+		{":trace of", ":trace off", 10, true},
+		{"zzz", "zzz", 0, false},
+	}
+	for _, tt := range tests {
+		got, pos, ok := cli.Complete(tt.line, len(tt.line), '\t')
+		if ok != tt.ok || (ok && (got != tt.want || pos != tt.wantPos)) {
+			t.Errorf("Complete(%q) = %q, %d, %v; want %q, %d, %v", tt.line, got, pos, ok, tt.want, tt.wantPos, tt.ok)
+		}
+	}
+	if _, _, ok := cli.Complete("km.ve", 5, 'x'); ok {
+		t.Error("Complete handled a non-tab key")
+	}
+}
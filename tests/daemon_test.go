@@ -0,0 +1,267 @@
+package lib_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	Macku "github.com/Auchrio/Makcu-go-lib/v3"
+	"github.com/Auchrio/Makcu-go-lib/v3/daemon"
+	"github.com/Auchrio/Makcu-go-lib/v3/emulator"
+)
+
+// ---------------------------------------------------------------------------
+// Daemon harness
+// ---------------------------------------------------------------------------
+
+// startDaemon serves a fake-backed controller on a temporary Unix socket.
+func startDaemon(t *testing.T) (string, *daemon.Server, *fakeTransport) {
+	t.Helper()
+	c, ft := newFakeController()
+	path, srv := serveDaemon(t, c)
+	return path, srv, ft
+}
+
+// serveDaemon serves c on a temporary Unix socket.
+func serveDaemon(t *testing.T, c *Macku.MakcuController) (string, *daemon.Server) {
+	t.Helper()
+	// Unix socket paths are length-limited, so avoid the long t.TempDir names.
+	dir, err := os.MkdirTemp("", "makcud")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "d.sock")
+
+	srv := daemon.NewServer(c)
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve(l) }()
+	t.Cleanup(func() {
+		srv.Close()
+		if err := <-served; err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+	})
+	return path, srv
+}
+
+// daemonController connects a controller to the daemon through a Client.
+func daemonController(t *testing.T, path string) *Macku.MakcuController {
+	t.Helper()
+	c := Macku.NewControllerWithTransport(daemon.NewClient(path))
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	t.Cleanup(func() { c.Disconnect() })
+	return c
+}
+
+// rawCall sends one request line and returns the next response line.
+func rawCall(t *testing.T, conn net.Conn, r *bufio.Reader, line string) daemon.Message {
+	t.Helper()
+	if _, err := conn.Write([]byte(line + "\n")); err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	resp, err := r.ReadBytes('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg daemon.Message
+	if err := json.Unmarshal(resp, &msg); err != nil {
+		t.Fatalf("bad response %q: %v", resp, err)
+	}
+	return msg
+}
+
+// ---------------------------------------------------------------------------
+// Client transport
+// ---------------------------------------------------------------------------
+
+func TestDaemonClientForwardsCommands(t *testing.T) {
+	path, _, ft := startDaemon(t)
+	ft.responses["km.version()"] = "km.MAKCU"
+	c := daemonController(t, path)
+
+	if err := c.Move(10, -5); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Click(Macku.MouseButtonRight); err != nil {
+		t.Fatal(err)
+	}
+	if got := ft.joined(); got != "km.move(10,-5) km.right(1) km.right(0)" {
+		t.Errorf("commands = %q", got)
+	}
+
+	v, err := c.GetFirmwareVersion()
+	if err != nil || v != "km.MAKCU" {
+		t.Errorf("GetFirmwareVersion = %q, %v", v, err)
+	}
+	if !strings.Contains(c.Transport.PortName(), "FAKE") {
+		t.Errorf("PortName = %q", c.Transport.PortName())
+	}
+}
+
+func TestDaemonClientPreservesErrorClass(t *testing.T) {
+	path, _, ft := startDaemon(t)
+	c := daemonController(t, path)
+
+	if _, err := c.Transport.SendCommand("km.unknown()", true, 50*time.Millisecond); !errors.Is(err, Macku.ErrTimeout) {
+		t.Errorf("unanswered query: err = %v, want ErrTimeout", err)
+	}
+	ft.mu.Lock()
+	ft.sendErr = Macku.NewConnectionError("port gone")
+	ft.mu.Unlock()
+	if err := c.Move(1, 1); !errors.Is(err, Macku.ErrConnection) {
+		t.Errorf("device failure: err = %v, want ErrConnection", err)
+	}
+}
+
+func TestDaemonClientReceivesButtonEvents(t *testing.T) {
+	path, _, ft := startDaemon(t)
+	c := daemonController(t, path)
+
+	events := make(chan string, 4)
+	c.SetButtonCallback(func(b Macku.MouseButton, pressed bool) {
+		events <- b.String() + map[bool]string{true: "+", false: "-"}[pressed]
+	})
+
+	ft.setMask(1 << uint(Macku.MouseButtonMiddle))
+	ft.setMask(0)
+	for _, want := range []string{"middle+", "middle-"} {
+		select {
+		case got := <-events:
+			if got != want {
+				t.Errorf("event = %q, want %q", got, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for %q", want)
+		}
+	}
+	if mask, _ := c.GetButtonMask(); mask != 0 {
+		t.Errorf("mask = %d after release", mask)
+	}
+}
+
+func TestDaemonSharesDeviceBetweenClients(t *testing.T) {
+	path, _, ft := startDaemon(t)
+	a := daemonController(t, path)
+	b := daemonController(t, path)
+
+	if err := a.Scroll(3); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Lock(Macku.LockX); err != nil {
+		t.Fatal(err)
+	}
+	if got := ft.joined(); got != "km.wheel(3) km.lock_mx(1)" {
+		t.Errorf("commands = %q", got)
+	}
+}
+
+func TestDaemonClientKeepsFramedMonitoring(t *testing.T) {
+	dev := emulator.New()
+	served, st := framingController(t, dev)
+	path, _ := serveDaemon(t, served)
+	c := daemonController(t, path)
+
+	for _, enable := range []bool{false, true} {
+		if err := c.EnableButtonMonitoring(enable); err != nil {
+			t.Fatal(err)
+		}
+	}
+	flush(t, served)
+	if !dev.Framed() || !st.ButtonFraming() {
+		t.Error("a daemon client switched the shared device to raw masks")
+	}
+	if cmds := dev.Commands(); !slices.Equal(cmds[len(cmds)-3:len(cmds)-1], []string{"km.buttons(0)", "km.buttons(2)"}) {
+		t.Errorf("commands = %q", cmds)
+	}
+}
+
+func TestDaemonClientSeesServerShutdown(t *testing.T) {
+	path, srv, _ := startDaemon(t)
+	c := daemonController(t, path)
+
+	srv.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for c.Transport.IsConnected() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if _, err := c.Transport.SendCommand("km.move(1,1)", false, 0); !errors.Is(err, Macku.ErrConnection) {
+		t.Errorf("after shutdown: err = %v, want ErrConnection", err)
+	}
+}
+
+func TestDaemonClientConnectFailure(t *testing.T) {
+	c := Macku.NewControllerWithTransport(daemon.NewClient(filepath.Join(os.TempDir(), "no-such-makcud.sock")))
+	if err := c.Connect(); !errors.Is(err, Macku.ErrConnection) {
+		t.Errorf("Connect: err = %v, want ErrConnection", err)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Wire protocol
+// ---------------------------------------------------------------------------
+
+func TestDaemonWireProtocol(t *testing.T) {
+	path, _, ft := startDaemon(t)
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	msg := rawCall(t, conn, r, `{"id":1,"method":"click","params":{"button":"left"}}`)
+	if msg.ID != 1 || msg.Error != nil || string(msg.Result) != "true" {
+		t.Errorf("click = %+v", msg)
+	}
+	if got := ft.joined(); got != "km.left(1) km.left(0)" {
+		t.Errorf("commands = %q", got)
+	}
+
+	tests := []struct {
+		line string
+		code string
+	}{
+		{`{"id":2,"method":"click","params":{"button":"thumb"}}`, daemon.CodeCommand},
+		{`{"id":3,"method":"teleport"}`, daemon.CodeInvalid},
+		{`{"id":4,"method":"move"}`, daemon.CodeInvalid},
+		{`{"id":5,"method":"send","params":{"command":"km.nope()","expect_response":true,"timeout_ms":10}}`, daemon.CodeTimeout},
+		{`not json`, daemon.CodeInvalid},
+	}
+	for _, tt := range tests {
+		msg := rawCall(t, conn, r, tt.line)
+		if msg.Error == nil || msg.Error.Code != tt.code {
+			t.Errorf("%s: error = %+v, want code %q", tt.line, msg.Error, tt.code)
+		}
+	}
+
+	msg = rawCall(t, conn, r, `{"id":6,"method":"subscribe","params":{"events":["button"]}}`)
+	if msg.Error != nil {
+		t.Fatalf("subscribe: %+v", msg.Error)
+	}
+	ft.setMask(1 << uint(Macku.MouseButton4))
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ev daemon.Message
+	json.Unmarshal(line, &ev)
+	if ev.Event == nil || ev.Event.Button != "mouse4" || !ev.Event.Pressed || ev.Event.Mask != 8 {
+		t.Errorf("event line = %s", line)
+	}
+}
@@ -0,0 +1,159 @@
+package lib_test
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	Macku "github.com/Auchrio/Makcu-go-lib/v3"
+	"github.com/Auchrio/Makcu-go-lib/v3/protocol"
+)
+
+// ---------------------------------------------------------------------------
+// Fake transport
+// ---------------------------------------------------------------------------
+
+// fakeTransport is an in-memory Macku.Transport that records every command
+// and answers queries from a fixed response table.
+type fakeTransport struct {
+	mu        sync.Mutex
+	connected bool
+	sent      []string
+	responses map[string]string // query command -> response
+	sendErr   error
+	callback  func(Macku.MouseButton, bool)
+	mask      int
+}
+
+func newFakeTransport() *fakeTransport {
+	return &fakeTransport{responses: make(map[string]string)}
+}
+
+// newFakeController returns a connected controller backed by a fakeTransport.
+func newFakeController() (*Macku.MakcuController, *fakeTransport) {
+	ft := newFakeTransport()
+	c := Macku.NewControllerWithTransport(ft)
+	if err := c.Connect(); err != nil {
+		panic(err)
+	}
+	return c, ft
+}
+
+func (f *fakeTransport) Connect() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.connected = true
+	return nil
+}
+
+func (f *fakeTransport) Disconnect() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.connected = false
+	return nil
+}
+
+func (f *fakeTransport) IsConnected() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.connected
+}
+
+func (f *fakeTransport) SendCommand(command string, expectResponse bool, timeout time.Duration) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.sendErr != nil {
+		return "", f.sendErr
+	}
+	f.sent = append(f.sent, command)
+	if !expectResponse {
+		return command, nil
+	}
+	resp, ok := f.responses[command]
+	if !ok {
+		return "", Macku.NewTimeoutError("command timed out: " + command)
+	}
+	return resp, nil
+}
+
+func (f *fakeTransport) SetButtonCallback(cb func(Macku.MouseButton, bool)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.callback = cb
+}
+
+func (f *fakeTransport) GetButtonStates() map[string]bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	states := make(map[string]bool, Macku.MaxButtons)
+	for b := Macku.MouseButtonLeft; b <= Macku.MouseButton8; b++ {
+		states[b.String()] = f.mask&(1<<uint(b)) != 0
+	}
+	return states
+}
+
+func (f *fakeTransport) GetButtonMask() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.mask
+}
+
+func (f *fakeTransport) EnableButtonMonitoring(enable bool) error {
+	cmd := "km.buttons(0)"
+	if enable {
+		cmd = "km.buttons(1)"
+	}
+	_, err := f.SendCommand(cmd, false, 0)
+	return err
+}
+
+func (f *fakeTransport) PortName() string { return "FAKE" }
+
+// setMask updates the button mask and fires the callback for changed bits.
+func (f *fakeTransport) setMask(mask int) {
+	f.mu.Lock()
+	old := f.mask
+	f.mask = mask
+	cb := f.callback
+	f.mu.Unlock()
+	if cb == nil {
+		return
+	}
+	for bit := 0; bit < Macku.MaxButtons; bit++ {
+		if (old^mask)&(1<<bit) != 0 {
+			cb(Macku.MouseButton(bit), mask&(1<<bit) != 0)
+		}
+	}
+}
+
+// answerLockQueries answers every lock query with "0", or "1" for the
+// named protocol targets (e.g. "mx").
+func (f *fakeTransport) answerLockQueries(locked ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, name := range protocol.LockNames {
+		f.responses["km.lock_"+name+"()"] = "0"
+	}
+	for _, name := range locked {
+		f.responses["km.lock_"+name+"()"] = "1"
+	}
+}
+
+// commands returns a copy of the commands sent so far.
+func (f *fakeTransport) commands() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.sent...)
+}
+
+// joined returns the commands sent so far separated by spaces.
+func (f *fakeTransport) joined() string {
+	return strings.Join(f.commands(), " ")
+}
+
+// reset clears the sent-command log.
+func (f *fakeTransport) reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = nil
+}
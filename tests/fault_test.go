@@ -0,0 +1,246 @@
+package lib_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	Macku "github.com/Auchrio/Makcu-go-lib/v3"
+	"github.com/Auchrio/Makcu-go-lib/v3/emulator"
+	"github.com/Auchrio/Makcu-go-lib/v3/fault"
+)
+
+// ---------------------------------------------------------------------------
+// Fault injection harness
+// ---------------------------------------------------------------------------
+
+// chunkPort is a Port that reads a fixed stream in chunks of the given size.
+type chunkPort struct {
+	data  []byte
+	chunk int
+}
+
+func (p *chunkPort) Read(b []byte) (int, error) {
+	n := min(p.chunk, len(p.data), len(b))
+	copy(b, p.data[:n])
+	p.data = p.data[n:]
+	return n, nil
+}
+
+func (p *chunkPort) Write(b []byte) (int, error)        { return len(b), nil }
+func (p *chunkPort) Close() error                       { return nil }
+func (p *chunkPort) SetReadTimeout(time.Duration) error { return nil }
+
+// drain reads everything p delivers from src until both go quiet.
+func drain(p Macku.Port, src *chunkPort) ([]byte, error) {
+	var out []byte
+	buf := make([]byte, 7)
+	for idle := 0; idle < 3; {
+		n, err := p.Read(buf)
+		if err != nil {
+			return out, err
+		}
+		if n == 0 && len(src.data) == 0 {
+			idle++
+		}
+		out = append(out, buf[:n]...)
+	}
+	return out, nil
+}
+
+// faultController connects a controller to dev through a fault transport
+// over a clean link, then starts sc.
+func faultController(t *testing.T, dev *emulator.Device, sc fault.Scenario) (*Macku.MakcuController, *fault.Transport) {
+	t.Helper()
+	ft := fault.NewTransport(dev.Open, fault.Scenario{}, false, true, true)
+	c := Macku.NewControllerWithTransport(ft)
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Disconnect() })
+	ft.Faults.SetScenario(sc)
+	return c, ft
+}
+
+// ---------------------------------------------------------------------------
+// Scenarios
+// ---------------------------------------------------------------------------
+
+func TestFaultScenarioIsDeterministic(t *testing.T) {
+	stream := bytes.Repeat([]byte(">>> km.MAKCU\r\n\x02\x00>>> 1\r\n"), 40)
+	sc := fault.Scenario{
+		Seed:                  7,
+		DropRate:              0.02,
+		CorruptRate:           0.02,
+		DuplicateRate:         0.02,
+		DropResponseRate:      0.1,
+		DuplicateResponseRate: 0.1,
+		ReorderResponseRate:   0.1,
+	}
+	run := func(sc fault.Scenario, chunk int) ([]byte, fault.Stats) {
+		inj := fault.New(sc)
+		src := &chunkPort{data: stream, chunk: chunk}
+		out, err := drain(inj.Wrap(src), src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out, inj.Stats()
+	}
+
+	out1, stats1 := run(sc, 3)
+	out2, stats2 := run(sc, 64)
+	if !bytes.Equal(out1, out2) || stats1 != stats2 {
+		t.Errorf("same seed, different chunking: %+v vs %+v", stats1, stats2)
+	}
+	if bytes.Equal(out1, stream) || stats1.BytesDropped == 0 || stats1.ResponsesReordered == 0 {
+		t.Errorf("scenario injected nothing: %+v", stats1)
+	}
+	sc.Seed = 8
+	if out3, _ := run(sc, 3); bytes.Equal(out1, out3) {
+		t.Error("different seeds produced the same faults")
+	}
+	if out, stats := run(fault.Scenario{Seed: 7}, 5); !bytes.Equal(out, stream) || stats != (fault.Stats{}) {
+		t.Errorf("zero scenario altered the stream: %+v", stats)
+	}
+}
+
+func TestFaultReadErrorTriggersReconnect(t *testing.T) {
+	dev := emulator.New()
+	c, ft := faultController(t, dev, fault.Scenario{ReadErrorAfter: 5})
+
+	// The read fails mid-response, so the query times out while the
+	// listener reconnects.
+	if _, err := c.GetFirmwareVersion(); !errors.Is(err, Macku.ErrTimeout) {
+		t.Fatalf("GetFirmwareVersion = %v, want timeout", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for ft.Metrics().Reconnects == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("no reconnect: %+v", ft.Metrics())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	ft.Faults.SetScenario(fault.Scenario{})
+	flush(t, c)
+	if s := ft.Faults.Stats(); s.ReadErrors != 1 {
+		t.Errorf("ReadErrors = %d, want 1", s.ReadErrors)
+	}
+	if !c.IsConnected() || !dev.Monitoring() {
+		t.Error("transport did not come back with button monitoring")
+	}
+}
+
+func TestFaultDroppedResponseTimesOut(t *testing.T) {
+	c, ft := faultController(t, emulator.New(), fault.Scenario{DropResponseRate: 1})
+
+	if _, err := c.GetFirmwareVersion(); !errors.Is(err, Macku.ErrTimeout) {
+		t.Fatalf("GetFirmwareVersion = %v, want timeout", err)
+	}
+	ft.Faults.SetScenario(fault.Scenario{})
+	flush(t, c) // the timed-out query left nothing pending
+	if m := ft.Metrics(); m.Timeouts != 1 {
+		t.Errorf("Timeouts = %d, want 1", m.Timeouts)
+	}
+}
+
+func TestFaultCleanupDropsLostQueries(t *testing.T) {
+	dev := emulator.New()
+	c, ft := faultController(t, dev, fault.Scenario{DropResponseRate: 1})
+
+	// The answer is lost; the listener drops the query as stale once it
+	// next reads data after a second, before the caller's deadline.
+	errc := make(chan error, 1)
+	go func() {
+		_, err := c.Transport.SendCommand("km.version()", true, 1500*time.Millisecond)
+		errc <- err
+	}()
+	time.Sleep(1100 * time.Millisecond)
+	ft.Faults.SetScenario(fault.Scenario{})
+	dev.SetButtons(1)
+	if err := <-errc; !errors.Is(err, Macku.ErrTimeout) {
+		t.Fatalf("SendCommand = %v, want timeout", err)
+	}
+	if m := ft.Metrics(); m.StaleCommands != 1 {
+		t.Errorf("StaleCommands = %d, want 1", m.StaleCommands)
+	}
+	flush(t, c)
+}
+
+func TestFaultWriteFailures(t *testing.T) {
+	dev := emulator.New()
+	c, ft := faultController(t, dev, fault.Scenario{WriteErrorRate: 1})
+
+	if err := c.Move(1, 1); !errors.Is(err, fault.ErrInjected) {
+		t.Errorf("Move = %v, want injected error", err)
+	}
+	if _, err := c.GetFirmwareVersion(); !errors.Is(err, fault.ErrInjected) {
+		t.Errorf("GetFirmwareVersion = %v, want injected error", err)
+	}
+
+	ft.Faults.SetScenario(fault.Scenario{WriteDropRate: 1})
+	if err := c.Move(2, 2); err != nil {
+		t.Errorf("Move with dropped write = %v", err)
+	}
+
+	ft.Faults.SetScenario(fault.Scenario{WriteStallRate: 1, WriteStall: 80 * time.Millisecond})
+	start := time.Now()
+	c.Move(3, 3)
+	if d := time.Since(start); d < 80*time.Millisecond {
+		t.Errorf("stalled Move returned after %v", d)
+	}
+
+	ft.Faults.SetScenario(fault.Scenario{})
+	flush(t, c)
+	if x, y := dev.Position(); x != 3 || y != 3 {
+		t.Errorf("device position = %d,%d, want only the stalled move", x, y)
+	}
+	if s := ft.Faults.Stats(); s.WriteErrors != 2 || s.WritesDropped != 1 || s.WritesStalled != 1 {
+		t.Errorf("Stats = %+v", s)
+	}
+}
+
+// TestFaultSoak drives a controller through a mix of every fault and checks
+// that no call hangs and the transport recovers once the link is clean.
+func TestFaultSoak(t *testing.T) {
+	dev := emulator.New()
+	c, ft := faultController(t, dev, fault.Scenario{
+		Seed:                  42,
+		DropRate:              0.01,
+		CorruptRate:           0.01,
+		DuplicateRate:         0.01,
+		DropResponseRate:      0.1,
+		DuplicateResponseRate: 0.1,
+		ReorderResponseRate:   0.1,
+		DelayResponseRate:     0.1,
+		ResponseDelay:         30 * time.Millisecond,
+		ReadErrorRate:         0.002,
+		WriteStallRate:        0.05,
+		WriteStall:            5 * time.Millisecond,
+		WriteDropRate:         0.02,
+	})
+
+	for i := 0; i < 60; i++ {
+		start := time.Now()
+		c.Move(1, -1)
+		c.IsLocked(Macku.MouseButtonLeft)
+		c.Transport.SendCommand("km.version()", true, 50*time.Millisecond)
+		if d := time.Since(start); d > time.Second {
+			t.Fatalf("iteration %d took %v", i, d)
+		}
+	}
+
+	ft.Faults.SetScenario(fault.Scenario{})
+	time.Sleep(3 * reconnectGrace)
+	flush(t, c)
+	if m := ft.Metrics(); m.Timeouts == 0 {
+		t.Errorf("soak produced no timeouts: %+v", m)
+	}
+	if s := ft.Faults.Stats(); s.ResponsesDropped == 0 || s.WritesDropped == 0 {
+		t.Errorf("soak injected too little: %+v", s)
+	}
+}
+
+// reconnectGrace covers a reconnect in progress (SerialTransport waits
+// 100ms before reopening).
+const reconnectGrace = 100 * time.Millisecond
@@ -0,0 +1,188 @@
+package lib_test
+
+import (
+	"errors"
+	"testing"
+
+	Macku "github.com/Auchrio/Makcu-go-lib/v3"
+	"github.com/Auchrio/Makcu-go-lib/v3/emulator"
+	"github.com/Auchrio/Makcu-go-lib/v3/fault"
+)
+
+// ---------------------------------------------------------------------------
+// Firmware version and capabilities
+// ---------------------------------------------------------------------------
+
+func TestParseFirmwareVersion(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Macku.FirmwareVersion
+	}{
+		{"km.MAKCU", Macku.FirmwareVersion{Raw: "km.MAKCU"}},
+		{"km.MAKCU v3.4.1 build 20250612 (right)", Macku.FirmwareVersion{
+			Raw: "km.MAKCU v3.4.1 build 20250612 (right)", Major: 3, Minor: 4, Patch: 1,
+			Build: "20250612", Board: "right", Known: true}},
+		{" MAKCU 3.2+a1b2 LEFT\r", Macku.FirmwareVersion{
+			Raw: "MAKCU 3.2+a1b2 LEFT", Major: 3, Minor: 2, Build: "a1b2", Board: "left", Known: true}},
+		{"v10.0.12", Macku.FirmwareVersion{Raw: "v10.0.12", Major: 10, Patch: 12, Known: true}},
+	}
+	for _, tt := range tests {
+		if got := Macku.ParseFirmwareVersion(tt.in); got != tt.want {
+			t.Errorf("ParseFirmwareVersion(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFirmwareVersionAtLeast(t *testing.T) {
+	v := Macku.ParseFirmwareVersion("v3.4.1")
+	for _, tt := range []struct {
+		major, minor, patch int
+		want                bool
+	}{
+		{3, 4, 1, true}, {3, 4, 2, false}, {3, 3, 9, true}, {4, 0, 0, false}, {2, 9, 9, true},
+	} {
+		if got := v.AtLeast(tt.major, tt.minor, tt.patch); got != tt.want {
+			t.Errorf("AtLeast(%d,%d,%d) = %v, want %v", tt.major, tt.minor, tt.patch, got, tt.want)
+		}
+	}
+	if Macku.ParseFirmwareVersion("km.MAKCU").AtLeast(0, 0, 0) {
+		t.Error("unknown version reported as at least 0.0.0")
+	}
+}
+
+// detectedController connects to dev and resolves its capabilities.
+func detectedController(t *testing.T, dev *emulator.Device) (*Macku.MakcuController, Macku.Capabilities) {
+	t.Helper()
+	c := emulatedController(t, dev)
+	caps, err := c.DetectCapabilities()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, caps
+}
+
+func TestCapabilitiesVersionedFirmware(t *testing.T) {
+	dev := emulator.New()
+	dev.Version = "km.MAKCU v3.4.1 build 42 (left)"
+	c, caps := detectedController(t, dev)
+
+	if !caps.Resolved || caps.Firmware.Board != "left" || caps.Firmware.Build != "42" || caps.FramedButtons {
+		t.Errorf("caps = %+v", caps)
+	}
+	if !caps.Supports("km.ms2()") || caps.Supports("ms3") || !caps.Supports("km.move(1,2)") {
+		t.Error("Supports disagrees with the capability table")
+	}
+	if err := c.Click(Macku.MouseButton5); err != nil {
+		t.Errorf("Click(MOUSE5) = %v", err)
+	}
+	if caps.MaxMoveSegments != 512 || !caps.Supports("catch_ml") || !caps.Supports("serial") {
+		t.Errorf("3.4 capabilities = %+v", caps)
+	}
+	if err := c.Mouse.MoveSmooth(10, 10, 512); err != nil {
+		t.Errorf("MoveSmooth at the segment limit = %v", err)
+	}
+}
+
+func TestCapabilitiesOlderFirmwareRefused(t *testing.T) {
+	dev := emulator.New()
+	dev.Version = "km.MAKCU v3.1.0"
+	c, caps := detectedController(t, dev)
+	if !caps.Resolved || caps.Supports("catch_ml") || caps.Supports("serial") || caps.MaxMoveSegments != 512 {
+		t.Fatalf("3.1 capabilities = %+v", caps)
+	}
+	before := len(dev.Commands())
+
+	checks := map[string]error{
+		"SetCatch":    c.Mouse.SetCatch(Macku.MouseButtonLeft, true),
+		"SpoofSerial": c.Mouse.SpoofSerial("ABC"),
+		"ResetSerial": c.Mouse.ResetSerial(),
+		"MoveSmooth":  c.Mouse.MoveSmooth(10, 10, 513),
+		"MoveBezier":  c.Mouse.MoveBezier(10, 10, 600, 5, 5),
+	}
+	for name, err := range checks {
+		if !errors.Is(err, Macku.ErrUnsupported) {
+			t.Errorf("%s = %v, want ErrUnsupported", name, err)
+		}
+	}
+	if cmds := dev.Commands()[before:]; len(cmds) != 0 {
+		t.Errorf("unsupported commands sent %q", cmds)
+	}
+	if err := c.Mouse.LockLeft(true); err != nil {
+		t.Errorf("LockLeft = %v", err)
+	}
+}
+
+func TestCapabilitiesSegmentLimitRaised(t *testing.T) {
+	dev := emulator.New()
+	dev.Version = "km.MAKCU v3.5.0"
+	c, caps := detectedController(t, dev)
+	if caps.MaxMoveSegments != 1024 {
+		t.Errorf("MaxMoveSegments = %d, want 1024", caps.MaxMoveSegments)
+	}
+	if err := c.Mouse.MoveSmooth(10, 10, 1024); err != nil {
+		t.Errorf("MoveSmooth(1024 segments) = %v", err)
+	}
+	if err := c.Mouse.MoveSmooth(10, 10, 1025); !errors.Is(err, Macku.ErrUnsupported) {
+		t.Errorf("MoveSmooth(1025 segments) = %v, want ErrUnsupported", err)
+	}
+}
+
+func TestCapabilitiesUnversionedFirmware(t *testing.T) {
+	dev := emulator.New()
+	c, caps := detectedController(t, dev)
+
+	if caps.Resolved || caps.Firmware.Known || !caps.Supports("lock_ms2") || !caps.Supports("catch_ml") {
+		t.Errorf("caps = %+v", caps)
+	}
+	if err := c.Mouse.MoveSmooth(1, 1, 5000); err != nil {
+		t.Errorf("MoveSmooth on unversioned firmware = %v", err)
+	}
+	v, err := c.GetFirmwareVersionInfo()
+	if err != nil || v.Raw != emulator.DefaultVersion {
+		t.Errorf("GetFirmwareVersionInfo = %+v, %v", v, err)
+	}
+}
+
+func TestCapabilitiesNotGatedBeforeDetection(t *testing.T) {
+	if Macku.DefaultConfig().DetectFirmware {
+		t.Error("DefaultConfig detects firmware; it is opt-in")
+	}
+	c, ft := newFakeController()
+	if c.Capabilities().Resolved {
+		t.Fatal("capabilities resolved without detection")
+	}
+	if err := c.Mouse.MoveSmooth(1, 1, 5000); err != nil {
+		t.Errorf("MoveSmooth = %v", err)
+	}
+	if err := c.Mouse.SpoofSerial("ABC"); err != nil || len(ft.commands()) != 2 {
+		t.Errorf("SpoofSerial = %v, sent %q", err, ft.commands())
+	}
+}
+
+func TestConnectDetectsFirmware(t *testing.T) {
+	dev := emulator.New()
+	dev.Version = "km.MAKCU v3.5.0"
+	c := Macku.NewControllerWithTransport(Macku.NewStreamTransport("emulator", dev.Open, false, true, false))
+	c.SetDetectFirmware(true)
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Disconnect() })
+	if caps := c.Capabilities(); !caps.Resolved || caps.MaxMoveSegments != 1024 {
+		t.Errorf("capabilities after Connect = %+v", caps)
+	}
+}
+
+func TestConnectReturnsDetectionError(t *testing.T) {
+	dev := emulator.New()
+	ft := fault.NewTransport(dev.Open, fault.Scenario{WriteErrorRate: 1}, false, false, false)
+	c := Macku.NewControllerWithTransport(ft)
+	c.SetDetectFirmware(true)
+	err := c.Connect()
+	if !errors.Is(err, fault.ErrInjected) {
+		t.Fatalf("Connect = %v, want the detection error", err)
+	}
+	if c.IsConnected() || ft.IsConnected() {
+		t.Error("still connected after detection failed")
+	}
+}
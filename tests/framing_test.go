@@ -0,0 +1,163 @@
+package lib_test
+
+import (
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	Macku "github.com/Auchrio/Makcu-go-lib/v3"
+	"github.com/Auchrio/Makcu-go-lib/v3/emulator"
+	"github.com/Auchrio/Makcu-go-lib/v3/fault"
+)
+
+// ---------------------------------------------------------------------------
+// Framed button reports
+// ---------------------------------------------------------------------------
+
+// framingController connects to dev with framed button reports requested.
+func framingController(t *testing.T, dev *emulator.Device) (*Macku.MakcuController, *Macku.SerialTransport) {
+	t.Helper()
+	st := Macku.NewStreamTransport("emulator", dev.Open, false, true, false)
+	st.SetButtonFraming(true)
+	c := Macku.NewControllerWithTransport(st)
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Disconnect() })
+	return c, st
+}
+
+// checkAllMasks reports every 5-button mask from dev and checks each one
+// reaches the transport intact, with queries answered in between.
+func checkAllMasks(t *testing.T, c *Macku.MakcuController, dev *emulator.Device) {
+	t.Helper()
+	for mask := 1; mask <= 32; mask++ {
+		mask := mask % 32 // end on 0
+		dev.SetButtons(mask)
+		flush(t, c)
+		if got := c.Transport.GetButtonMask(); got != mask {
+			t.Errorf("GetButtonMask = 0x%02X, want 0x%02X", got, mask)
+		}
+	}
+}
+
+func TestButtonFramingNegotiated(t *testing.T) {
+	dev := emulator.New()
+	dev.SetButtons(0x0A) // right+mouse4 held at connect
+	c, st := framingController(t, dev)
+
+	if !st.ButtonFraming() || !dev.Framed() {
+		t.Fatalf("ButtonFraming = %v, device framed = %v", st.ButtonFraming(), dev.Framed())
+	}
+	if got := c.Transport.GetButtonMask(); got != 0x0A {
+		t.Errorf("mask after negotiation = 0x%02X, want 0x0A", got)
+	}
+	if cmds := dev.Commands(); !slices.Equal(cmds, []string{"km.buttons(2)"}) {
+		t.Errorf("commands = %q", cmds)
+	}
+	checkAllMasks(t, c, dev)
+
+	// Re-enabling monitoring keeps the framed format.
+	if err := c.EnableButtonMonitoring(true); err != nil {
+		t.Fatal(err)
+	}
+	flush(t, c)
+	if !dev.Framed() {
+		t.Error("EnableButtonMonitoring switched the device to raw masks")
+	}
+}
+
+func TestButtonFramingFallsBack(t *testing.T) {
+	dev := emulator.New()
+	dev.Framing = false
+	c, st := framingController(t, dev)
+
+	if st.ButtonFraming() || dev.Framed() {
+		t.Fatal("framing reported by a device without support")
+	}
+	if cmds := dev.Commands(); !slices.Equal(cmds, []string{"km.buttons(2)", "km.buttons(1)"}) {
+		t.Errorf("commands = %q", cmds)
+	}
+	if !dev.Monitoring() {
+		t.Error("monitoring not enabled")
+	}
+	checkAllMasks(t, c, dev)
+}
+
+func TestButtonFramingSurvivesReconnect(t *testing.T) {
+	dev := emulator.New()
+	ft := fault.NewTransport(dev.Open, fault.Scenario{}, false, true, true)
+	ft.SetButtonFraming(true)
+	c := Macku.NewControllerWithTransport(ft)
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Disconnect() })
+
+	ft.Faults.SetScenario(fault.Scenario{ReadErrorRate: 1})
+	c.Transport.SendCommand("km.version()", true, 50*time.Millisecond)
+	deadline := time.Now().Add(2 * time.Second)
+	for ft.Metrics().Reconnects == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("no reconnect: %+v", ft.Metrics())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	ft.Faults.SetScenario(fault.Scenario{})
+	time.Sleep(3 * reconnectGrace)
+
+	dev.SetButtons(0x0D)
+	flush(t, c)
+	if !ft.ButtonFraming() || c.Transport.GetButtonMask() != 0x0D {
+		t.Errorf("after reconnect: ButtonFraming = %v, mask = 0x%02X", ft.ButtonFraming(), c.Transport.GetButtonMask())
+	}
+}
+
+func TestButtonFramingFallsBackAfterReconnect(t *testing.T) {
+	dev, plain := emulator.New(), emulator.New()
+	plain.Framing = false
+	var swapped atomic.Bool
+	open := func() (Macku.Port, error) {
+		if swapped.Load() {
+			return plain.Open()
+		}
+		return dev.Open()
+	}
+	ft := fault.NewTransport(open, fault.Scenario{}, false, true, true)
+	ft.SetButtonFraming(true)
+	c := Macku.NewControllerWithTransport(ft)
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Disconnect() })
+	if !ft.ButtonFraming() {
+		t.Fatal("framing not negotiated")
+	}
+
+	// The device comes back without framing support.
+	swapped.Store(true)
+	ft.Faults.SetScenario(fault.Scenario{ReadErrorRate: 1})
+	c.Transport.SendCommand("km.version()", true, 50*time.Millisecond)
+	deadline := time.Now().Add(2 * time.Second)
+	for ft.Metrics().Reconnects == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("no reconnect: %+v", ft.Metrics())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	ft.Faults.SetScenario(fault.Scenario{})
+	time.Sleep(3*reconnectGrace + Macku.DefaultTimeout)
+
+	if ft.ButtonFraming() {
+		t.Error("framing reported by a device without support")
+	}
+	if cmds := plain.Commands(); !slices.Contains(cmds, "km.buttons(1)") {
+		t.Errorf("no fallback to raw masks: commands = %q", cmds)
+	}
+	plain.SetButtons(0x0A)
+	flush(t, c)
+	if got := c.Transport.GetButtonMask(); got != 0x0A {
+		t.Errorf("mask after fallback = 0x%02X, want 0x0A", got)
+	}
+}
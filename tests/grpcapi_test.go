@@ -0,0 +1,237 @@
+package lib_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	Macku "github.com/Auchrio/Makcu-go-lib/v3"
+	"github.com/Auchrio/Makcu-go-lib/v3/daemon"
+	"github.com/Auchrio/Makcu-go-lib/v3/grpcapi"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// ---------------------------------------------------------------------------
+// gRPC harness
+// ---------------------------------------------------------------------------
+
+// remoteAPI is the controller surface shared by MakcuController and grpcapi.Client.
+type remoteAPI interface {
+	Move(dx, dy int) error
+	MoveSmooth(dx, dy, segments int) error
+	Click(button Macku.MouseButton) error
+	Press(button Macku.MouseButton) error
+	Release(button Macku.MouseButton) error
+	Scroll(delta int) error
+	Lock(target Macku.LockTarget) error
+	Unlock(target Macku.LockTarget) error
+	IsLocked(button Macku.MouseButton) (bool, error)
+	GetAllLockStates() (map[string]bool, error)
+	GetDeviceInfo() (Macku.DeviceInfo, error)
+	GetFirmwareVersion() (string, error)
+	GetButtonMask() (int, error)
+	GetButtonStates() (map[string]bool, error)
+	IsPressed(button Macku.MouseButton) (bool, error)
+	SetButtonCallback(cb func(Macku.MouseButton, bool)) error
+	OnConnectionChange(cb func(bool)) (remove func())
+}
+
+var (
+	_ remoteAPI = (*Macku.MakcuController)(nil)
+	_ remoteAPI = (*grpcapi.Client)(nil)
+)
+
+// startGRPC serves a fake-backed controller over an in-memory listener.
+func startGRPC(t *testing.T) (*grpcapi.Client, *Macku.MakcuController, *fakeTransport) {
+	t.Helper()
+	c, ft := newFakeController()
+	lis := bufconn.Listen(1 << 16)
+	g := grpc.NewServer()
+	srv := grpcapi.Register(g, c)
+	go g.Serve(lis)
+	t.Cleanup(func() {
+		srv.Close()
+		g.Stop()
+	})
+
+	client, err := grpcapi.Dial("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client, c, ft
+}
+
+// ---------------------------------------------------------------------------
+// Unary calls
+// ---------------------------------------------------------------------------
+
+func TestGRPCActionsReachDevice(t *testing.T) {
+	client, _, ft := startGRPC(t)
+
+	steps := []struct {
+		name string
+		call func() error
+		want string
+	}{
+		{"move", func() error { return client.Move(5, -3) }, "km.move(5,-3)"},
+		{"click", func() error { return client.Click(Macku.MouseButtonMiddle) }, "km.middle(1) km.middle(0)"},
+		{"press", func() error { return client.Press(Macku.MouseButton4) }, "km.ms1(1)"},
+		{"release", func() error { return client.Release(Macku.MouseButton4) }, "km.ms1(0)"},
+		{"scroll", func() error { return client.Scroll(-2) }, "km.wheel(-2)"},
+		{"lock", func() error { return client.Lock(Macku.LockY) }, "km.lock_my(1)"},
+		{"unlock", func() error { return client.Unlock(Macku.LockY) }, "km.lock_my(0)"},
+	}
+	for _, s := range steps {
+		ft.reset()
+		if err := s.call(); err != nil {
+			t.Errorf("%s: %v", s.name, err)
+			continue
+		}
+		if got := ft.joined(); got != s.want {
+			t.Errorf("%s: commands = %q, want %q", s.name, got, s.want)
+		}
+	}
+}
+
+func TestGRPCQueries(t *testing.T) {
+	client, _, ft := startGRPC(t)
+	ft.responses["km.version()"] = "km.MAKCU"
+	ft.mask = 1 << uint(Macku.MouseButtonRight)
+
+	if v, err := client.GetFirmwareVersion(); err != nil || v != "km.MAKCU" {
+		t.Errorf("GetFirmwareVersion = %q, %v", v, err)
+	}
+	info, err := client.GetDeviceInfo()
+	if err != nil || !info.IsConnected {
+		t.Errorf("GetDeviceInfo = %+v, %v", info, err)
+	}
+	if mask, err := client.GetButtonMask(); err != nil || mask != 2 {
+		t.Errorf("GetButtonMask = %d, %v", mask, err)
+	}
+	if pressed, err := client.IsPressed(Macku.MouseButtonRight); err != nil || !pressed {
+		t.Errorf("IsPressed(right) = %v, %v", pressed, err)
+	}
+
+	ft.answerLockQueries("ms1")
+	if locked, err := client.IsLocked(Macku.MouseButton4); err != nil || !locked {
+		t.Errorf("IsLocked(mouse4) = %v, %v", locked, err)
+	}
+}
+
+func TestGRPCErrorsKeepTheirClass(t *testing.T) {
+	client, _, ft := startGRPC(t)
+
+	if _, err := client.GetFirmwareVersion(); !errors.Is(err, Macku.ErrTimeout) {
+		t.Errorf("unanswered query: err = %v, want ErrTimeout", err)
+	}
+	if err := client.Click(Macku.MouseButton(42)); !errors.Is(err, Macku.ErrCommand) {
+		t.Errorf("bad button: err = %v, want ErrCommand", err)
+	}
+	ft.mu.Lock()
+	ft.sendErr = Macku.NewConnectionError("port gone")
+	ft.mu.Unlock()
+	if err := client.Move(1, 1); !errors.Is(err, Macku.ErrConnection) {
+		t.Errorf("device failure: err = %v, want ErrConnection", err)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Streams
+// ---------------------------------------------------------------------------
+
+func TestGRPCButtonStream(t *testing.T) {
+	client, _, ft := startGRPC(t)
+
+	events := make(chan string, 4)
+	err := client.SetButtonCallback(func(b Macku.MouseButton, pressed bool) {
+		events <- b.String() + map[bool]string{true: "+", false: "-"}[pressed]
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ft.setMask(1 << uint(Macku.MouseButton5))
+	ft.setMask(0)
+	for _, want := range []string{"mouse5+", "mouse5-"} {
+		select {
+		case got := <-events:
+			if got != want {
+				t.Errorf("event = %q, want %q", got, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for %q", want)
+		}
+	}
+
+	if err := client.SetButtonCallback(nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGRPCConnectionStream(t *testing.T) {
+	client, c, _ := startGRPC(t)
+
+	states := make(chan bool, 4)
+	client.OnConnectionChange(func(connected bool) { states <- connected })
+
+	expect := func(want bool) {
+		t.Helper()
+		select {
+		case got := <-states:
+			if got != want {
+				t.Errorf("connected = %v, want %v", got, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for connected=%v", want)
+		}
+	}
+	expect(true) // initial state
+	c.Disconnect()
+	expect(false)
+}
+
+func TestGRPCAndDaemonShareButtonEvents(t *testing.T) {
+	client, c, ft := startGRPC(t)
+
+	dir, err := os.MkdirTemp("", "makcud")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "d.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := daemon.NewServer(c)
+	go srv.Serve(l)
+	defer srv.Close()
+	local := daemonController(t, path)
+
+	got := make(chan string, 4)
+	if err := client.SetButtonCallback(func(b Macku.MouseButton, pressed bool) { got <- "grpc" }); err != nil {
+		t.Fatal(err)
+	}
+	local.SetButtonCallback(func(b Macku.MouseButton, pressed bool) { got <- "daemon" })
+
+	ft.setMask(1)
+	seen := map[string]bool{}
+	for len(seen) < 2 {
+		select {
+		case who := <-got:
+			seen[who] = true
+		case <-time.After(2 * time.Second):
+			t.Fatalf("only %v received the event", seen)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package lib_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	Macku "github.com/Auchrio/Makcu-go-lib/v3"
+	"github.com/Auchrio/Makcu-go-lib/v3/httpapi"
+	"golang.org/x/net/websocket"
+)
+
+// ---------------------------------------------------------------------------
+// HTTP harness
+// ---------------------------------------------------------------------------
+
+func startHTTP(t *testing.T, opts httpapi.Options) (*httptest.Server, *Macku.MakcuController, *fakeTransport) {
+	t.Helper()
+	c, ft := newFakeController()
+	api := httpapi.NewServer(c, opts)
+	ts := httptest.NewServer(api)
+	t.Cleanup(func() {
+		api.Close()
+		ts.Close()
+	})
+	return ts, c, ft
+}
+
+// do sends a request and returns the status code and decoded JSON body.
+func do(t *testing.T, ts *httptest.Server, method, path, body, token string) (int, map[string]interface{}) {
+	t.Helper()
+	req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	var out map[string]interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatalf("%s %s: non-JSON body %q", method, path, data)
+	}
+	return resp.StatusCode, out
+}
+
+func dialEvents(t *testing.T, ts *httptest.Server, query string) *websocket.Conn {
+	t.Helper()
+	url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/events" + query
+	ws, err := websocket.Dial(url, "", ts.URL)
+	if err != nil {
+		t.Fatalf("dial %s: %v", url, err)
+	}
+	t.Cleanup(func() { ws.Close() })
+	return ws
+}
+
+func readEvent(t *testing.T, ws *websocket.Conn) httpapi.Event {
+	t.Helper()
+	ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var ev httpapi.Event
+	if err := websocket.JSON.Receive(ws, &ev); err != nil {
+		t.Fatal(err)
+	}
+	return ev
+}
+
+// ---------------------------------------------------------------------------
+// REST endpoints
+// ---------------------------------------------------------------------------
+
+func TestHTTPActions(t *testing.T) {
+	ts, _, ft := startHTTP(t, httpapi.Options{})
+
+	tests := []struct {
+		path, body, want string
+	}{
+		{"/move", `{"dx":4,"dy":-2}`, "km.move(4,-2)"},
+		{"/click", `{"button":"right","count":2}`, "km.right(1) km.right(0) km.right(1) km.right(0)"},
+		{"/press", `{"button":"mouse5"}`, "km.ms2(1)"},
+		{"/release", `{"button":"mouse5"}`, "km.ms2(0)"},
+		{"/scroll", `{"delta":5}`, "km.wheel(5)"},
+		{"/lock", `{"target":"x"}`, "km.lock_mx(1)"},
+		{"/unlock", `{"target":"x"}`, "km.lock_mx(0)"},
+	}
+	for _, tt := range tests {
+		ft.reset()
+		status, body := do(t, ts, "POST", tt.path, tt.body, "")
+		if status != http.StatusOK || body["ok"] != true {
+			t.Errorf("POST %s = %d %v", tt.path, status, body)
+		}
+		if got := ft.joined(); got != tt.want {
+			t.Errorf("POST %s: commands = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestHTTPClickCountClamped(t *testing.T) {
+	ts, _, ft := startHTTP(t, httpapi.Options{})
+	status, body := do(t, ts, "POST", "/click", `{"button":"left","count":1000000000}`, "")
+	if status != http.StatusOK || body["ok"] != true {
+		t.Fatalf("POST /click = %d %v", status, body)
+	}
+	if n := len(ft.commands()); n != 2*Macku.MaxClickCount {
+		t.Errorf("sent %d commands, want %d clicks", n, Macku.MaxClickCount)
+	}
+}
+
+func TestHTTPClickStopsOnCancel(t *testing.T) {
+	c, ft := newFakeController()
+	api := httpapi.NewServer(c, httpapi.Options{})
+	t.Cleanup(func() { api.Close() })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest("POST", "/click", strings.NewReader(`{"button":"left","count":50}`)).WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	api.ServeHTTP(httptest.NewRecorder(), req)
+	if cmds := ft.commands(); len(cmds) != 0 {
+		t.Errorf("clicked after the request was cancelled: %q", cmds)
+	}
+}
+
+func TestHTTPQueries(t *testing.T) {
+	ts, _, ft := startHTTP(t, httpapi.Options{})
+	ft.responses["km.version()"] = "km.MAKCU"
+	ft.answerLockQueries("mx")
+	ft.mask = 1 << uint(Macku.MouseButtonMiddle)
+
+	status, body := do(t, ts, "GET", "/device", "", "")
+	if status != http.StatusOK || body["firmware"] != "km.MAKCU" || body["connected"] != true {
+		t.Errorf("GET /device = %d %v", status, body)
+	}
+	status, body = do(t, ts, "GET", "/locks", "", "")
+	if status != http.StatusOK || body["X"] != true || body["Y"] != false {
+		t.Errorf("GET /locks = %d %v", status, body)
+	}
+	status, body = do(t, ts, "GET", "/buttons", "", "")
+	if status != http.StatusOK || body["mask"] != float64(4) {
+		t.Errorf("GET /buttons = %d %v", status, body)
+	}
+}
+
+func TestHTTPErrors(t *testing.T) {
+	ts, _, ft := startHTTP(t, httpapi.Options{})
+
+	tests := []struct {
+		method, path, body string
+		status             int
+		code               string
+	}{
+		{"POST", "/click", `{"button":"thumb"}`, http.StatusBadRequest, "command"},
+		{"POST", "/move", `{"dx":`, http.StatusBadRequest, "invalid_request"},
+		{"POST", "/move", `{"dz":1}`, http.StatusBadRequest, "invalid_request"},
+		{"GET", "/device", "", http.StatusGatewayTimeout, "timeout"}, // no version response
+	}
+	for _, tt := range tests {
+		status, body := do(t, ts, tt.method, tt.path, tt.body, "")
+		if status != tt.status || body["code"] != tt.code {
+			t.Errorf("%s %s %s = %d %v, want %d %q", tt.method, tt.path, tt.body, status, body, tt.status, tt.code)
+		}
+	}
+
+	ft.mu.Lock()
+	ft.sendErr = Macku.NewConnectionError("port gone")
+	ft.mu.Unlock()
+	if status, body := do(t, ts, "POST", "/scroll", `{"delta":1}`, ""); status != http.StatusServiceUnavailable {
+		t.Errorf("device failure = %d %v", status, body)
+	}
+
+	// A form post (what a cross-site page can send without preflight) is refused.
+	resp, err := http.Post(ts.URL+"/move", "text/plain", strings.NewReader(`{"dx":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("text/plain POST = %d, want 415", resp.StatusCode)
+	}
+}
+
+func TestHTTPBearerAuth(t *testing.T) {
+	ts, _, _ := startHTTP(t, httpapi.Options{Token: "s3cret"})
+
+	if status, _ := do(t, ts, "POST", "/move", `{"dx":1}`, ""); status != http.StatusUnauthorized {
+		t.Errorf("no token = %d, want 401", status)
+	}
+	if status, _ := do(t, ts, "POST", "/move", `{"dx":1}`, "wrong"); status != http.StatusUnauthorized {
+		t.Errorf("wrong token = %d, want 401", status)
+	}
+	if status, _ := do(t, ts, "POST", "/move", `{"dx":1}`, "s3cret"); status != http.StatusOK {
+		t.Errorf("good token = %d, want 200", status)
+	}
+
+	url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/events"
+	if _, err := websocket.Dial(url, "", ts.URL); err == nil {
+		t.Error("WebSocket without token was accepted")
+	}
+	ws := dialEvents(t, ts, "?access_token=s3cret")
+	if ev := readEvent(t, ws); ev.Type != "connection" {
+		t.Errorf("first event = %+v", ev)
+	}
+}
+
+func TestHTTPIsLoopback(t *testing.T) {
+	for addr, want := range map[string]bool{
+		httpapi.DefaultAddr: true,
+		"localhost:80":      true,
+		"[::1]:7711":        true,
+		":7711":             false,
+		"0.0.0.0:7711":      false,
+		"192.168.1.5:7711":  false,
+	} {
+		if got := httpapi.IsLoopback(addr); got != want {
+			t.Errorf("IsLoopback(%q) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// WebSocket events
+// ---------------------------------------------------------------------------
+
+func TestHTTPEventStream(t *testing.T) {
+	ts, c, ft := startHTTP(t, httpapi.Options{})
+	ws := dialEvents(t, ts, "")
+
+	if ev := readEvent(t, ws); ev.Type != "connection" || !ev.Connected {
+		t.Fatalf("initial event = %+v", ev)
+	}
+
+	ft.setMask(1 << uint(Macku.MouseButtonLeft))
+	if ev := readEvent(t, ws); ev.Type != "button" || ev.Button != "left" || !ev.Pressed || ev.Mask != 1 {
+		t.Errorf("press event = %+v", ev)
+	}
+	ft.setMask(0)
+	if ev := readEvent(t, ws); ev.Button != "left" || ev.Pressed || ev.Mask != 0 {
+		t.Errorf("release event = %+v", ev)
+	}
+
+	c.Disconnect()
+	if ev := readEvent(t, ws); ev.Type != "connection" || ev.Connected {
+		t.Errorf("disconnect event = %+v", ev)
+	}
+}
+
+func TestHTTPEventStreamRejectsCrossOrigin(t *testing.T) {
+	ts, _, _ := startHTTP(t, httpapi.Options{})
+	url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/events"
+	if _, err := websocket.Dial(url, "", "http://evil.example"); err == nil {
+		t.Error("cross-origin WebSocket was accepted")
+	}
+}
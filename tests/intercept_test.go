@@ -0,0 +1,201 @@
+package lib_test
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	Macku "github.com/Auchrio/Makcu-go-lib/v3"
+	"github.com/Auchrio/Makcu-go-lib/v3/emulator"
+	"github.com/Auchrio/Makcu-go-lib/v3/fault"
+)
+
+// ---------------------------------------------------------------------------
+// Button catch and interception
+// ---------------------------------------------------------------------------
+
+func TestSetCatchCommands(t *testing.T) {
+	c, ft := newFakeController()
+	if err := c.Mouse.SetCatch(Macku.MouseButtonLeft, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Mouse.SetCatch(Macku.MouseButton4, false); err != nil {
+		t.Fatal(err)
+	}
+	if got := ft.joined(); got != "km.catch_ml(1) km.catch_ms1(0)" {
+		t.Errorf("commands = %q", got)
+	}
+	if !c.Mouse.IsCaught(Macku.MouseButtonLeft) || c.Mouse.IsCaught(Macku.MouseButton4) {
+		t.Error("catch state not tracked")
+	}
+	if c.Mouse.Intercepting(Macku.MouseButtonLeft) {
+		t.Error("left intercepted without a lock")
+	}
+
+	ft.responses["km.catch_ml()"] = "3"
+	if n, err := c.Mouse.CatchCount(Macku.MouseButtonLeft); err != nil || n != 3 {
+		t.Errorf("CatchCount = %d, %v", n, err)
+	}
+	if err := c.Mouse.SetCatch(Macku.MouseButton6, true); !errors.Is(err, Macku.ErrUnsupported) {
+		t.Errorf("SetCatch(mouse6) = %v, want ErrUnsupported", err)
+	}
+}
+
+func TestInterceptedButtonEvents(t *testing.T) {
+	dev := emulator.New()
+	c := emulatedController(t, dev)
+	if err := c.Lock(Macku.LockLeft); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Mouse.SetCatch(Macku.MouseButtonLeft, true); err != nil {
+		t.Fatal(err)
+	}
+	events := make(chan Macku.ButtonEvent, 4)
+	if err := c.SetButtonEventCallback(func(ev Macku.ButtonEvent) { events <- ev }); err != nil {
+		t.Fatal(err)
+	}
+	flush(t, c)
+
+	dev.SetButtons(1 << uint(Macku.MouseButtonLeft))
+	dev.SetButtons(1<<uint(Macku.MouseButtonLeft) | 1<<uint(Macku.MouseButtonRight))
+	want := []Macku.ButtonEvent{
+		{Button: Macku.MouseButtonLeft, Pressed: true, Intercepted: true},
+		{Button: Macku.MouseButtonRight, Pressed: true},
+	}
+	for _, w := range want {
+		select {
+		case got := <-events:
+			if got != w {
+				t.Errorf("event = %+v, want %+v", got, w)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for %+v", w)
+		}
+	}
+	if n, err := c.Mouse.CatchCount(Macku.MouseButtonLeft); err != nil || n != 1 {
+		t.Errorf("CatchCount = %d, %v", n, err)
+	}
+}
+
+func TestInterceptRoutesAndRestores(t *testing.T) {
+	dev := emulator.New()
+	c := emulatedController(t, dev)
+	presses := make(chan bool, 4)
+	stop, err := c.Intercept(Macku.MouseButtonMiddle, func(pressed bool) { presses <- pressed })
+	if err != nil {
+		t.Fatal(err)
+	}
+	flush(t, c)
+	if !dev.Locked(Macku.LockMiddle) || !dev.Caught(Macku.LockMiddle) {
+		t.Fatal("middle not locked and caught")
+	}
+	if _, err := c.Intercept(Macku.MouseButtonMiddle, func(bool) {}); !errors.Is(err, Macku.ErrCommand) {
+		t.Errorf("second Intercept = %v, want ErrCommand", err)
+	}
+
+	dev.SetButtons(1 << uint(Macku.MouseButtonMiddle))
+	dev.SetButtons(0)
+	for _, want := range []bool{true, false} {
+		select {
+		case got := <-presses:
+			if got != want {
+				t.Errorf("pressed = %v, want %v", got, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for pressed = %v", want)
+		}
+	}
+
+	if err := stop(); err != nil {
+		t.Fatal(err)
+	}
+	flush(t, c)
+	if dev.Locked(Macku.LockMiddle) || dev.Caught(Macku.LockMiddle) {
+		t.Error("middle still locked or caught after stop")
+	}
+	dev.SetButtons(1 << uint(Macku.MouseButtonMiddle))
+	select {
+	case <-presses:
+		t.Error("interceptor called after stop")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestInterceptAcrossReconnect(t *testing.T) {
+	dev := emulator.New()
+	ft := fault.NewTransport(dev.Open, fault.Scenario{}, false, true, true)
+	c := Macku.NewControllerWithTransport(ft)
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Disconnect() })
+
+	stop, err := c.Intercept(Macku.MouseButton4, func(bool) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ft.Faults.SetScenario(fault.Scenario{ReadErrorRate: 1})
+	c.Transport.SendCommand("km.version()", true, 50*time.Millisecond)
+	deadline := time.Now().Add(2 * time.Second)
+	for ft.Metrics().Reconnects == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("no reconnect: %+v", ft.Metrics())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	ft.Faults.SetScenario(fault.Scenario{})
+	time.Sleep(3 * reconnectGrace)
+
+	if !c.Mouse.Intercepting(Macku.MouseButton4) {
+		t.Error("mouse4 not intercepted after the reconnect")
+	}
+	cmds := dev.Commands()
+	if n := len(slices.DeleteFunc(cmds, func(s string) bool { return s != "km.catch_ms1(1)" })); n != 2 {
+		t.Errorf("catch applied %d times, want again after the reconnect", n)
+	}
+	if err := stop(); err != nil {
+		t.Fatal(err)
+	}
+	flush(t, c)
+	if dev.Locked(Macku.LockMouse4) || dev.Caught(Macku.LockMouse4) {
+		t.Error("mouse4 still locked or caught after stop")
+	}
+}
+
+func TestInterceptReportsReapplyFailure(t *testing.T) {
+	dev := emulator.New()
+	ft := fault.NewTransport(dev.Open, fault.Scenario{}, false, true, true)
+	c := Macku.NewControllerWithTransport(ft)
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Disconnect() })
+
+	// Registered first, so the re-apply after it runs with writes failing.
+	c.OnReconnect(func() error {
+		ft.Faults.SetScenario(fault.Scenario{WriteErrorRate: 1})
+		return nil
+	})
+	errs := make(chan error, 4)
+	c.OnReconnectError(func(err error) { errs <- err })
+	stop, err := c.Intercept(Macku.MouseButton4, func(bool) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ft.Faults.SetScenario(fault.Scenario{ReadErrorRate: 1})
+	c.Transport.SendCommand("km.version()", true, 50*time.Millisecond)
+	select {
+	case err := <-errs:
+		if !strings.Contains(err.Error(), "re-apply") {
+			t.Errorf("reconnect error = %v, want a re-apply failure", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("no re-apply failure reported: %+v", ft.Metrics())
+	}
+	ft.Faults.SetScenario(fault.Scenario{})
+	if err := stop(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -2,9 +2,14 @@ package lib_test
 
 import (
 	"errors"
+	"strings"
 	"testing"
+	"time"
 
-	Macku "github.com/Auchrio/Makcu-go-lib"
+	Macku "github.com/Auchrio/Makcu-go-lib/v3"
+	"github.com/Auchrio/Makcu-go-lib/v3/emulator"
+	"github.com/Auchrio/Makcu-go-lib/v3/fault"
+	"github.com/Auchrio/Makcu-go-lib/v3/internal/events"
 )
 
 // ---------------------------------------------------------------------------
@@ -21,6 +26,9 @@ func TestMouseButtonValues(t *testing.T) {
 		{Macku.MouseButtonMiddle, 2},
 		{Macku.MouseButton4, 3},
 		{Macku.MouseButton5, 4},
+		{Macku.MouseButton6, 5},
+		{Macku.MouseButton7, 6},
+		{Macku.MouseButton8, 7},
 	}
 	for _, tt := range tests {
 		if int(tt.button) != tt.want {
@@ -39,6 +47,7 @@ func TestMouseButtonString(t *testing.T) {
 		{Macku.MouseButtonMiddle, "middle"},
 		{Macku.MouseButton4, "mouse4"},
 		{Macku.MouseButton5, "mouse5"},
+		{Macku.MouseButton8, "mouse8"},
 		{Macku.MouseButton(99), "unknown"},
 	}
 	for _, tt := range tests {
@@ -48,6 +57,30 @@ func TestMouseButtonString(t *testing.T) {
 	}
 }
 
+func TestParseMouseButton(t *testing.T) {
+	for b := Macku.MouseButtonLeft; b <= Macku.MouseButton8; b++ {
+		got, err := Macku.ParseMouseButton(strings.ToUpper(b.String()))
+		if err != nil || got != b {
+			t.Errorf("ParseMouseButton(%q) = %v, %v; want %v", b.String(), got, err, b)
+		}
+	}
+	if _, err := Macku.ParseMouseButton("thumb"); !errors.Is(err, Macku.ErrCommand) {
+		t.Errorf("ParseMouseButton(thumb) err = %v, want command error", err)
+	}
+}
+
+func TestParseLockTarget(t *testing.T) {
+	for lt := Macku.LockLeft; lt <= Macku.LockY; lt++ {
+		got, err := Macku.ParseLockTarget(lt.String())
+		if err != nil || got != lt {
+			t.Errorf("ParseLockTarget(%q) = %v, %v; want %v", lt.String(), got, err, lt)
+		}
+	}
+	if _, err := Macku.ParseLockTarget("z"); !errors.Is(err, Macku.ErrCommand) {
+		t.Errorf("ParseLockTarget(z) err = %v, want command error", err)
+	}
+}
+
 func TestLockTargetValues(t *testing.T) {
 	targets := []Macku.LockTarget{
 		Macku.LockLeft, Macku.LockRight, Macku.LockMiddle,
@@ -203,6 +236,20 @@ func TestVersion(t *testing.T) {
 	}
 }
 
+func TestControllerSerialTransport(t *testing.T) {
+	st := Macku.NewSerialTransport("", false, true, false, false)
+	if got := Macku.NewControllerWithTransport(st).SerialTransport(); got != st {
+		t.Errorf("SerialTransport() = %p, want %p", got, st)
+	}
+	ft := fault.NewTransport(emulator.New().Open, fault.Scenario{}, false, true, false)
+	if got := Macku.NewControllerWithTransport(ft).SerialTransport(); got != ft.SerialTransport {
+		t.Error("SerialTransport() does not unwrap an embedded transport")
+	}
+	if c, _ := newFakeController(); c.SerialTransport() != nil {
+		t.Error("SerialTransport() not nil for a non-serial transport")
+	}
+}
+
 // ---------------------------------------------------------------------------
 // Disconnected controller error handling
 // ---------------------------------------------------------------------------
@@ -235,3 +282,61 @@ func TestDisconnectedControllerReturnsError(t *testing.T) {
 		t.Errorf("GetFirmwareVersion on disconnected controller: got %v, want connection error", err)
 	}
 }
+
+// ---------------------------------------------------------------------------
+// Connection callbacks
+// ---------------------------------------------------------------------------
+
+func TestOnConnectionChangeRemove(t *testing.T) {
+	c, _ := newFakeController()
+	var calls []string
+	removeA := c.OnConnectionChange(func(bool) { calls = append(calls, "a") })
+	c.OnConnectionChange(func(bool) { calls = append(calls, "b") })
+	removeA()
+	removeA()
+	c.Disconnect()
+	if got := strings.Join(calls, ""); got != "b" {
+		t.Errorf("callbacks called = %q, want only b", got)
+	}
+}
+
+func TestHubAttachBeforeConnect(t *testing.T) {
+	ft := newFakeTransport()
+	c := Macku.NewControllerWithTransport(ft)
+	h := events.Attach(c)
+	defer h.Detach()
+	sub := h.Subscribe(4)
+
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	ft.setMask(2)
+	var got []events.Event
+	for len(got) < 2 {
+		select {
+		case ev := <-sub.C():
+			got = append(got, ev)
+		case <-time.After(time.Second):
+			t.Fatalf("events = %+v, want connect then the right press", got)
+		}
+	}
+	if got[0].Kind != events.Connection || !got[0].Connected {
+		t.Errorf("first event = %+v, want connected", got[0])
+	}
+	if ev := got[1]; ev.Kind != events.Button || ev.Button != Macku.MouseButtonRight || !ev.Pressed {
+		t.Errorf("button event = %+v", ev)
+	}
+}
+
+func TestHubDetachAfterDisconnect(t *testing.T) {
+	c, ft := newFakeController()
+	h := events.Attach(c)
+	c.Disconnect()
+	h.Detach()
+	ft.mu.Lock()
+	cb := ft.callback
+	ft.mu.Unlock()
+	if cb != nil {
+		t.Error("button handler still installed after Detach on a disconnected controller")
+	}
+}
@@ -0,0 +1,212 @@
+package lib_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	Macku "github.com/Auchrio/Makcu-go-lib/v3"
+	"github.com/Auchrio/Makcu-go-lib/v3/emulator"
+	"github.com/Auchrio/Makcu-go-lib/v3/fault"
+)
+
+// ---------------------------------------------------------------------------
+// Lock state model
+// ---------------------------------------------------------------------------
+
+func TestLockStatesPartialFailure(t *testing.T) {
+	c, ft := newFakeController()
+	ft.answerLockQueries("mx")
+	delete(ft.responses, "km.lock_mr()")
+	ft.responses["km.lock_mm()"] = "maybe"
+
+	states, err := c.GetAllLockStates()
+	var qe *Macku.LockQueryError
+	if !errors.As(err, &qe) || len(qe.Failed) != 2 {
+		t.Fatalf("GetAllLockStates error = %v, want LockQueryError for two targets", err)
+	}
+	if !errors.Is(err, Macku.ErrTimeout) || !errors.Is(err, Macku.ErrResponse) {
+		t.Errorf("error %v does not match the underlying timeout and response errors", err)
+	}
+	if _, ok := states["RIGHT"]; ok {
+		t.Error("unread RIGHT reported in the states")
+	}
+	if !states["X"] || len(states) != 5 {
+		t.Errorf("states = %v", states)
+	}
+	if st := c.LockStatuses()["RIGHT"]; st.Known || !st.Stale(0) {
+		t.Errorf("RIGHT status = %+v, want unknown", st)
+	}
+
+	// The next read only queries what is still unknown.
+	ft.answerLockQueries("mx")
+	ft.sent = nil
+	if _, err := c.GetAllLockStates(); err != nil {
+		t.Fatal(err)
+	}
+	if got := ft.joined(); got != "km.lock_mr() km.lock_mm()" {
+		t.Errorf("commands = %q", got)
+	}
+}
+
+func TestLockStatesTrackCommandsAndRefresh(t *testing.T) {
+	c, ft := newFakeController()
+	if err := c.Lock(Macku.LockLeft); err != nil {
+		t.Fatal(err)
+	}
+	st := c.LockStatuses()["LEFT"]
+	if !st.Locked || !st.Known || st.Confirmed || st.Updated.IsZero() {
+		t.Errorf("LEFT after Lock = %+v", st)
+	}
+	if locked, err := c.IsLocked(Macku.MouseButtonLeft); err != nil || !locked {
+		t.Errorf("IsLocked(left) = %v, %v", locked, err)
+	}
+
+	// The device says otherwise; a forced refresh believes it.
+	ft.answerLockQueries()
+	ft.sent = nil
+	states, err := c.RefreshLocks()
+	if err != nil || states["LEFT"] {
+		t.Fatalf("RefreshLocks = %v, %v", states, err)
+	}
+	if n := len(ft.commands()); n != 7 {
+		t.Errorf("RefreshLocks sent %d queries, want 7", n)
+	}
+	if st := c.LockStatuses()["LEFT"]; st.Locked || !st.Confirmed {
+		t.Errorf("LEFT after refresh = %+v", st)
+	}
+
+	c.Mouse.InvalidateCache()
+	if st := c.LockStatuses()["LEFT"]; st.Known {
+		t.Errorf("LEFT after InvalidateCache = %+v", st)
+	}
+}
+
+func TestLockStatesMaxAge(t *testing.T) {
+	c, ft := newFakeController()
+	ft.answerLockQueries("my")
+	c.Mouse.SetLockMaxAge(time.Nanosecond)
+	for i := 0; i < 2; i++ {
+		if locked, err := c.IsLocked(Macku.MouseButton5); err != nil || locked {
+			t.Fatalf("IsLocked(mouse5) = %v, %v", locked, err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if n := len(ft.commands()); n != 2 {
+		t.Errorf("%d queries, want 2 with expired states", n)
+	}
+}
+
+func TestLockStatesDroppedOnReconnect(t *testing.T) {
+	dev := emulator.New()
+	ft := fault.NewTransport(dev.Open, fault.Scenario{}, false, true, true)
+	c := Macku.NewControllerWithTransport(ft)
+	reconnected := make(chan struct{}, 1)
+	c.OnReconnect(func() error {
+		reconnected <- struct{}{}
+		return nil
+	})
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Disconnect() })
+
+	if err := c.Lock(Macku.LockX); err != nil {
+		t.Fatal(err)
+	}
+	if !c.LockStatuses()["X"].Known {
+		t.Fatal("X unknown after Lock")
+	}
+
+	conns := ft.Connections()
+	ft.Faults.SetScenario(fault.Scenario{ReadErrorRate: 1})
+	c.Transport.SendCommand("km.version()", true, 50*time.Millisecond)
+	select {
+	case <-reconnected:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("no reconnect: %+v", ft.Metrics())
+	}
+	ft.Faults.SetScenario(fault.Scenario{})
+	time.Sleep(3 * reconnectGrace)
+
+	if ft.Connections() != conns+1 {
+		t.Errorf("Connections = %d, want %d", ft.Connections(), conns+1)
+	}
+	if st := c.LockStatuses()["X"]; st.Known {
+		t.Errorf("X after reconnect = %+v, want unknown", st)
+	}
+	states, err := c.GetAllLockStates()
+	if err != nil || !states["X"] {
+		t.Errorf("GetAllLockStates = %v, %v", states, err)
+	}
+	if st := c.LockStatuses()["X"]; !st.Confirmed {
+		t.Errorf("X not confirmed by the device: %+v", st)
+	}
+}
+
+func TestLockSyncReportsFailures(t *testing.T) {
+	if Macku.DefaultConfig().SyncLocks {
+		t.Error("DefaultConfig syncs locks; it is opt-in")
+	}
+	c, ft := newFakeController()
+	c.SetSyncLocks(true)
+	errs := make(chan error, 1)
+	c.OnLockSyncError(func(err error) { errs <- err })
+	ft.answerLockQueries("my")
+	delete(ft.responses, "km.lock_mr()")
+
+	c.Disconnect()
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-errs:
+		var qe *Macku.LockQueryError
+		if !errors.As(err, &qe) || len(qe.Failed) != 1 || qe.Failed["RIGHT"] == nil {
+			t.Errorf("sync error = %v, want RIGHT unread", err)
+		}
+	default:
+		t.Fatal("failed lock sync not reported")
+	}
+	if st := c.LockStatuses()["Y"]; !st.Locked || !st.Confirmed {
+		t.Errorf("Y after sync = %+v", st)
+	}
+}
+
+func TestLockSyncAfterReconnect(t *testing.T) {
+	dev := emulator.New()
+	ft := fault.NewTransport(dev.Open, fault.Scenario{}, false, true, true)
+	c := Macku.NewControllerWithTransport(ft)
+	c.SetSyncLocks(true)
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Disconnect() })
+	if err := c.Lock(Macku.LockX); err != nil {
+		t.Fatal(err)
+	}
+
+	// Capability detection runs alongside the refresh the reconnect starts.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for ft.Metrics().Reconnects == 0 {
+			c.DetectCapabilities()
+		}
+	}()
+	ft.Faults.SetScenario(fault.Scenario{ReadErrorRate: 1})
+	c.Transport.SendCommand("km.version()", true, 50*time.Millisecond)
+	<-done
+	ft.Faults.SetScenario(fault.Scenario{})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !c.LockStatuses()["X"].Confirmed {
+		if time.Now().After(deadline) {
+			t.Fatalf("X not read back after the reconnect: %+v", c.LockStatuses()["X"])
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !c.LockStatuses()["X"].Locked {
+		t.Error("X unlocked after the reconnect")
+	}
+}
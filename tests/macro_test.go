@@ -0,0 +1,228 @@
+package lib_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	Macku "github.com/Auchrio/Makcu-go-lib/v3"
+	"github.com/Auchrio/Makcu-go-lib/v3/macro"
+)
+
+// ---------------------------------------------------------------------------
+// Macro parser / validator tests
+// ---------------------------------------------------------------------------
+
+const sampleMacro = `{
+  "version": 1,
+  "name": "sample",
+  "macros": {
+    "double": {"steps": [
+      {"op": "click", "button": "left"},
+      {"op": "click", "button": "left"}
+    ]}
+  },
+  "steps": [
+    {"op": "move", "x": 10, "y": -4},
+    {"op": "smooth_move", "x": 100, "y": 0, "segments": 5},
+    {"op": "lock", "target": "x"},
+    {"op": "press", "button": "right"},
+    {"op": "release", "button": "right"},
+    {"op": "scroll", "delta": -2},
+    {"op": "wait", "duration": "1ms"},
+    {"op": "loop", "count": 2, "steps": [
+      {"op": "call", "macro": "double"}
+    ]},
+    {"op": "unlock", "target": "x"}
+  ]
+}`
+
+func TestMacroParse(t *testing.T) {
+	m, err := macro.Parse([]byte(sampleMacro))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if m.Name != "sample" || len(m.Steps) != 9 {
+		t.Fatalf("parsed %q with %d steps", m.Name, len(m.Steps))
+	}
+	if m.Steps[0].Line != 11 {
+		t.Errorf("first step line = %d, want 11", m.Steps[0].Line)
+	}
+	if got := m.Steps[7].Steps[0].Line; got != 19 {
+		t.Errorf("nested step line = %d, want 19", got)
+	}
+	if got := m.Macros["double"].Steps[1].Line; got != 7 {
+		t.Errorf("sub-macro step line = %d, want 7", got)
+	}
+	if time.Duration(m.Steps[6].Duration) != time.Millisecond {
+		t.Errorf("wait duration = %v, want 1ms", time.Duration(m.Steps[6].Duration))
+	}
+}
+
+func TestMacroValidationErrorsHaveLines(t *testing.T) {
+	src := `{
+  "version": 1,
+  "steps": [
+    {"op": "click", "button": "thumb"},
+    {"op": "teleport"},
+    {"op": "loop", "count": 0, "steps": [
+      {"op": "call", "macro": "missing"}
+    ]}
+  ]
+}`
+	_, err := macro.Parse([]byte(src))
+	var errs macro.Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Parse err = %v, want macro.Errors", err)
+	}
+	wantLines := []int{4, 5, 6, 7}
+	if len(errs) != len(wantLines) {
+		t.Fatalf("got %d errors, want %d:\n%v", len(errs), len(wantLines), err)
+	}
+	for i, e := range errs {
+		if e.Line != wantLines[i] {
+			t.Errorf("error %d (%s) line = %d, want %d", i, e.Msg, e.Line, wantLines[i])
+		}
+	}
+}
+
+func TestMacroSyntaxErrorLine(t *testing.T) {
+	src := "{\n  \"version\": 1,\n  \"steps\": [\n    {\"op\": \"move\" \"x\": 1}\n  ]\n}"
+	_, err := macro.Parse([]byte(src))
+	var e *macro.Error
+	if !errors.As(err, &e) || e.Line != 4 {
+		t.Errorf("Parse err = %v, want syntax error on line 4", err)
+	}
+}
+
+func TestMacroUnknownFieldLine(t *testing.T) {
+	tests := []struct {
+		src  string
+		line int
+	}{
+		{"{\n  \"version\": 1,\n  \"stepz\": []\n}", 3},
+		// "count" is known in steps but not in the sub-macro.
+		{"{\n  \"version\": 1,\n  \"steps\": [{\"op\": \"loop\", \"count\": 2}],\n  \"macros\": {\n    \"m\": {\"count\": 1, \"steps\": []}\n  }\n}", 5},
+		{"{\n  \"version\": 1,\n  \"steps\": [\n    {\"op\": \"loop\", \"steps\": [\n      {\"op\": \"move\",\n       \"z\": 1}\n    ]}\n  ]\n}", 6},
+	}
+	for _, tt := range tests {
+		_, err := macro.Parse([]byte(tt.src))
+		var e *macro.Error
+		if !errors.As(err, &e) || e.Line != tt.line || !strings.Contains(e.Msg, "unknown field") {
+			t.Errorf("Parse err = %v, want unknown field on line %d", err, tt.line)
+		}
+	}
+}
+
+func TestMacroRejectsBadVersionAndRecursion(t *testing.T) {
+	src := `{
+  "version": 2,
+  "macros": {
+    "a": {"steps": [{"op": "call", "macro": "b"}]},
+    "b": {"steps": [{"op": "call", "macro": "a"}]}
+  },
+  "steps": [{"op": "call", "macro": "a"}]
+}`
+	_, err := macro.Parse([]byte(src))
+	if err == nil {
+		t.Fatal("Parse accepted version 2 with recursive macros")
+	}
+	msg := err.Error()
+	if !strings.Contains(msg, "version 2") || !strings.Contains(msg, "recursively") {
+		t.Errorf("errors = %q, want version and recursion errors", msg)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Runner tests
+// ---------------------------------------------------------------------------
+
+func TestMacroRunner(t *testing.T) {
+	m, err := macro.Parse([]byte(sampleMacro))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	c, ft := newFakeController()
+	if err := macro.NewRunner(c).Run(context.Background(), m); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	want := "km.move(10,-4) km.move(100,0,5) km.lock_mx(1) km.right(1) km.right(0) km.wheel(-2) " +
+		"km.left(1) km.left(0) km.left(1) km.left(0) km.left(1) km.left(0) km.left(1) km.left(0) km.lock_mx(0)"
+	if got := ft.joined(); got != want {
+		t.Errorf("sent:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestMacroRunnerReleasesOnCancel(t *testing.T) {
+	src := `{
+  "version": 1,
+  "steps": [
+    {"op": "lock", "target": "y"},
+    {"op": "press", "button": "left"},
+    {"op": "wait", "duration": "10s"}
+  ]
+}`
+	m, err := macro.Parse([]byte(src))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	c, ft := newFakeController()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := macro.NewRunner(c).Run(ctx, m); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Run err = %v, want deadline exceeded", err)
+	}
+	if got := ft.joined(); got != "km.lock_my(1) km.left(1) km.left(0) km.lock_my(0)" {
+		t.Errorf("sent %q, want held button and lock released", got)
+	}
+}
+
+func TestMacroRunnerWaitForButton(t *testing.T) {
+	src := `{
+  "version": 1,
+  "steps": [
+    {"op": "wait_for_button", "button": "mouse4", "timeout": "1s"},
+    {"op": "click", "button": "left"}
+  ]
+}`
+	m, err := macro.Parse([]byte(src))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	c, ft := newFakeController()
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		ft.setMask(1 << 3)
+	}()
+	if err := macro.NewRunner(c).Run(context.Background(), m); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if got := ft.joined(); got != "km.left(1) km.left(0)" {
+		t.Errorf("sent %q", got)
+	}
+}
+
+func TestMacroRunnerWaitForButtonTimeout(t *testing.T) {
+	src := `{
+  "version": 1,
+  "steps": [
+    {"op": "press", "button": "middle"},
+    {"op": "wait_for_button", "button": "right", "timeout": 15}
+  ]
+}`
+	m, err := macro.Parse([]byte(src))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	c, ft := newFakeController()
+	err = macro.NewRunner(c).Run(context.Background(), m)
+	if !errors.Is(err, Macku.ErrTimeout) || !strings.HasPrefix(err.Error(), "line 5:") {
+		t.Errorf("Run err = %v, want timeout on line 5", err)
+	}
+	if got := ft.joined(); got != "km.middle(1) km.middle(0)" {
+		t.Errorf("sent %q, want middle released after failure", got)
+	}
+}
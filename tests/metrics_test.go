@@ -0,0 +1,202 @@
+package lib_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	Macku "github.com/Auchrio/Makcu-go-lib/v3"
+	"github.com/Auchrio/Makcu-go-lib/v3/emulator"
+	"github.com/Auchrio/Makcu-go-lib/v3/httpapi"
+)
+
+// ---------------------------------------------------------------------------
+// Transport metrics
+// ---------------------------------------------------------------------------
+
+func TestMetricsCountTraffic(t *testing.T) {
+	dev := emulator.New()
+	c := emulatedController(t, dev)
+	st := c.SerialTransport()
+
+	c.Move(1, 2)
+	c.Move(-3, 4)
+	c.Transport.SendCommand("hello", false, 0)
+	flush(t, c) // km.version()#1
+	if _, err := c.Transport.SendCommand("km.nothing()", true, 20*time.Millisecond); !errors.Is(err, Macku.ErrTimeout) {
+		t.Fatalf("unanswered query: %v", err)
+	}
+	pressed := make(chan bool, 2)
+	c.SetButtonCallback(func(_ Macku.MouseButton, down bool) { pressed <- down })
+	dev.SetButtons(1 << uint(Macku.MouseButtonRight))
+	dev.SetButtons(0)
+	for i := 0; i < 2; i++ {
+		select {
+		case <-pressed:
+		case <-time.After(2 * time.Second):
+			t.Fatal("no button event")
+		}
+	}
+
+	m := st.Metrics()
+	if !m.Connected {
+		t.Error("Connected = false")
+	}
+	wantSent := map[string]uint64{"buttons": 1, "move": 2, "other": 1, "version": 1, "nothing": 1}
+	if fmt.Sprint(m.CommandsSent) != fmt.Sprint(wantSent) {
+		t.Errorf("CommandsSent = %v, want %v", m.CommandsSent, wantSent)
+	}
+	written := len("km.buttons(1)\r") + len("km.move(1,2)\r\n") + len("km.move(-3,4)\r\n") +
+		len("hello\r\n") + len("km.version()#1\r\n") + len("km.nothing()#2\r\n")
+	if m.BytesWritten != uint64(written) {
+		t.Errorf("BytesWritten = %d, want %d", m.BytesWritten, written)
+	}
+	if read := len(">>> km.MAKCU\r\n") + 2; m.BytesRead != uint64(read) {
+		t.Errorf("BytesRead = %d, want %d", m.BytesRead, read)
+	}
+	if h := m.ResponseLatency["version"]; h.Count != 1 || h.Sum <= 0 || len(h.Counts) != len(Macku.LatencyBuckets)+1 {
+		t.Errorf("version latency = %+v", h)
+	}
+	if _, ok := m.ResponseLatency["nothing"]; ok {
+		t.Error("timed-out query recorded a latency")
+	}
+	if m.Timeouts != 1 {
+		t.Errorf("Timeouts = %d, want 1", m.Timeouts)
+	}
+	if m.ButtonEvents["right"] != 2 {
+		t.Errorf("ButtonEvents = %v, want right:2", m.ButtonEvents)
+	}
+}
+
+func TestMetricsCountStaleCommands(t *testing.T) {
+	dev := emulator.New()
+	c := emulatedController(t, dev)
+	// The listener drops a command pending for over a second when it next
+	// reads data; the caller then times out on its own deadline.
+	errc := make(chan error, 1)
+	go func() {
+		_, err := c.Transport.SendCommand("km.nothing()", true, 1500*time.Millisecond)
+		errc <- err
+	}()
+	time.Sleep(1100 * time.Millisecond)
+	dev.SetButtons(1)
+	if err := <-errc; !errors.Is(err, Macku.ErrTimeout) {
+		t.Fatalf("unanswered query: %v", err)
+	}
+	m := c.SerialTransport().Metrics()
+	if m.StaleCommands != 1 || m.Timeouts != 1 {
+		t.Errorf("StaleCommands = %d, Timeouts = %d, want 1, 1", m.StaleCommands, m.Timeouts)
+	}
+}
+
+func TestMetricsCountReconnects(t *testing.T) {
+	dev := emulator.New()
+	var mu sync.Mutex
+	var last Macku.Port
+	st := Macku.NewStreamTransport("emulator", func() (Macku.Port, error) {
+		p, err := dev.Open()
+		mu.Lock()
+		last = p
+		mu.Unlock()
+		return p, err
+	}, false, false, true)
+	c := Macku.NewControllerWithTransport(st)
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Disconnect()
+
+	mu.Lock()
+	last.Close() // the link drops under the listener
+	mu.Unlock()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for st.Metrics().Reconnects == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("no reconnect: %+v", st.Metrics())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	flush(t, c)
+	if m := st.Metrics(); m.ReconnectAttempts != 1 {
+		t.Errorf("ReconnectAttempts = %d, want 1", m.ReconnectAttempts)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Prometheus exposition
+// ---------------------------------------------------------------------------
+
+func TestWritePrometheus(t *testing.T) {
+	m := Macku.Metrics{
+		Connected:    true,
+		CommandsSent: map[string]uint64{"move": 3, "version": 1},
+		BytesWritten: 60,
+		ResponseLatency: map[string]Macku.Histogram{
+			"version": {Counts: []uint64{0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}, Count: 2, Sum: 1.5},
+		},
+		ButtonEvents: map[string]uint64{"left": 2},
+	}
+	var buf bytes.Buffer
+	if err := Macku.WritePrometheus(&buf, m); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"# TYPE makcu_connected gauge\nmakcu_connected 1\n",
+		"# TYPE makcu_commands_sent_total counter\n" +
+			`makcu_commands_sent_total{command="move"} 3` + "\n" +
+			`makcu_commands_sent_total{command="version"} 1` + "\n",
+		"makcu_bytes_written_total 60\n",
+		"# TYPE makcu_response_latency_seconds histogram\n",
+		`makcu_response_latency_seconds_bucket{command="version",le="0.0005"} 0` + "\n",
+		`makcu_response_latency_seconds_bucket{command="version",le="0.001"} 1` + "\n",
+		`makcu_response_latency_seconds_bucket{command="version",le="1"} 1` + "\n",
+		`makcu_response_latency_seconds_bucket{command="version",le="+Inf"} 2` + "\n",
+		`makcu_response_latency_seconds_sum{command="version"} 1.5` + "\n",
+		`makcu_response_latency_seconds_count{command="version"} 2` + "\n",
+		"makcu_command_timeouts_total 0\n",
+		`makcu_button_events_total{button="left"} 2` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("exposition is missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestHTTPMetricsEndpoint(t *testing.T) {
+	c := emulatedController(t, emulator.New())
+	api := httpapi.NewServer(c, httpapi.Options{})
+	ts := httptest.NewServer(api)
+	defer ts.Close()
+	defer api.Close()
+
+	c.Move(1, 1)
+	resp, err := http.Get(ts.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), `makcu_commands_sent_total{command="move"} 1`) {
+		t.Errorf("GET /metrics = %d\n%s", resp.StatusCode, body)
+	}
+
+	// Transports without metrics have no endpoint.
+	ts2, _, _ := startHTTP(t, httpapi.Options{})
+	resp, err = http.Get(ts2.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GET /metrics on fake transport = %d, want 404", resp.StatusCode)
+	}
+}
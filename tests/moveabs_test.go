@@ -0,0 +1,251 @@
+package lib_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	Macku "github.com/Auchrio/Makcu-go-lib/v3"
+)
+
+// ---------------------------------------------------------------------------
+// Simulated cursor
+// ---------------------------------------------------------------------------
+
+// simCursor is a CursorProvider whose position is driven by relative moves
+// passed through an acceleration curve, clamped to a fixed screen size.
+type simCursor struct {
+	x, y          int
+	width, height int
+	accel         func(counts int) int
+	lockX, lockY  bool
+	moves         int
+}
+
+func newSimCursor(x, y int, accel func(int) int) *simCursor {
+	return &simCursor{x: x, y: y, width: 1920, height: 1080, accel: accel}
+}
+
+func (c *simCursor) CursorPos() (int, int, error) {
+	return c.x, c.y, nil
+}
+
+func (c *simCursor) Move(dx, dy int) error {
+	c.moves++
+	if !c.lockX {
+		c.x = min(max(c.x+c.accel(dx), 0), c.width-1)
+	}
+	if !c.lockY {
+		c.y = min(max(c.y+c.accel(dy), 0), c.height-1)
+	}
+	return nil
+}
+
+func flatAccel(counts int) int { return counts }
+
+func linearAccel(factor float64) func(int) int {
+	return func(counts int) int {
+		return int(math.Round(float64(counts) * factor))
+	}
+}
+
+// thresholdAccel doubles any step larger than 4 counts, like a two-level
+// "enhance pointer precision" curve.
+func thresholdAccel(counts int) int {
+	if counts > 4 || counts < -4 {
+		return counts * 2
+	}
+	return counts
+}
+
+func fastMoveAbsOptions() Macku.MoveAbsOptions {
+	opts := Macku.DefaultMoveAbsOptions()
+	opts.StepDelay = 0
+	return opts
+}
+
+// ---------------------------------------------------------------------------
+// MoveToPosition tests
+// ---------------------------------------------------------------------------
+
+func TestMoveToPositionConverges(t *testing.T) {
+	curves := map[string]func(int) int{
+		"flat":      flatAccel,
+		"linear2x":  linearAccel(2),
+		"linear0.5": linearAccel(0.5),
+		"linear3.5": linearAccel(3.5),
+		"threshold": thresholdAccel,
+	}
+	for name, curve := range curves {
+		t.Run(name, func(t *testing.T) {
+			cur := newSimCursor(100, 900, curve)
+			res, err := Macku.MoveToPosition(cur, cur.Move, [2]int{1500, 200}, fastMoveAbsOptions())
+			if err != nil {
+				t.Fatalf("MoveToPosition: %v (result %+v)", err, res)
+			}
+			if !res.Converged {
+				t.Errorf("Converged = false, want true")
+			}
+			if absInt(res.ErrorX) > 1 || absInt(res.ErrorY) > 1 {
+				t.Errorf("final error = (%d,%d), want within 1", res.ErrorX, res.ErrorY)
+			}
+			if res.Iterations != cur.moves {
+				t.Errorf("Iterations = %d, want %d", res.Iterations, cur.moves)
+			}
+		})
+	}
+}
+
+func TestMoveToPositionAlreadyOnTarget(t *testing.T) {
+	cur := newSimCursor(50, 50, flatAccel)
+	res, err := Macku.MoveToPosition(cur, cur.Move, [2]int{51, 50}, fastMoveAbsOptions())
+	if err != nil {
+		t.Fatalf("MoveToPosition: %v", err)
+	}
+	if res.Iterations != 0 || !res.Converged {
+		t.Errorf("result = %+v, want 0 iterations and converged", res)
+	}
+}
+
+func TestMoveToPositionTolerance(t *testing.T) {
+	cur := newSimCursor(0, 0, linearAccel(4))
+	opts := fastMoveAbsOptions()
+	opts.Tolerance = 5
+	res, err := Macku.MoveToPosition(cur, cur.Move, [2]int{400, 300}, opts)
+	if err != nil {
+		t.Fatalf("MoveToPosition: %v", err)
+	}
+	if absInt(res.ErrorX) > 5 || absInt(res.ErrorY) > 5 {
+		t.Errorf("final error = (%d,%d), want within 5", res.ErrorX, res.ErrorY)
+	}
+}
+
+func TestMoveToPositionWrongGainDoesNotOscillate(t *testing.T) {
+	// A gain ten times too high would bounce forever with a fixed multiplier.
+	cur := newSimCursor(960, 540, linearAccel(3))
+	opts := fastMoveAbsOptions()
+	opts.Gain = 10
+	opts.MaxStep = 127
+	res, err := Macku.MoveToPosition(cur, cur.Move, [2]int{1000, 500}, opts)
+	if err != nil {
+		t.Fatalf("MoveToPosition: %v (result %+v)", err, res)
+	}
+	if res.Iterations > 50 {
+		t.Errorf("Iterations = %d, expected fast convergence after gain adaptation", res.Iterations)
+	}
+}
+
+func TestMoveToPositionLockedAxisStalls(t *testing.T) {
+	cur := newSimCursor(100, 100, flatAccel)
+	cur.lockY = true
+	res, err := Macku.MoveToPosition(cur, cur.Move, [2]int{200, 300}, fastMoveAbsOptions())
+	if !errors.Is(err, Macku.ErrTimeout) {
+		t.Fatalf("err = %v, want timeout error", err)
+	}
+	if res.Converged {
+		t.Error("Converged = true for a locked axis")
+	}
+	if res.ErrorY != 200 {
+		t.Errorf("ErrorY = %d, want 200", res.ErrorY)
+	}
+}
+
+func TestMoveToPositionMaxIterations(t *testing.T) {
+	cur := newSimCursor(0, 0, flatAccel)
+	opts := fastMoveAbsOptions()
+	opts.MaxIterations = 3
+	opts.MaxStep = 1
+	res, err := Macku.MoveToPosition(cur, cur.Move, [2]int{500, 500}, opts)
+	if !errors.Is(err, Macku.ErrTimeout) {
+		t.Fatalf("err = %v, want timeout error", err)
+	}
+	if res.Iterations != 3 {
+		t.Errorf("Iterations = %d, want 3", res.Iterations)
+	}
+}
+
+func TestMoveToPositionTimeout(t *testing.T) {
+	cur := newSimCursor(0, 0, flatAccel)
+	opts := fastMoveAbsOptions()
+	opts.MaxIterations = 0
+	opts.MaxStep = 1
+	opts.StepDelay = 5 * time.Millisecond
+	opts.Timeout = 30 * time.Millisecond
+	res, err := Macku.MoveToPosition(cur, cur.Move, [2]int{1000, 1000}, opts)
+	if !errors.Is(err, Macku.ErrTimeout) {
+		t.Fatalf("err = %v, want timeout error", err)
+	}
+	if res.Elapsed < opts.Timeout {
+		t.Errorf("Elapsed = %v, want >= %v", res.Elapsed, opts.Timeout)
+	}
+}
+
+func TestMoveToPositionMoveError(t *testing.T) {
+	cur := newSimCursor(0, 0, flatAccel)
+	want := Macku.NewConnectionError("gone")
+	_, err := Macku.MoveToPosition(cur, func(int, int) error { return want }, [2]int{10, 10}, fastMoveAbsOptions())
+	if !errors.Is(err, Macku.ErrConnection) {
+		t.Errorf("err = %v, want connection error", err)
+	}
+}
+
+func TestMoveAbsWithOptionsDisconnected(t *testing.T) {
+	c := Macku.NewController(Macku.DefaultConfig())
+	if _, err := c.MoveAbsWithOptions([2]int{1, 1}, Macku.DefaultMoveAbsOptions()); !errors.Is(err, Macku.ErrConnection) {
+		t.Errorf("MoveAbsWithOptions on disconnected controller: got %v, want connection error", err)
+	}
+}
+
+// countingModel is a flat AccelerationModel that counts its use.
+type countingModel struct{ calls int }
+
+func (m *countingModel) Displacement(counts float64) float64 { m.calls++; return counts }
+func (m *countingModel) Counts(px float64) float64           { m.calls++; return px }
+
+// jumpCursor reports from, then to once it has been read.
+type jumpCursor struct {
+	from, to [2]int
+	read     bool
+}
+
+func (c *jumpCursor) CursorPos() (int, int, error) {
+	if c.read {
+		return c.to[0], c.to[1], nil
+	}
+	c.read = true
+	return c.from[0], c.from[1], nil
+}
+
+func TestMoveAbsWithOptionsGainOverridesMouseModel(t *testing.T) {
+	ft := newFakeTransport()
+	m := Macku.NewMouse(ft)
+	model := &countingModel{}
+	m.SetAccelerationModel(model)
+	target := [2]int{100, 50}
+
+	opts := Macku.DefaultMoveAbsOptions()
+	opts.Gain = 2
+	m.SetCursorProvider(&jumpCursor{to: target})
+	if _, err := m.MoveAbsWithOptions(target, opts); err != nil {
+		t.Fatal(err)
+	}
+	if model.calls != 0 {
+		t.Errorf("Mouse model used %d times although Gain was set", model.calls)
+	}
+
+	m.SetCursorProvider(&jumpCursor{to: target})
+	if _, err := m.MoveAbsWithOptions(target, Macku.DefaultMoveAbsOptions()); err != nil {
+		t.Fatal(err)
+	}
+	if model.calls == 0 {
+		t.Error("Mouse model not used without Model or Gain")
+	}
+}
+
+func absInt(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
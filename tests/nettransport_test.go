@@ -0,0 +1,200 @@
+package lib_test
+
+import (
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	Macku "github.com/Auchrio/Makcu-go-lib/v3"
+	"github.com/Auchrio/Makcu-go-lib/v3/emulator"
+	"github.com/Auchrio/Makcu-go-lib/v3/relay"
+)
+
+// ---------------------------------------------------------------------------
+// Emulator and relay harness
+// ---------------------------------------------------------------------------
+
+// emulatedController connects a controller to dev through the real
+// SerialTransport parser.
+func emulatedController(t *testing.T, dev *emulator.Device) *Macku.MakcuController {
+	t.Helper()
+	c := Macku.NewControllerWithTransport(Macku.NewStreamTransport("emulator", dev.Open, false, true, false))
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Disconnect() })
+	return c
+}
+
+// startRelay serves dev on a loopback TCP port and returns its address.
+func startRelay(t *testing.T, dev *emulator.Device) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := relay.NewServer(dev.Open)
+	go srv.Serve(l)
+	t.Cleanup(func() { srv.Close() })
+	return l.Addr().String()
+}
+
+func netController(t *testing.T, addr string) *Macku.MakcuController {
+	t.Helper()
+	c := Macku.NewControllerWithTransport(Macku.NewNetTransport(addr, false, true, false))
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Disconnect() })
+	return c
+}
+
+// flush waits until the device has handled every command sent so far: the
+// emulator answers in order, so a query's reply follows earlier commands.
+func flush(t *testing.T, c *Macku.MakcuController) {
+	t.Helper()
+	if v, err := c.GetFirmwareVersion(); err != nil || v != emulator.DefaultVersion {
+		t.Fatalf("GetFirmwareVersion = %q, %v", v, err)
+	}
+}
+
+// exerciseDevice drives c against dev and checks the device saw it.
+func exerciseDevice(t *testing.T, c *Macku.MakcuController, dev *emulator.Device) {
+	t.Helper()
+	if err := c.Move(10, -4); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Move(5, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Scroll(-3); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Press(Macku.MouseButton4); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Lock(Macku.LockY); err != nil {
+		t.Fatal(err)
+	}
+	flush(t, c)
+
+	if x, y := dev.Position(); x != 15 || y != -3 {
+		t.Errorf("position = (%d,%d), want (15,-3)", x, y)
+	}
+	if w := dev.Wheel(); w != -3 {
+		t.Errorf("wheel = %d, want -3", w)
+	}
+	if !dev.Held(Macku.MouseButton4) {
+		t.Error("mouse4 not held")
+	}
+	if locked, err := c.IsLocked(Macku.MouseButtonLeft); err != nil || locked {
+		t.Errorf("IsLocked(left) = %v, %v", locked, err)
+	}
+	states, err := c.GetAllLockStates()
+	if err != nil || !states["Y"] || states["X"] {
+		t.Errorf("GetAllLockStates = %v, %v", states, err)
+	}
+	if !dev.Monitoring() {
+		t.Error("km.buttons(1) was not sent on connect")
+	}
+
+	events := make(chan string, 4)
+	c.SetButtonCallback(func(b Macku.MouseButton, pressed bool) {
+		events <- b.String() + map[bool]string{true: "+", false: "-"}[pressed]
+	})
+	dev.SetButtons(1 << uint(Macku.MouseButtonRight))
+	dev.SetButtons(0)
+	for _, want := range []string{"right+", "right-"} {
+		select {
+		case got := <-events:
+			if got != want {
+				t.Errorf("event = %q, want %q", got, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for %q", want)
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Tests
+// ---------------------------------------------------------------------------
+
+func TestStreamTransportOnEmulator(t *testing.T) {
+	dev := emulator.New()
+	c := emulatedController(t, dev)
+	if got := c.Transport.PortName(); got != "emulator" {
+		t.Errorf("PortName = %q", got)
+	}
+	exerciseDevice(t, c, dev)
+}
+
+func TestNetTransportThroughRelay(t *testing.T) {
+	dev := emulator.New()
+	addr := startRelay(t, dev)
+	c := netController(t, addr)
+	if got := c.Transport.PortName(); got != "tcp://"+addr {
+		t.Errorf("PortName = %q", got)
+	}
+	exerciseDevice(t, c, dev)
+
+	// The relay forwards bytes untouched: the device sees the tagged commands
+	// without any relay framing.
+	for _, cmd := range dev.Commands() {
+		if !strings.HasPrefix(cmd, "km.") {
+			t.Errorf("device received %q", cmd)
+		}
+	}
+}
+
+func TestRelayServesOneClientAtATime(t *testing.T) {
+	dev := emulator.New()
+	addr := startRelay(t, dev)
+	first := netController(t, addr)
+	flush(t, first)
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("second client read = %v, want EOF", err)
+	}
+
+	first.Disconnect()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		c := Macku.NewControllerWithTransport(Macku.NewNetTransport(addr, false, false, false))
+		if err := c.Connect(); err != nil {
+			t.Fatal(err)
+		}
+		v, err := c.GetFirmwareVersion()
+		c.Disconnect()
+		if err == nil && v == emulator.DefaultVersion {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("relay did not free the device: %v", err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestNetTransportUnreachable(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	c := Macku.NewControllerWithTransport(Macku.NewNetTransport(addr, false, false, false))
+	if err := c.Connect(); !errors.Is(err, Macku.ErrConnection) {
+		t.Errorf("Connect = %v, want ErrConnection", err)
+	}
+}
@@ -0,0 +1,358 @@
+package lib_test
+
+import (
+	"bytes"
+	"math/rand"
+	"slices"
+	"testing"
+
+	Macku "github.com/Auchrio/Makcu-go-lib/v3"
+)
+
+// ---------------------------------------------------------------------------
+// StreamParser
+// ---------------------------------------------------------------------------
+
+// parsed is what a StreamParser reported, in order: lines as strings and
+// masks as ints.
+type parsed []any
+
+func parse(data []byte, mask int) parsed {
+	return parseFrames(data, mask, false)
+}
+
+// parseFrames is parse with frame prefixes honoured if frames is set, as
+// after km.buttons(2).
+func parseFrames(data []byte, mask int, frames bool) parsed {
+	var out parsed
+	p := Macku.NewStreamParser(func(line []byte) {
+		out = append(out, string(line))
+	}, func(mask int) {
+		out = append(out, mask)
+	})
+	p.SetMask(mask)
+	p.AcceptFrames(frames)
+	p.Write(data)
+	return out
+}
+
+func TestStreamParser(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		mask int
+		want parsed
+	}{
+		{"crlf line", ">>> km.MAKCU\r\n", 0, parsed{">>> km.MAKCU"}},
+		{"lf line", "km.version()\n>>> 1\r\n", 0, parsed{"km.version()", ">>> 1"}},
+		{"tab is text", "a\tb\r\n", 0, parsed{"a\tb"}},
+		{"masks", "\x01\x00\x1f", 0, parsed{1, 0, 31}},
+		{"lf at start is a mask", "\n>>> 1\r\n", 0, parsed{10, ">>> 1"}},
+		{"lf after a mask", "\x02\n\x00", 0, parsed{2, 10, 0}},
+		{"lf while a button is held", "\n", 2, parsed{10}},
+		{"lf after a line", ">>> 1\r\n\n", 0, parsed{">>> 1", 10}},
+		{"cr before a mask", "\r\x01", 0, parsed{13, 1}},
+		{"cr before a line is a mask", "\r>>> 1\r\n", 0, parsed{13, ">>> 1"}},
+		{"tab before a line is a mask", "\x02\t>>> 1\r\n", 0, parsed{2, 9, ">>> 1"}},
+		{"mask discards a partial line", ">>> 1\x01>>> 2\r\n", 0, parsed{1, ">>> 2"}},
+		{"empty lines are dropped", "\r\n\r\n", 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parse([]byte(tt.in), tt.mask); !slices.Equal(got, tt.want) {
+				t.Errorf("parse(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestStreamParserListenDifferences pins the inputs the parser decodes
+// differently from the listen() loop it was extracted from. Each case
+// shows what listen() reported and why the parser does not.
+func TestStreamParserListenDifferences(t *testing.T) {
+	tests := []struct {
+		name   string
+		in     string
+		listen parsed // what listen() reported
+		want   parsed
+	}{
+		// listen() took every TAB as text, so a left+mouse4 mask before a
+		// line was glued onto the line and the button change was lost.
+		{"tab before text", "\t>>> 1\r\n", parsed{"\t>>> 1"}, parsed{9, ">>> 1"}},
+		// listen() dropped a CR followed by text, losing a
+		// left+middle+mouse4 mask sent just before a response.
+		{"cr before text", ">>> 1\r\n\r>>> 2\r\n", parsed{">>> 1", ">>> 2"}, parsed{">>> 1", 13, ">>> 2"}},
+		// listen() kept 0xFE as text. It is not ASCII, so it is never part
+		// of a response, and a frame prefix from a device that frames
+		// without being asked would corrupt the line.
+		{"frame prefix in heuristic mode", ">>> \xfe1\r\n", parsed{">>> \xfe1"}, parsed{">>> 1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parse([]byte(tt.in), 0)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("parse(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+			if slices.Equal(got, tt.listen) {
+				t.Errorf("parse(%q) = %v, same as listen()", tt.in, got)
+			}
+		})
+	}
+}
+
+func TestStreamParserTruncatesLongLines(t *testing.T) {
+	line := bytes.Repeat([]byte("x"), 300)
+	got := parse(append(line, "\r\n"...), 0)
+	if len(got) != 1 || len(got[0].(string)) != 256 {
+		t.Errorf("got %d lines, first %d bytes; want one 256-byte line", len(got), len(got[0].(string)))
+	}
+}
+
+// interleaving builds a device stream from responses and button-mask
+// transitions in the order given, returning it with what the parser should
+// report for it.
+type interleaving struct {
+	stream []byte
+	want   parsed
+	mask   int
+	framed bool // send masks as frames
+}
+
+func (s *interleaving) response(text string) {
+	line := ">>> " + text
+	s.stream = append(s.stream, line+"\r\n"...)
+	s.want = append(s.want, line)
+}
+
+func (s *interleaving) buttons(mask int) {
+	switch {
+	case mask == s.mask:
+		return
+	case s.framed:
+		s.stream = append(s.stream, Macku.ButtonFramePrefix)
+	case mask == 0x0A && s.endsInCR():
+		return // CR+LF is a line terminator whatever produced it
+	}
+	s.mask = mask
+	s.stream = append(s.stream, byte(mask))
+	s.want = append(s.want, mask)
+}
+
+// endsInCR reports whether the stream ends in a raw CR mask, which the
+// parser can only classify once the next byte arrives.
+func (s *interleaving) endsInCR() bool {
+	return !s.framed && len(s.stream) > 0 && s.stream[len(s.stream)-1] == 0x0D
+}
+
+// program turns arbitrary bytes into an interleaving: a byte with the top
+// bit set is a mask transition, any other byte starts a response whose
+// length and printable text come from the bytes that follow.
+func program(data []byte, framed bool) *interleaving {
+	s := &interleaving{framed: framed}
+	for len(data) > 0 {
+		op := data[0]
+		data = data[1:]
+		if op&0x80 != 0 {
+			s.buttons(int(op & 0x1F))
+			continue
+		}
+		n := min(int(op&0x0F)+1, len(data))
+		text := make([]byte, n)
+		for i, b := range data[:n] {
+			text[i] = ' ' + b%95
+		}
+		data = data[n:]
+		s.response(string(bytes.TrimRight(text, " ")) + "x")
+	}
+	if s.endsInCR() {
+		s.stream = s.stream[:len(s.stream)-1]
+		s.want = s.want[:len(s.want)-1]
+	}
+	return s
+}
+
+// TestStreamParserInterleavings checks that any interleaving of well-formed
+// responses and mask transitions, raw or framed, split into reads
+// anywhere, decodes to the same responses and transitions.
+func TestStreamParserInterleavings(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 4000; i++ {
+		prog := make([]byte, r.Intn(64))
+		r.Read(prog)
+		s := program(prog, i%2 == 1)
+
+		var got parsed
+		p := Macku.NewStreamParser(func(line []byte) {
+			got = append(got, string(line))
+		}, func(mask int) {
+			got = append(got, mask)
+		})
+		p.AcceptFrames(s.framed)
+		for rest := s.stream; len(rest) > 0; {
+			n := min(1+r.Intn(8), len(rest))
+			p.Write(rest[:n])
+			rest = rest[n:]
+		}
+		if !slices.Equal(got, s.want) {
+			t.Fatalf("stream %q\ngot  %v\nwant %v", s.stream, got, s.want)
+		}
+	}
+}
+
+// FuzzStreamParserInterleavings is the fuzzing form of
+// TestStreamParserInterleavings.
+func FuzzStreamParserInterleavings(f *testing.F) {
+	f.Add([]byte{0x81, 0x03, 'a', 'b', 'c', 'd', 0x80})
+	f.Add([]byte{0x82, 0x8A, 0x00, 'z', 0x80, 0x8A})
+	f.Fuzz(func(t *testing.T, prog []byte) {
+		for _, framed := range []bool{false, true} {
+			s := program(prog, framed)
+			if got := parseFrames(s.stream, 0, framed); !slices.Equal(got, s.want) {
+				t.Fatalf("stream %q\ngot  %v\nwant %v", s.stream, got, s.want)
+			}
+		}
+	})
+}
+
+// FuzzStreamParser checks invariants that hold for any device output: lines
+// are bounded and hold only text, masks are bytes, and the result does not
+// depend on how the stream is split into reads.
+func FuzzStreamParser(f *testing.F) {
+	f.Add([]byte(">>> km.MAKCU\r\n\x02\x00>>> 1\r\n"), uint8(3))
+	f.Add([]byte("\r\n\n\x0a\x0d\x0d\n\t\x01>"), uint8(1))
+	f.Add([]byte(">>> 1\xfe\x0a\r\n\xfe\xfe\x01\n"), uint8(2))
+	f.Fuzz(func(t *testing.T, data []byte, split uint8) {
+		whole := parse(data, 0)
+		for _, v := range whole {
+			switch v := v.(type) {
+			case string:
+				if v == "" || len(v) > 256 {
+					t.Fatalf("line of %d bytes", len(v))
+				}
+				for _, c := range []byte(v) {
+					if c < 32 && c != '\t' || c == Macku.ButtonFramePrefix {
+						t.Fatalf("control byte 0x%02X in line %q", c, v)
+					}
+				}
+			case int:
+				if v < 0 || v > 0xFF {
+					t.Fatalf("mask 0x%02X", v)
+				}
+			}
+		}
+
+		var chunked parsed
+		p := Macku.NewStreamParser(func(line []byte) {
+			chunked = append(chunked, string(line))
+		}, func(mask int) {
+			chunked = append(chunked, mask)
+		})
+		step := int(split%16) + 1
+		for rest := data; len(rest) > 0; {
+			n := min(step, len(rest))
+			p.Write(rest[:n])
+			rest = rest[n:]
+		}
+		if !slices.Equal(whole, chunked) {
+			t.Fatalf("split every %d bytes: %v, whole: %v", step, chunked, whole)
+		}
+	})
+}
+
+// maskContexts places a mask among responses the way a device can: around
+// CRLF and LF-only lines, between two masks, and at the start. raw is false
+// where a raw mask byte is ambiguous: while a button is held, the LF ending
+// an LF-only line reads as mask 0x0A.
+var maskContexts = []struct {
+	name   string
+	before string
+	after  string
+	raw    bool
+	want   func(mask int) parsed
+}{
+	{"after crlf line", ">>> 1\r\n", ">>> 2\r\n", true, func(m int) parsed { return parsed{">>> 1", m, ">>> 2"} }},
+	{"after lf line", "km.version()\n", ">>> 2\n", false, func(m int) parsed { return parsed{"km.version()", m, ">>> 2"} }},
+	{"between masks", "\x10", "\x10", true, func(m int) parsed { return parsed{16, m, 16} }},
+	{"at start", "", ">>> 2\r\n", true, func(m int) parsed { return parsed{m, ">>> 2"} }},
+}
+
+// TestStreamParserRawMasks covers every 5-button mask sent as a raw byte.
+func TestStreamParserRawMasks(t *testing.T) {
+	for mask := 0; mask < 32; mask++ {
+		for _, ctx := range maskContexts {
+			if !ctx.raw || ctx.name == "between masks" && mask == 0x10 {
+				continue // ambiguous, or not a transition
+			}
+			in := ctx.before + string(rune(mask)) + ctx.after
+			if got, want := parse([]byte(in), 0), ctx.want(mask); !slices.Equal(got, want) {
+				t.Errorf("mask 0x%02X %s: parse(%q) = %v, want %v", mask, ctx.name, in, got, want)
+			}
+		}
+	}
+}
+
+// TestStreamParserFramedMasks covers every 5-button mask sent as a frame,
+// including mid-line and directly before an LF, where raw bytes would be
+// ambiguous.
+func TestStreamParserFramedMasks(t *testing.T) {
+	frame := func(mask int) string { return string([]byte{Macku.ButtonFramePrefix, byte(mask)}) }
+	for mask := 0; mask < 32; mask++ {
+		for _, ctx := range maskContexts {
+			in := frame(0x10) + ctx.before + frame(mask) + ctx.after
+			want := append(parsed{16}, ctx.want(mask)...)
+			if ctx.name == "between masks" {
+				in = frame(0x10) + frame(mask) + frame(0x10)
+				want = parsed{16, mask, 16}
+			}
+			if got := parseFrames([]byte(in), 0, true); !slices.Equal(got, want) {
+				t.Errorf("mask 0x%02X %s: parse(%q) = %v, want %v", mask, ctx.name, in, got, want)
+			}
+		}
+
+		in := frame(0x0D) + ">>> " + frame(mask) + "1\r\n" + frame(mask) + "\n"
+		want := parsed{13, mask, ">>> 1", mask}
+		if got := parseFrames([]byte(in), 0, true); !slices.Equal(got, want) {
+			t.Errorf("mask 0x%02X mid-line: parse(%q) = %v, want %v", mask, in, got, want)
+		}
+	}
+}
+
+func TestStreamParserFramedMode(t *testing.T) {
+	var got parsed
+	p := Macku.NewStreamParser(func(line []byte) {
+		got = append(got, string(line))
+	}, func(mask int) {
+		got = append(got, mask)
+	})
+	p.Write([]byte("\x02>>> 1\r\n"))
+	if p.Framed() {
+		t.Fatal("framed before the first frame")
+	}
+	p.AcceptFrames(true)
+	// Once framed, raw control bytes are dropped and a bare LF ends a line
+	// even while a button is held.
+	p.Write([]byte{Macku.ButtonFramePrefix, 0x0A, '\x01', '>', '\r', 'a', '\n', '\x0D', '\n'})
+	if !p.Framed() {
+		t.Fatal("not framed after a frame")
+	}
+	p.SetFramed(false)
+	p.Write([]byte("\x04"))
+	want := parsed{2, ">>> 1", 10, ">a", 4}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// TestStreamParserIgnoresUnrequestedFrames checks that a prefix byte only
+// starts a frame once frames were requested: before, it is dropped and the
+// parser stays heuristic.
+func TestStreamParserIgnoresUnrequestedFrames(t *testing.T) {
+	in := []byte{'>', Macku.ButtonFramePrefix, '1', '\r', '\n', Macku.ButtonFramePrefix, 0x02, '\n'}
+	want := parsed{">1", 2, 10}
+	if got := parse(in, 0); !slices.Equal(got, want) {
+		t.Errorf("parse(%q) = %v, want %v", in, got, want)
+	}
+	if got, want := parseFrames(in, 0, true), (parsed{0x31, ">", 2}); !slices.Equal(got, want) {
+		t.Errorf("with frames: parse(%q) = %v, want %v", in, got, want)
+	}
+}
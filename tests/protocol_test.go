@@ -0,0 +1,150 @@
+package lib_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	Macku "github.com/Auchrio/Makcu-go-lib/v3"
+	"github.com/Auchrio/Makcu-go-lib/v3/emulator"
+	"github.com/Auchrio/Makcu-go-lib/v3/protocol"
+)
+
+// ---------------------------------------------------------------------------
+// Protocol
+// ---------------------------------------------------------------------------
+
+func TestProtocolConstructors(t *testing.T) {
+	must := func(c protocol.Command, err error) string {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c.String()
+	}
+	tests := []struct{ got, want string }{
+		{must(protocol.Move(10, -4)), "km.move(10,-4)"},
+		{must(protocol.MoveSmooth(1, 2, 30)), "km.move(1,2,30)"},
+		{must(protocol.MoveBezier(1, 2, 30, -5, 6)), "km.move(1,2,30,-5,6)"},
+		{must(protocol.Wheel(-3)), "km.wheel(-3)"},
+		{must(protocol.Button(0, true)), "km.left(1)"},
+		{must(protocol.Button(4, false)), "km.ms2(0)"},
+		{must(protocol.ButtonQuery(4)), "km.ms2()"},
+		{must(protocol.Lock("mx", true)), "km.lock_mx(1)"},
+		{must(protocol.LockQuery("ms2")), "km.lock_ms2()"},
+		{must(protocol.Buttons(2)), "km.buttons(2)"},
+		{must(protocol.Serial(`it's a\b`)), `km.serial('it\'s a\\b')`},
+		{protocol.Version().String(), "km.version()"},
+		{protocol.ResetSerial().String(), "km.serial(0)"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("got %s, want %s", tt.got, tt.want)
+		}
+	}
+	if got := string(protocol.Encode(protocol.Version(), 42)); got != "km.version()#42\r\n" {
+		t.Errorf("Encode = %q", got)
+	}
+	if got := string(protocol.EncodeLine("km.left(1)", protocol.NoTag)); got != "km.left(1)\r\n" {
+		t.Errorf("EncodeLine = %q", got)
+	}
+}
+
+func TestProtocolValidation(t *testing.T) {
+	invalid := []func() (protocol.Command, error){
+		func() (protocol.Command, error) { return protocol.Move(protocol.MaxMove+1, 0) },
+		func() (protocol.Command, error) { return protocol.Move(0, protocol.MinMove-1) },
+		func() (protocol.Command, error) { return protocol.MoveSmooth(1, 1, 0) },
+		func() (protocol.Command, error) { return protocol.MoveBezier(1, 1, 5, 0, protocol.MaxMove+1) },
+		func() (protocol.Command, error) { return protocol.Wheel(protocol.MaxWheel + 1) },
+		func() (protocol.Command, error) { return protocol.Button(5, true) },
+		func() (protocol.Command, error) { return protocol.LockQuery("ms3") },
+		func() (protocol.Command, error) { return protocol.Lock("mz", true) },
+		func() (protocol.Command, error) { return protocol.Buttons(3) },
+		func() (protocol.Command, error) { return protocol.Serial("") },
+		func() (protocol.Command, error) {
+			return protocol.Serial(strings.Repeat("x", protocol.MaxSerialLength+1))
+		},
+		func() (protocol.Command, error) { return protocol.Serial("a\r\nkm.move(1,1)") },
+	}
+	for i, f := range invalid {
+		if c, err := f(); !errors.Is(err, protocol.ErrInvalid) {
+			t.Errorf("case %d: %v, %v; want ErrInvalid", i, c, err)
+		}
+	}
+}
+
+func TestProtocolDecode(t *testing.T) {
+	tests := []struct {
+		line string
+		want protocol.Command
+		tag  int
+	}{
+		{"km.move(10, -4)\r\n", protocol.Command{Name: "move", Args: []protocol.Arg{protocol.Int(10), protocol.Int(-4)}}, protocol.NoTag},
+		{"km.version()#17", protocol.Command{Name: "version"}, 17},
+		{`km.serial("a#)")`, protocol.Command{Name: "serial", Args: []protocol.Arg{protocol.Str("a#)")}}, protocol.NoTag},
+		{`km.serial('it\'s')#3`, protocol.Command{Name: "serial", Args: []protocol.Arg{protocol.Str("it's")}}, 3},
+	}
+	for _, tt := range tests {
+		got, tag, err := protocol.Decode(tt.line)
+		if err != nil || tag != tt.tag || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Decode(%q) = %#v, %d, %v", tt.line, got, tag, err)
+		}
+	}
+	for _, line := range []string{"move(1)", "km.move(1", "km.move(1,,2)", "km.move(x)", "km.serial('open)", "km.(1)", "km.version()#x"} {
+		if _, _, err := protocol.Decode(line); !errors.Is(err, protocol.ErrSyntax) {
+			t.Errorf("Decode(%q) = %v, want ErrSyntax", line, err)
+		}
+	}
+}
+
+// FuzzProtocolRoundTrip checks that any command the constructors accept
+// decodes back to itself.
+func FuzzProtocolRoundTrip(f *testing.F) {
+	f.Add("O'Brien\\", 12, -7, uint16(3))
+	f.Fuzz(func(t *testing.T, serial string, x, y int, tag uint16) {
+		var cmds []protocol.Command
+		if c, err := protocol.Serial(serial); err == nil {
+			cmds = append(cmds, c)
+		}
+		if c, err := protocol.MoveBezier(x, y, 10, y, x); err == nil {
+			cmds = append(cmds, c)
+		}
+		for _, c := range cmds {
+			got, gotTag, err := protocol.Decode(string(protocol.Encode(c, int(tag))))
+			if err != nil || gotTag != int(tag) || !reflect.DeepEqual(got, c) {
+				t.Fatalf("round trip of %s: %#v, %d, %v", c, got, gotTag, err)
+			}
+		}
+	})
+}
+
+func TestMouseRejectsInvalidArguments(t *testing.T) {
+	c, ft := newFakeController()
+	for _, err := range []error{
+		c.Move(protocol.MaxMove+1, 0),
+		c.Scroll(1000),
+		c.MoveSmooth(1, 1, 0),
+		c.SpoofSerial("bad')\r\nkm.lock_mx(1"),
+	} {
+		if !errors.Is(err, Macku.ErrCommand) {
+			t.Errorf("got %v, want command error", err)
+		}
+	}
+	if cmds := ft.commands(); len(cmds) != 0 {
+		t.Errorf("invalid commands sent %q", cmds)
+	}
+}
+
+func TestSpoofSerialEscapesQuotes(t *testing.T) {
+	dev := emulator.New()
+	c := emulatedController(t, dev)
+	if err := c.SpoofSerial(`O'Brien\1`); err != nil {
+		t.Fatal(err)
+	}
+	flush(t, c)
+	if got := dev.Serial(); got != `O'Brien\1` {
+		t.Errorf("device serial = %q", got)
+	}
+}
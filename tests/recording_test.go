@@ -0,0 +1,213 @@
+package lib_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	Macku "github.com/Auchrio/Makcu-go-lib/v3"
+	"github.com/Auchrio/Makcu-go-lib/v3/emulator"
+)
+
+// ---------------------------------------------------------------------------
+// Recorder / Recording tests
+// ---------------------------------------------------------------------------
+
+func newRecordingController() (*Macku.MakcuController, *Macku.Recorder, *fakeTransport) {
+	ft := newFakeTransport()
+	rec := Macku.NewRecorder(ft)
+	c := Macku.NewControllerWithTransport(rec)
+	if err := c.Connect(); err != nil {
+		panic(err)
+	}
+	return c, rec, ft
+}
+
+func TestRecorderCapturesCommands(t *testing.T) {
+	c, rec, ft := newRecordingController()
+	ft.responses["km.version()"] = "km.MAKCU"
+
+	c.Move(10, -5)
+	time.Sleep(5 * time.Millisecond)
+	c.Click(Macku.MouseButtonLeft)
+	c.Scroll(2)
+	c.GetFirmwareVersion()
+
+	got := rec.Recording()
+	want := []string{"km.move(10,-5)", "km.left(1)", "km.left(0)", "km.wheel(2)", "km.version()"}
+	if len(got.Commands) != len(want) {
+		t.Fatalf("recorded %d commands, want %d: %+v", len(got.Commands), len(want), got.Commands)
+	}
+	for i, cmd := range got.Commands {
+		if cmd.Command != want[i] {
+			t.Errorf("command %d = %q, want %q", i, cmd.Command, want[i])
+		}
+		if i > 0 && cmd.At < got.Commands[i-1].At {
+			t.Errorf("command %d offset %v before previous %v", i, cmd.At, got.Commands[i-1].At)
+		}
+	}
+	if got.Commands[1].At < 5*time.Millisecond {
+		t.Errorf("second command offset = %v, want >= 5ms", got.Commands[1].At)
+	}
+	if !got.Commands[4].ExpectResponse {
+		t.Error("km.version() should be recorded as a query")
+	}
+	if got.Version != Macku.RecordingVersion {
+		t.Errorf("Version = %d, want %d", got.Version, Macku.RecordingVersion)
+	}
+}
+
+func TestRecorderSkipsFailedSends(t *testing.T) {
+	c, rec, ft := newRecordingController()
+	ft.sendErr = Macku.NewConnectionError("unplugged")
+	c.Move(1, 1)
+	if n := len(rec.Recording().Commands); n != 0 {
+		t.Errorf("recorded %d commands after failed send, want 0", n)
+	}
+}
+
+func TestRecorderReset(t *testing.T) {
+	c, rec, _ := newRecordingController()
+	c.Move(1, 1)
+	rec.Reset()
+	c.Move(2, 2)
+	got := rec.Recording().Commands
+	if len(got) != 1 || got[0].Command != "km.move(2,2)" {
+		t.Errorf("after Reset got %+v, want only km.move(2,2)", got)
+	}
+}
+
+func TestRecordingSaveLoad(t *testing.T) {
+	rec := &Macku.Recording{
+		Version: Macku.RecordingVersion,
+		Commands: []Macku.RecordedCommand{
+			{At: 0, Command: "km.move(1,2)"},
+			{At: 15 * time.Millisecond, Command: "km.lock_mx()", ExpectResponse: true},
+		},
+	}
+	var buf bytes.Buffer
+	if err := rec.Save(&buf); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	loaded, err := Macku.LoadRecording(&buf)
+	if err != nil {
+		t.Fatalf("LoadRecording: %v", err)
+	}
+	if len(loaded.Commands) != 2 || loaded.Commands[1] != rec.Commands[1] {
+		t.Errorf("round trip = %+v, want %+v", loaded.Commands, rec.Commands)
+	}
+}
+
+func TestLoadRecordingRejectsUnknownVersion(t *testing.T) {
+	if _, err := Macku.LoadRecording(bytes.NewBufferString(`{"version":99,"commands":[]}`)); err == nil {
+		t.Error("LoadRecording accepted version 99")
+	}
+}
+
+func TestRecordingTrim(t *testing.T) {
+	rec := &Macku.Recording{Version: Macku.RecordingVersion}
+	for i := 0; i < 5; i++ {
+		rec.Commands = append(rec.Commands, Macku.RecordedCommand{
+			At: time.Duration(i) * 10 * time.Millisecond, Command: "km.move(1,0)",
+		})
+	}
+	trimmed := rec.Trim(10*time.Millisecond, 30*time.Millisecond)
+	if len(trimmed.Commands) != 3 {
+		t.Fatalf("trimmed to %d commands, want 3", len(trimmed.Commands))
+	}
+	if trimmed.Commands[0].At != 0 || trimmed.Duration() != 20*time.Millisecond {
+		t.Errorf("trimmed offsets = %v..%v, want 0..20ms", trimmed.Commands[0].At, trimmed.Duration())
+	}
+	if len(rec.Commands) != 5 {
+		t.Error("Trim modified the original recording")
+	}
+}
+
+func TestRecordingReplay(t *testing.T) {
+	rec := &Macku.Recording{
+		Version: Macku.RecordingVersion,
+		Commands: []Macku.RecordedCommand{
+			{At: 0, Command: "km.move(5,5)"},
+			{At: 20 * time.Millisecond, Command: "km.left(1)"},
+			{At: 40 * time.Millisecond, Command: "km.left(0)"},
+		},
+	}
+	c, ft := newFakeController()
+
+	start := time.Now()
+	if err := rec.Replay(context.Background(), c, 1); err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("replay at 1x took %v, want >= 40ms", elapsed)
+	}
+	if got := ft.joined(); got != "km.move(5,5) km.left(1) km.left(0)" {
+		t.Errorf("replayed %q", got)
+	}
+
+	ft.reset()
+	start = time.Now()
+	if err := rec.Replay(context.Background(), c, 4); err != nil {
+		t.Fatalf("Replay 4x: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= 40*time.Millisecond {
+		t.Errorf("replay at 4x took %v, want < 40ms", elapsed)
+	}
+}
+
+func TestRecordingReplayCancelled(t *testing.T) {
+	rec := &Macku.Recording{
+		Version: Macku.RecordingVersion,
+		Commands: []Macku.RecordedCommand{
+			{At: 0, Command: "km.move(1,1)"},
+			{At: time.Second, Command: "km.move(2,2)"},
+		},
+	}
+	c, ft := newFakeController()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := rec.Replay(ctx, c, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Replay err = %v, want deadline exceeded", err)
+	}
+	if got := ft.joined(); got != "km.move(1,1)" {
+		t.Errorf("replayed %q before cancellation", got)
+	}
+}
+
+func TestRecordingReplayDisconnected(t *testing.T) {
+	rec := &Macku.Recording{Version: Macku.RecordingVersion}
+	c := Macku.NewController(Macku.DefaultConfig())
+	if err := rec.Replay(context.Background(), c, 1); !errors.Is(err, Macku.ErrConnection) {
+		t.Errorf("Replay on disconnected controller: got %v, want connection error", err)
+	}
+}
+
+func TestRecorderKeepsFramedMonitoring(t *testing.T) {
+	dev := emulator.New()
+	st := Macku.NewStreamTransport("emulator", dev.Open, false, true, false)
+	st.SetButtonFraming(true)
+	rec := Macku.NewRecorder(st)
+	c := Macku.NewControllerWithTransport(rec)
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Disconnect() })
+
+	if err := c.EnableButtonMonitoring(true); err != nil {
+		t.Fatal(err)
+	}
+	flush(t, c)
+	if !dev.Framed() || !st.ButtonFraming() {
+		t.Error("EnableButtonMonitoring through a Recorder switched the device to raw masks")
+	}
+	dev.SetButtons(0x0A)
+	flush(t, c)
+	if got := c.Transport.GetButtonMask(); got != 0x0A {
+		t.Errorf("mask = 0x%02X, want 0x0A", got)
+	}
+	if got := rec.Recording().Commands; len(got) == 0 || got[0].Command != "km.buttons(2)" {
+		t.Errorf("recorded %+v, want the km.buttons(2) that was sent", got)
+	}
+}
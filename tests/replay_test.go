@@ -0,0 +1,195 @@
+package lib_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	Macku "github.com/Auchrio/Makcu-go-lib/v3"
+	"github.com/Auchrio/Makcu-go-lib/v3/emulator"
+)
+
+// ---------------------------------------------------------------------------
+// Replay harness
+// ---------------------------------------------------------------------------
+
+// recordSession runs script against the emulator with a tap and returns
+// the capture.
+func recordSession(t *testing.T, dev *emulator.Device, script func(*Macku.MakcuController)) *Macku.Capture {
+	t.Helper()
+	var buf bytes.Buffer
+	st := Macku.NewStreamTransport("emulator", dev.Open, false, true, false)
+	st.SetTap(Macku.NewCaptureWriter(&buf, Macku.CaptureText))
+	c := Macku.NewControllerWithTransport(st)
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	script(c)
+	c.Disconnect()
+	capture, err := Macku.ReadCapture(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return capture
+}
+
+func replayController(t *testing.T, capture *Macku.Capture) (*Macku.MakcuController, *Macku.ReplayTransport) {
+	t.Helper()
+	rt := Macku.NewReplayTransport(capture)
+	c := Macku.NewControllerWithTransport(rt)
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Disconnect() })
+	return c, rt
+}
+
+// ---------------------------------------------------------------------------
+// Tests
+// ---------------------------------------------------------------------------
+
+func TestReplayReproducesSession(t *testing.T) {
+	dev := emulator.New()
+	dev.Version = "km.MAKCU v3.9"
+	script := func(c *Macku.MakcuController) (string, bool) {
+		c.Move(12, -7)
+		c.Lock(Macku.LockMouse5)
+		v, _ := c.GetFirmwareVersion()
+		locked, _ := c.IsLocked(Macku.MouseButton5)
+		c.Click(Macku.MouseButtonLeft)
+		return v, locked
+	}
+	capture := recordSession(t, dev, func(c *Macku.MakcuController) {
+		script(c)
+		dev.SetButtons(1 << uint(Macku.MouseButtonMiddle))
+		time.Sleep(20 * time.Millisecond)
+	})
+
+	c, rt := replayController(t, capture)
+	pressed := make(chan Macku.MouseButton, 1)
+	c.SetButtonCallback(func(b Macku.MouseButton, down bool) {
+		if down {
+			pressed <- b
+		}
+	})
+	v, locked := script(c)
+	if v != "km.MAKCU v3.9" || !locked {
+		t.Errorf("replayed answers = %q, %v", v, locked)
+	}
+	select {
+	case b := <-pressed:
+		if b != Macku.MouseButtonMiddle {
+			t.Errorf("replayed press = %v", b)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("button report was not replayed")
+	}
+	if err := rt.Verify(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestReplayDetectsDivergentWrites(t *testing.T) {
+	capture := recordSession(t, emulator.New(), func(c *Macku.MakcuController) {
+		c.Move(1, 2)
+		c.Scroll(3)
+	})
+
+	c, rt := replayController(t, capture)
+	if err := c.Move(1, 2); err != nil {
+		t.Fatal(err)
+	}
+	err := c.Scroll(-3)
+	var re *Macku.ReplayError
+	if !errors.As(err, &re) || !strings.Contains(string(re.Want), "km.wheel(3)") {
+		t.Fatalf("Scroll(-3) = %v, want ReplayError expecting km.wheel(3)", err)
+	}
+	if !errors.Is(rt.Verify(), err) {
+		t.Errorf("Verify = %v, want %v", rt.Verify(), err)
+	}
+}
+
+func TestReplayReportsMissingWrites(t *testing.T) {
+	capture := recordSession(t, emulator.New(), func(c *Macku.MakcuController) {
+		c.Move(1, 2)
+		c.Click(Macku.MouseButtonRight)
+	})
+
+	c, rt := replayController(t, capture)
+	c.Move(1, 2)
+	err := rt.Verify()
+	if err == nil || !strings.Contains(err.Error(), `km.right(1)`) {
+		t.Errorf("Verify = %v, want missing km.right(1)", err)
+	}
+}
+
+func TestReplayKeepsOriginalTiming(t *testing.T) {
+	capture := &Macku.Capture{Records: []Macku.CaptureRecord{
+		{Offset: 0, Dir: Macku.CaptureWrite, Data: []byte("km.version()#1\r\n")},
+		{Offset: 150 * time.Millisecond, Dir: Macku.CaptureRead, Data: []byte(">>> km.MAKCU\r\n")},
+	}}
+
+	for _, tt := range []struct {
+		speed    float64
+		min, max time.Duration
+	}{
+		{1, 140 * time.Millisecond, time.Second},
+		{0, 0, 100 * time.Millisecond},
+	} {
+		c, rt := replayController(t, capture)
+		rt.SetSpeed(tt.speed)
+		start := time.Now()
+		if _, err := c.Transport.SendCommand("km.version()", true, 2*time.Second); err != nil {
+			t.Fatal(err)
+		}
+		if d := time.Since(start); d < tt.min || d > tt.max {
+			t.Errorf("speed %v: response after %v, want %v..%v", tt.speed, d, tt.min, tt.max)
+		}
+	}
+}
+
+// TestReplayFieldCapture reproduces a session reported from the field,
+// stored as a text capture under testdata.
+func TestReplayFieldCapture(t *testing.T) {
+	f, err := os.Open("testdata/echo_firmware.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	capture, err := Macku.ReadCapture(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, rt := replayController(t, capture)
+	rt.SetSpeed(0)
+	var events []string
+	done := make(chan struct{})
+	c.SetButtonCallback(func(b Macku.MouseButton, pressed bool) {
+		events = append(events, b.String()+map[bool]string{true: "+", false: "-"}[pressed])
+		if len(events) == 4 {
+			close(done)
+		}
+	})
+
+	if v, err := c.GetFirmwareVersion(); err != nil || v != "km.MAKCU" {
+		t.Errorf("GetFirmwareVersion = %q, %v", v, err)
+	}
+	if resp, err := c.Transport.SendCommand("km.lock_mx()", true, time.Second); err != nil || resp != "1" {
+		t.Errorf("km.lock_mx() = %q, %v", resp, err)
+	}
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("button events = %v", events)
+	}
+	if want := "right+ mouse4+ right- mouse4-"; strings.Join(events, " ") != want {
+		t.Errorf("button events = %v, want %s", events, want)
+	}
+	if err := rt.Verify(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,95 @@
+package lib_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	Macku "github.com/Auchrio/Makcu-go-lib/v3"
+)
+
+// ---------------------------------------------------------------------------
+// Response parsing
+// ---------------------------------------------------------------------------
+
+func TestParseBoolResponse(t *testing.T) {
+	tests := []struct {
+		line   string
+		want   bool
+		err    error
+		device bool
+	}{
+		{"1", true, nil, false},
+		{" 0\r", false, nil, false},
+		{"2", false, Macku.ErrResponse, false},
+		{"", false, Macku.ErrResponse, false},
+		{"km.move(1,2)", false, Macku.ErrResponse, false},
+		{"ERROR: unknown command", false, Macku.ErrDevice, true},
+		{"SyntaxError: invalid syntax", false, Macku.ErrDevice, true},
+	}
+	for _, tt := range tests {
+		got, err := Macku.ParseBoolResponse("km.lock_ml()", tt.line)
+		if got != tt.want || !errors.Is(err, tt.err) || (err == nil) != (tt.err == nil) {
+			t.Errorf("ParseBoolResponse(%q) = %v, %v; want %v, %v", tt.line, got, err, tt.want, tt.err)
+			continue
+		}
+		if err == nil {
+			continue
+		}
+		var re *Macku.ResponseError
+		if !errors.As(err, &re) || re.Device != tt.device || re.Raw != strings.TrimSpace(tt.line) || re.Command != "km.lock_ml()" {
+			t.Errorf("ParseBoolResponse(%q) error = %#v", tt.line, err)
+		}
+		if !errors.Is(err, Macku.ErrResponse) {
+			t.Errorf("ParseBoolResponse(%q) error does not match ErrResponse", tt.line)
+		}
+	}
+}
+
+func TestParseIntResponse(t *testing.T) {
+	if n, err := Macku.ParseIntResponse("km.catch_ml()", "-12"); n != -12 || err != nil {
+		t.Errorf("ParseIntResponse = %d, %v", n, err)
+	}
+	_, err := Macku.ParseIntResponse("km.catch_ml()", "twelve")
+	if !errors.Is(err, Macku.ErrResponse) || !strings.Contains(err.Error(), `"twelve"`) {
+		t.Errorf("ParseIntResponse(twelve) = %v, want ErrResponse quoting the reply", err)
+	}
+}
+
+func TestParseVersionResponse(t *testing.T) {
+	for _, line := range []string{"km.MAKCU", "v3.4.1 (right)"} {
+		if _, err := Macku.ParseVersionResponse("km.version()", line); err != nil {
+			t.Errorf("ParseVersionResponse(%q) = %v", line, err)
+		}
+	}
+	for _, line := range []string{"1", "km.version()", "Error: busy"} {
+		if _, err := Macku.ParseVersionResponse("km.version()", line); !errors.Is(err, Macku.ErrResponse) {
+			t.Errorf("ParseVersionResponse(%q) = %v, want ErrResponse", line, err)
+		}
+	}
+}
+
+func TestCheckEchoResponse(t *testing.T) {
+	if err := Macku.CheckEchoResponse("km.move(1,2)", "km.move(1, 2)"); err != nil {
+		t.Errorf("CheckEchoResponse = %v", err)
+	}
+	if err := Macku.CheckEchoResponse("km.move(1,2)", "km.move(2,1)"); !errors.Is(err, Macku.ErrResponse) {
+		t.Errorf("mismatched echo = %v, want ErrResponse", err)
+	}
+	if err := Macku.CheckEchoResponse("km.move(1,2)", "invalid argument"); !errors.Is(err, Macku.ErrDevice) {
+		t.Errorf("device error = %v, want ErrDevice", err)
+	}
+}
+
+func TestQueriesRejectMalformedReplies(t *testing.T) {
+	c, ft := newFakeController()
+	ft.responses["km.lock_ml()"] = "yes"
+	if _, err := c.IsLocked(Macku.MouseButtonLeft); !errors.Is(err, Macku.ErrResponse) {
+		t.Errorf("IsLocked = %v, want ErrResponse", err)
+	}
+
+	ft.responses["km.version()"] = "ERROR: busy"
+	if _, err := c.GetFirmwareVersion(); !errors.Is(err, Macku.ErrDevice) {
+		t.Errorf("GetFirmwareVersion = %v, want ErrDevice", err)
+	}
+}
@@ -0,0 +1,156 @@
+package lib_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	Macku "github.com/Auchrio/Makcu-go-lib/v3"
+	"github.com/Auchrio/Makcu-go-lib/v3/script"
+)
+
+// ---------------------------------------------------------------------------
+// Scripting engine tests
+// ---------------------------------------------------------------------------
+
+func runScript(t *testing.T, c *Macku.MakcuController, opts script.Options, src string) error {
+	t.Helper()
+	return script.New(c, opts).Run(context.Background(), "test.star", []byte(src))
+}
+
+func TestScriptControllerAPI(t *testing.T) {
+	c, ft := newFakeController()
+	ft.responses["km.lock_ml()"] = "1"
+	src := `
+left_locked = is_locked("left")
+move(10, -5)
+move(100, 0, segments=4)
+lock("x")
+for i in range(2):
+    click("right")
+scroll(-1)
+if left_locked:
+    press("middle")
+    release("middle")
+unlock("x")
+`
+	if err := runScript(t, c, script.DefaultOptions(), src); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	want := "km.lock_ml() km.move(10,-5) km.move(100,0,4) km.lock_mx(1) km.right(1) km.right(0) km.right(1) km.right(0) " +
+		"km.wheel(-1) km.middle(1) km.middle(0) km.lock_mx(0)"
+	if got := ft.joined(); got != want {
+		t.Errorf("sent:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestScriptBranchesOnButtonState(t *testing.T) {
+	c, ft := newFakeController()
+	ft.setMask(1 << 1)
+	src := `
+if is_pressed("right"):
+    click("left", count=2)
+else:
+    scroll(1)
+`
+	if err := runScript(t, c, script.DefaultOptions(), src); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if got := ft.joined(); got != "km.left(1) km.left(0) km.left(1) km.left(0)" {
+		t.Errorf("sent %q", got)
+	}
+}
+
+func TestScriptWaitFor(t *testing.T) {
+	c, ft := newFakeController()
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		ft.setMask(1 << 4)
+	}()
+	src := `
+if wait_for("mouse5", timeout=1):
+    click()
+if not wait_for("left", timeout=0.01):
+    scroll(3)
+`
+	if err := runScript(t, c, script.DefaultOptions(), src); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if got := ft.joined(); got != "km.left(1) km.left(0) km.wheel(3)" {
+		t.Errorf("sent %q", got)
+	}
+}
+
+func TestScriptTimeoutReleasesState(t *testing.T) {
+	c, ft := newFakeController()
+	opts := script.DefaultOptions()
+	opts.Timeout = 30 * time.Millisecond
+	src := `
+lock("y")
+press("left")
+sleep(10)
+`
+	err := runScript(t, c, opts, src)
+	if !errors.Is(err, script.ErrLimit) {
+		t.Fatalf("Run err = %v, want ErrLimit", err)
+	}
+	if got := ft.joined(); got != "km.lock_my(1) km.left(1) km.left(0) km.lock_my(0)" {
+		t.Errorf("sent %q, want held button and lock released", got)
+	}
+}
+
+func TestScriptStepLimit(t *testing.T) {
+	c, _ := newFakeController()
+	opts := script.DefaultOptions()
+	opts.MaxSteps = 10_000
+	err := runScript(t, c, opts, "while True:\n    pass\n")
+	if !errors.Is(err, script.ErrLimit) {
+		t.Errorf("Run err = %v, want ErrLimit", err)
+	}
+}
+
+func TestScriptClickCountBounded(t *testing.T) {
+	c, ft := newFakeController()
+	err := runScript(t, c, script.DefaultOptions(), `click(count=1000000000)`)
+	if err == nil || !strings.Contains(err.Error(), "count must be") {
+		t.Errorf("Run err = %v, want count rejected", err)
+	}
+	if got := ft.joined(); got != "" {
+		t.Errorf("sent %q", got)
+	}
+}
+
+func TestScriptSandbox(t *testing.T) {
+	c, _ := newFakeController()
+	err := runScript(t, c, script.DefaultOptions(), `load("os.star", "system")`)
+	if err == nil || !strings.Contains(err.Error(), "load() is not allowed") {
+		t.Errorf("load err = %v, want sandbox error", err)
+	}
+}
+
+func TestScriptErrorsPropagate(t *testing.T) {
+	c, ft := newFakeController()
+	if err := runScript(t, c, script.DefaultOptions(), `click("thumb")`); !errors.Is(err, Macku.ErrCommand) {
+		t.Errorf("bad button err = %v, want command error", err)
+	}
+	ft.sendErr = Macku.NewConnectionError("unplugged")
+	if err := runScript(t, c, script.DefaultOptions(), `move(1, 1)`); !errors.Is(err, Macku.ErrConnection) {
+		t.Errorf("send failure err = %v, want connection error", err)
+	}
+}
+
+func TestScriptPrintAndGlobals(t *testing.T) {
+	c, _ := newFakeController()
+	var out []string
+	opts := script.DefaultOptions()
+	opts.Print = func(msg string) { out = append(out, msg) }
+	opts.Globals = map[string]string{"PROFILE": "gaming"}
+	if err := runScript(t, c, opts, `print("profile", PROFILE)`); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(out) != 1 || out[0] != "profile gaming" {
+		t.Errorf("print output = %q", out)
+	}
+}
@@ -0,0 +1,236 @@
+package lib_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	Macku "github.com/Auchrio/Makcu-go-lib/v3"
+	"github.com/Auchrio/Makcu-go-lib/v3/emulator"
+	"github.com/Auchrio/Makcu-go-lib/v3/fault"
+)
+
+// ---------------------------------------------------------------------------
+// In-memory tracer
+// ---------------------------------------------------------------------------
+
+type recordedSpan struct {
+	name   string
+	parent *recordedSpan
+	attrs  map[string]interface{}
+	err    error
+	ended  bool
+}
+
+type memTracer struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+type spanKey struct{}
+
+func (t *memTracer) Start(ctx context.Context, name string) (context.Context, Macku.Span) {
+	parent, _ := ctx.Value(spanKey{}).(*recordedSpan)
+	s := &recordedSpan{name: name, parent: parent, attrs: map[string]interface{}{}}
+	t.mu.Lock()
+	t.spans = append(t.spans, s)
+	t.mu.Unlock()
+	return context.WithValue(ctx, spanKey{}, s), &memSpan{t, s}
+}
+
+type memSpan struct {
+	t *memTracer
+	s *recordedSpan
+}
+
+func (m *memSpan) SetAttribute(key string, value interface{}) {
+	m.t.mu.Lock()
+	m.s.attrs[key] = value
+	m.t.mu.Unlock()
+}
+
+func (m *memSpan) End(err error) {
+	m.t.mu.Lock()
+	m.s.err, m.s.ended = err, true
+	m.t.mu.Unlock()
+}
+
+// children returns the command texts of the spans under parent.
+func (t *memTracer) children(parent *recordedSpan) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var out []string
+	for _, s := range t.spans {
+		if s.parent == parent {
+			out = append(out, fmt.Sprint(s.attrs[Macku.AttrCommandText]))
+		}
+	}
+	return out
+}
+
+func (t *memTracer) roots() []*recordedSpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var out []*recordedSpan
+	for _, s := range t.spans {
+		if s.parent == nil {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// ---------------------------------------------------------------------------
+// Tests
+// ---------------------------------------------------------------------------
+
+func TestTracingClickSpans(t *testing.T) {
+	c, _ := newFakeController()
+	tr := &memTracer{}
+	c.SetTracer(tr)
+
+	if err := c.Click(Macku.MouseButtonLeft); err != nil {
+		t.Fatal(err)
+	}
+	c.Move(1, 1) // not a traced operation
+
+	roots := tr.roots()
+	if len(roots) != 1 || roots[0].name != "makcu.Click" || !roots[0].ended || roots[0].attrs["makcu.button"] != "left" {
+		t.Fatalf("root spans = %+v", roots)
+	}
+	if got, want := tr.children(roots[0]), []string{"km.left(1)", "km.left(0)"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("command spans = %v, want %v", got, want)
+	}
+	for _, s := range tr.spans[1:] {
+		if s.name != Macku.SpanCommand || !s.ended || s.attrs[Macku.AttrCommandResponse] != false {
+			t.Errorf("command span = %+v", s)
+		}
+		if _, ok := s.attrs[Macku.AttrCommandLatency].(float64); !ok {
+			t.Errorf("command span has no latency: %+v", s.attrs)
+		}
+	}
+}
+
+func TestTracingRecordsErrorClass(t *testing.T) {
+	c, ft := newFakeController()
+	tr := &memTracer{}
+	c.SetTracer(tr)
+	ft.sendErr = Macku.NewConnectionError("link down")
+
+	err := c.Drag(0, 0, 10, 10, Macku.MouseButtonRight, 0)
+	if !errors.Is(err, Macku.ErrConnection) {
+		t.Fatalf("Drag = %v", err)
+	}
+	root := tr.roots()[0]
+	if root.name != "makcu.Drag" || root.err != err || root.attrs[Macku.AttrErrorClass] != "connection" {
+		t.Errorf("Drag span = %+v", root)
+	}
+	cmd := tr.spans[1]
+	if cmd.parent != root || cmd.attrs[Macku.AttrErrorClass] != "connection" || cmd.err == nil {
+		t.Errorf("command span = %+v", cmd)
+	}
+
+	for err, want := range map[error]string{
+		nil:                         "",
+		Macku.NewTimeoutError("x"):  "timeout",
+		Macku.NewCommandError("x"):  "command",
+		Macku.NewResponseError("x"): "response",
+		fmt.Errorf("wrapped: %w", Macku.ErrConnection): "connection",
+		errors.New("x"): "other",
+	} {
+		if got := Macku.ErrorClass(err); got != want {
+			t.Errorf("ErrorClass(%v) = %q, want %q", err, got, want)
+		}
+	}
+}
+
+func TestTracingLockQueriesCarryIDs(t *testing.T) {
+	c := emulatedController(t, emulator.New())
+	tr := &memTracer{}
+	c.SetTracer(tr)
+
+	if _, err := c.GetAllLockStates(); err != nil {
+		t.Fatal(err)
+	}
+	root := tr.roots()[0]
+	if root.name != "makcu.GetAllLockStates" || root.err != nil {
+		t.Fatalf("root span = %+v", root)
+	}
+	var ids []interface{}
+	for _, s := range tr.spans[1:] {
+		if s.parent != root || s.attrs[Macku.AttrCommandResponse] != true {
+			t.Errorf("command span = %+v", s)
+		}
+		ids = append(ids, s.attrs[Macku.AttrCommandID])
+	}
+	if want := []interface{}{1, 2, 3, 4, 5, 6, 7}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("command IDs = %v, want %v", ids, want)
+	}
+
+	c.SetTracer(nil)
+	c.Click(Macku.MouseButtonLeft)
+	if n := len(tr.roots()); n != 1 {
+		t.Errorf("%d root spans after SetTracer(nil), want 1", n)
+	}
+}
+
+func TestTracingClickHumanLikeRecordsFailure(t *testing.T) {
+	c, ft := newFakeController()
+	tr := &memTracer{}
+	c.SetTracer(tr)
+	ft.sendErr = Macku.NewConnectionError("link down")
+
+	err := c.ClickHumanLike(Macku.MouseButtonLeft, 3, Macku.ProfileGaming, 0)
+	if !errors.Is(err, Macku.ErrConnection) {
+		t.Fatalf("ClickHumanLike = %v", err)
+	}
+	if root := tr.roots()[0]; root.err != err || root.attrs[Macku.AttrErrorClass] != "connection" {
+		t.Errorf("ClickHumanLike span = %+v", root)
+	}
+	if n := len(tr.spans); n != 2 {
+		t.Errorf("%d spans, want the operation and the failed press", n)
+	}
+}
+
+func TestTracingKeepsTransportState(t *testing.T) {
+	dev := emulator.New()
+	ft := fault.NewTransport(dev.Open, fault.Scenario{}, false, true, true)
+	ft.SetButtonFraming(true)
+	c := Macku.NewControllerWithTransport(ft)
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Disconnect() })
+	c.SetTracer(&memTracer{})
+
+	caps, err := c.DetectCapabilities()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !caps.FramedButtons {
+		t.Error("FramedButtons not reported with a tracer set")
+	}
+
+	if err := c.Lock(Macku.LockLeft); err != nil {
+		t.Fatal(err)
+	}
+	ft.Faults.SetScenario(fault.Scenario{ReadErrorRate: 1})
+	c.Transport.SendCommand("km.version()", true, 50*time.Millisecond)
+	deadline := time.Now().Add(2 * time.Second)
+	for ft.Metrics().Reconnects == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("no reconnect: %+v", ft.Metrics())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	ft.Faults.SetScenario(fault.Scenario{})
+	time.Sleep(3 * reconnectGrace)
+
+	if st := c.Mouse.LockStatuses()["LEFT"]; st.Known {
+		t.Errorf("lock state kept across a reconnect with a tracer set: %+v", st)
+	}
+}
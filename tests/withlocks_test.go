@@ -0,0 +1,168 @@
+package lib_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	Macku "github.com/Auchrio/Makcu-go-lib/v3"
+	"github.com/Auchrio/Makcu-go-lib/v3/emulator"
+	"github.com/Auchrio/Makcu-go-lib/v3/fault"
+)
+
+// ---------------------------------------------------------------------------
+// WithLocks
+// ---------------------------------------------------------------------------
+
+var errFn = errors.New("fn failed")
+
+func TestWithLocksRestoresPreviousState(t *testing.T) {
+	c, ft := newFakeController()
+	ft.answerLockQueries("ml")
+
+	err := c.WithLocks(context.Background(), []Macku.LockTarget{Macku.LockLeft, Macku.LockX, Macku.LockX}, func() error {
+		if got := ft.joined(); got != "km.lock_ml() km.lock_mx() km.lock_ml(1) km.lock_mx(1)" {
+			t.Errorf("commands before fn = %q", got)
+		}
+		ft.sent = nil
+		return errFn
+	})
+	if !errors.Is(err, errFn) {
+		t.Errorf("WithLocks = %v, want fn's error", err)
+	}
+	if got := ft.joined(); got != "km.lock_mx(0) km.lock_ml(1)" {
+		t.Errorf("restore commands = %q", got)
+	}
+}
+
+func TestWithLocksRestoresOnPanic(t *testing.T) {
+	c, ft := newFakeController()
+	ft.answerLockQueries()
+
+	defer func() {
+		if p := recover(); p != "boom" {
+			t.Errorf("recovered %v, want the panic from fn", p)
+		}
+		if got := ft.commands(); got[len(got)-1] != "km.lock_my(0)" {
+			t.Errorf("commands = %q, want the lock restored", got)
+		}
+	}()
+	c.WithLocks(context.Background(), []Macku.LockTarget{Macku.LockY}, func() error {
+		panic("boom")
+	})
+}
+
+func TestWithLocksRestoresOnCancel(t *testing.T) {
+	c, ft := newFakeController()
+	ft.answerLockQueries()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	err := c.WithLocks(ctx, []Macku.LockTarget{Macku.LockMiddle}, func() error {
+		cancel()
+		deadline := time.Now().Add(time.Second)
+		for !strings.HasSuffix(ft.joined(), "km.lock_mm(0)") {
+			if time.Now().After(deadline) {
+				t.Error("lock not restored while fn was still running")
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("WithLocks = %v, want context.Canceled", err)
+	}
+	if n := strings.Count(ft.joined(), "km.lock_mm(0)"); n != 1 {
+		t.Errorf("restored %d times, want once", n)
+	}
+}
+
+func TestWithLocksNeedsPreviousState(t *testing.T) {
+	c, ft := newFakeController()
+	ran := false
+	err := c.WithLocks(context.Background(), []Macku.LockTarget{Macku.LockRight}, func() error {
+		ran = true
+		return nil
+	})
+	if !errors.Is(err, Macku.ErrTimeout) || ran {
+		t.Errorf("WithLocks = %v, ran = %v; want a timeout without running fn", err, ran)
+	}
+	if got := ft.joined(); got != "km.lock_mr()" {
+		t.Errorf("commands = %q", got)
+	}
+}
+
+func TestWithLocksAcrossReconnect(t *testing.T) {
+	dev := emulator.New()
+	ft := fault.NewTransport(dev.Open, fault.Scenario{}, false, true, true)
+	c := Macku.NewControllerWithTransport(ft)
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Disconnect() })
+
+	err := c.WithLocks(context.Background(), []Macku.LockTarget{Macku.LockY}, func() error {
+		ft.Faults.SetScenario(fault.Scenario{ReadErrorRate: 1})
+		c.Transport.SendCommand("km.version()", true, 50*time.Millisecond)
+		deadline := time.Now().Add(2 * time.Second)
+		for ft.Metrics().Reconnects == 0 {
+			if time.Now().After(deadline) {
+				t.Fatalf("no reconnect: %+v", ft.Metrics())
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		ft.Faults.SetScenario(fault.Scenario{})
+		time.Sleep(3 * reconnectGrace)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	flush(t, c)
+	if dev.Locked(Macku.LockY) {
+		t.Error("Y still locked after WithLocks")
+	}
+	cmds := dev.Commands()
+	if n := len(slices.DeleteFunc(cmds, func(s string) bool { return s != "km.lock_my(1)" })); n != 2 {
+		t.Errorf("lock applied %d times, want again after the reconnect", n)
+	}
+}
+
+func TestWithLocksReturnsReapplyFailure(t *testing.T) {
+	dev := emulator.New()
+	ft := fault.NewTransport(dev.Open, fault.Scenario{}, false, true, true)
+	c := Macku.NewControllerWithTransport(ft)
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Disconnect() })
+
+	// Registered first, so the re-apply after it runs with writes failing.
+	c.OnReconnect(func() error {
+		ft.Faults.SetScenario(fault.Scenario{WriteErrorRate: 1})
+		return nil
+	})
+	reported := make(chan error, 4)
+	c.OnReconnectError(func(err error) { reported <- err })
+	err := c.WithLocks(context.Background(), []Macku.LockTarget{Macku.LockY}, func() error {
+		ft.Faults.SetScenario(fault.Scenario{ReadErrorRate: 1})
+		c.Transport.SendCommand("km.version()", true, 50*time.Millisecond)
+		select {
+		case <-reported:
+		case <-time.After(2 * time.Second):
+			t.Errorf("no re-apply failure reported: %+v", ft.Metrics())
+		}
+		ft.Faults.SetScenario(fault.Scenario{})
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "re-apply") {
+		t.Fatalf("WithLocks = %v, want the re-apply failure", err)
+	}
+	flush(t, c)
+	if dev.Locked(Macku.LockY) {
+		t.Error("Y still locked after WithLocks")
+	}
+}
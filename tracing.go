@@ -0,0 +1,184 @@
+package Macku
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Tracer starts spans for controller operations. It is a deliberately small
+// interface so the core module does not depend on a tracing library; the
+// otelmakcu module adapts an OpenTelemetry tracer to it.
+type Tracer interface {
+	// Start begins a span named name as a child of any span in ctx and
+	// returns a context carrying the new span.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a single traced operation.
+type Span interface {
+	// SetAttribute records a key/value pair. Values are string, int, bool
+	// or float64.
+	SetAttribute(key string, value interface{})
+	// End finishes the span; a non-nil err marks it as failed.
+	End(err error)
+}
+
+// Span and attribute names used by the controller.
+const (
+	SpanCommand = "makcu.command" // child span per serial command
+
+	AttrCommandText     = "makcu.command.text"
+	AttrCommandID       = "makcu.command.id" // tag of a query; absent for fire-and-forget commands
+	AttrCommandResponse = "makcu.command.expect_response"
+	AttrCommandLatency  = "makcu.command.latency_ms"
+	AttrErrorClass      = "makcu.error.class" // connection, command, timeout, response or other
+)
+
+// ErrorClass returns the attribute value used for err: the name of the
+// sentinel it wraps, "other", or "" for nil.
+func ErrorClass(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrConnection):
+		return "connection"
+	case errors.Is(err, ErrTimeout):
+		return "timeout"
+	case errors.Is(err, ErrCommand):
+		return "command"
+	case errors.Is(err, ErrResponse):
+		return "response"
+	default:
+		return "other"
+	}
+}
+
+// SetTracer enables tracing with t, or disables it when t is nil. Click,
+// Drag, ClickHumanLike, MoveAbs and GetAllLockStates then each produce a
+// span, with a child SpanCommand per command the Mouse sends while it runs.
+// Commands sent outside those calls, or directly on c.Transport, are not
+// traced. The controller tracks one operation at a time: when several
+// goroutines share a controller their command spans may be attributed to
+// each other's operations.
+func (c *MakcuController) SetTracer(t Tracer) {
+	tt, traced := c.Mouse.transport.(*tracingTransport)
+	if traced {
+		c.Mouse.transport = tt.Transport
+	}
+	if t != nil {
+		c.Mouse.transport = &tracingTransport{Transport: c.Mouse.transport, tracer: t}
+	}
+}
+
+// startSpan begins an operation span and returns the function that ends it.
+// It is a no-op without a tracer.
+func (c *MakcuController) startSpan(name string, attrs ...interface{}) func(error) {
+	tt, ok := c.Mouse.transport.(*tracingTransport)
+	if !ok {
+		return func(error) {}
+	}
+	ctx, span := tt.tracer.Start(tt.enter(), name)
+	for i := 0; i+1 < len(attrs); i += 2 {
+		span.SetAttribute(attrs[i].(string), attrs[i+1])
+	}
+	prev := tt.push(ctx)
+	return func(err error) {
+		tt.pop(prev)
+		if err != nil {
+			span.SetAttribute(AttrErrorClass, ErrorClass(err))
+		}
+		span.End(err)
+	}
+}
+
+// --- transport wrapper ---
+
+// taggedSender is implemented by transports that can report the tag of the
+// query they sent.
+type taggedSender interface {
+	sendTagged(command string, expectResponse bool, timeout time.Duration) (resp string, id int, err error)
+}
+
+// tracingTransport wraps the Mouse's transport and opens a child span for
+// each command sent while an operation span is active.
+type tracingTransport struct {
+	Transport
+	tracer Tracer
+
+	mu  sync.Mutex
+	ctx context.Context // current operation; nil outside one
+}
+
+func (t *tracingTransport) enter() context.Context {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.ctx != nil {
+		return t.ctx
+	}
+	return context.Background()
+}
+
+func (t *tracingTransport) push(ctx context.Context) context.Context {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	prev := t.ctx
+	t.ctx = ctx
+	return prev
+}
+
+func (t *tracingTransport) pop(prev context.Context) {
+	t.mu.Lock()
+	t.ctx = prev
+	t.mu.Unlock()
+}
+
+// Connections forwards to the wrapped transport, so lock states are still
+// dropped when it reconnects.
+func (t *tracingTransport) Connections() uint64 {
+	if cc, ok := t.Transport.(connectionCounter); ok {
+		return cc.Connections()
+	}
+	return 0
+}
+
+// ButtonFraming forwards to the wrapped transport, so DetectCapabilities
+// still sees negotiated framing.
+func (t *tracingTransport) ButtonFraming() bool {
+	f, ok := t.Transport.(interface{ ButtonFraming() bool })
+	return ok && f.ButtonFraming()
+}
+
+func (t *tracingTransport) SendCommand(command string, expectResponse bool, timeout time.Duration) (string, error) {
+	t.mu.Lock()
+	ctx := t.ctx
+	t.mu.Unlock()
+	if ctx == nil {
+		return t.Transport.SendCommand(command, expectResponse, timeout)
+	}
+
+	_, span := t.tracer.Start(ctx, SpanCommand)
+	span.SetAttribute(AttrCommandText, command)
+	span.SetAttribute(AttrCommandResponse, expectResponse)
+	start := time.Now()
+	var (
+		resp string
+		id   int
+		err  error
+	)
+	if ts, ok := t.Transport.(taggedSender); ok {
+		resp, id, err = ts.sendTagged(command, expectResponse, timeout)
+	} else {
+		resp, err = t.Transport.SendCommand(command, expectResponse, timeout)
+	}
+	span.SetAttribute(AttrCommandLatency, float64(time.Since(start))/float64(time.Millisecond))
+	if id > 0 {
+		span.SetAttribute(AttrCommandID, id)
+	}
+	if err != nil {
+		span.SetAttribute(AttrErrorClass, ErrorClass(err))
+	}
+	span.End(err)
+	return resp, err
+}
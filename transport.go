@@ -0,0 +1,18 @@
+package Macku
+
+import "time"
+
+// Transport is the command channel between the library and a Makcu device.
+// SerialTransport is the default implementation; wrappers such as Recorder
+// decorate another Transport and can be passed to NewControllerWithTransport.
+type Transport interface {
+	Connect() error
+	Disconnect() error
+	IsConnected() bool
+	SendCommand(command string, expectResponse bool, timeout time.Duration) (string, error)
+	SetButtonCallback(cb func(MouseButton, bool))
+	GetButtonStates() map[string]bool
+	GetButtonMask() int
+	EnableButtonMonitoring(enable bool) error
+	PortName() string
+}
@@ -0,0 +1,131 @@
+package Macku
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// restoreTimeout bounds how long WithLocks keeps retrying a restore while
+// the device is reconnecting.
+const restoreTimeout = 2 * time.Second
+
+// WithLocks locks targets, runs fn and then puts every target back in the
+// state it had before, whether fn returns, panics or ctx is cancelled.
+// Cancellation restores the targets at once; fn should watch ctx too, as
+// WithLocks waits for it and then returns ctx.Err(). Restoring sends each
+// target's previous state explicitly, so it also holds if the device
+// reconnected and lost the locks mid-operation; the locks are applied
+// again after such a reconnect while fn runs, and a failure to do so is
+// part of the returned error (and passed to OnReconnectError).
+//
+// The previous states are read first; if any cannot be, fn is not run.
+func (c *MakcuController) WithLocks(ctx context.Context, targets []LockTarget, fn func() error) (err error) {
+	if err := c.checkConnection(); err != nil {
+		return err
+	}
+	prev := make(map[LockTarget]bool, len(targets))
+	var order []LockTarget
+	for _, t := range targets {
+		if _, seen := prev[t]; seen {
+			continue
+		}
+		locked, err := c.lockState(t)
+		if err != nil {
+			return err
+		}
+		prev[t] = locked
+		order = append(order, t)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var (
+		mu         sync.Mutex
+		restored   bool
+		restErr    error
+		reapplyErr error
+	)
+	restore := func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		if !restored {
+			restored = true
+			restErr = c.restoreLocks(order, prev)
+		}
+		return restErr
+	}
+
+	for i, t := range order {
+		if err := c.setLockByTarget(t, true); err != nil {
+			return errors.Join(err, c.restoreLocks(order[:i+1], prev))
+		}
+	}
+
+	unhook := c.OnReconnect(func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		if restored {
+			return nil
+		}
+		var errs []error
+		for _, t := range order {
+			if err := c.setLockByTarget(t, true); err != nil {
+				errs = append(errs, fmt.Errorf("re-apply %v lock: %w", t, err))
+			}
+		}
+		err := errors.Join(errs...)
+		reapplyErr = errors.Join(reapplyErr, err)
+		return err
+	})
+	stopCancel := context.AfterFunc(ctx, func() { restore() })
+	defer func() {
+		unhook()
+		stopCancel()
+		rerr := restore()
+		if p := recover(); p != nil {
+			panic(p)
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil && err == nil {
+			err = ctxErr
+		}
+		mu.Lock()
+		err = errors.Join(err, reapplyErr, rerr)
+		mu.Unlock()
+	}()
+	return fn()
+}
+
+// restoreLocks sets each target back to its state in prev, in reverse
+// order, retrying while the device is reconnecting.
+func (c *MakcuController) restoreLocks(targets []LockTarget, prev map[LockTarget]bool) error {
+	var errs []error
+	for _, t := range slices.Backward(targets) {
+		deadline := time.Now().Add(restoreTimeout)
+		for {
+			err := c.setLockByTarget(t, prev[t])
+			if err == nil || errors.Is(err, ErrCommand) || !c.connected.Load() || time.Now().After(deadline) {
+				if err != nil {
+					errs = append(errs, fmt.Errorf("restore %v lock: %w", t, err))
+				}
+				break
+			}
+			time.Sleep(reconnectDelay)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// lockState returns whether target is locked, from the cache if fresh.
+func (c *MakcuController) lockState(t LockTarget) (bool, error) {
+	name := strings.ToUpper(t.String())
+	if _, ok := lockTargets[name]; !ok {
+		return false, NewCommandError(fmt.Sprintf("invalid lock target: %d", t))
+	}
+	return c.Mouse.lockState(name)
+}